@@ -0,0 +1,54 @@
+package jsonpathplus
+
+import "github.com/reclaimprotocol/jsonpathplus-go/internal/lru"
+
+// CacheStats reports the activity of a PathCache.
+type CacheStats struct {
+	Hits      int64 // Lookups that found a compiled path
+	Misses    int64 // Lookups that had to compile the path
+	Evictions int64 // Paths dropped to make room for others
+	Size      int   // Paths currently cached
+	Capacity  int   // Maximum number of cached paths
+}
+
+// PathCache is a concurrency-safe LRU cache of compiled paths, keyed by path string
+// and grammar.
+type PathCache struct {
+	cache *lru.Cache
+}
+
+// cacheKey identifies a compiled path; the same string compiles differently with
+// the RFC 9535 grammar.
+type cacheKey struct {
+	path     string
+	standard bool
+}
+
+// newPathCache creates a cache holding up to capacity compiled paths.
+func newPathCache(capacity int) *PathCache {
+	return &PathCache{cache: lru.New(capacity)}
+}
+
+// get returns the compiled path cached under key, marking it as recently used.
+func (c *PathCache) get(key cacheKey) (*JSONPath, bool) {
+	jp, ok := c.cache.Get(key)
+	if !ok {
+		return nil, false
+	}
+	return jp.(*JSONPath), true
+}
+
+// add caches jp under key, evicting the least recently used path when full.
+func (c *PathCache) add(key cacheKey, jp *JSONPath) {
+	c.cache.Add(key, jp)
+}
+
+// Stats returns the cache counters and its current size.
+func (c *PathCache) Stats() CacheStats {
+	return CacheStats(c.cache.Stats())
+}
+
+// Purge removes every cached path. The hit, miss and eviction counters are kept.
+func (c *PathCache) Purge() {
+	c.cache.Purge()
+}
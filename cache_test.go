@@ -0,0 +1,183 @@
+package jsonpathplus
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestPathCache(t *testing.T) {
+	data := `{"a":1,"b":2,"c":3}`
+
+	t.Run("HitsAndMisses", func(t *testing.T) {
+		engine := NewJSONPathEngine()
+		for i := 0; i < 3; i++ {
+			if _, err := engine.Query("$.a", data); err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+		}
+		// The same string is cached separately for the RFC 9535 grammar
+		if _, err := engine.QueryWithOptions("$.a", data, &Options{Standard: true}); err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		// Paths that fail to compile are not cached
+		engine.Query("$.a[", data)
+		engine.Query("$.a[", data)
+
+		stats := engine.Cache().Stats()
+		if stats.Hits != 2 || stats.Misses != 4 || stats.Size != 2 || stats.Capacity != DefaultCacheSize {
+			t.Errorf("Unexpected cache stats %+v", stats)
+		}
+
+		first, _ := engine.Compile("$.b")
+		second, _ := engine.Compile("$.b")
+		if first != second {
+			t.Error("Expected Compile to return the cached path")
+		}
+	})
+
+	t.Run("Eviction", func(t *testing.T) {
+		config := DefaultConfig()
+		config.CacheSize = 2
+		engine, err := NewJSONPathEngineWithConfig(config)
+		if err != nil {
+			t.Fatalf("NewJSONPathEngineWithConfig failed: %v", err)
+		}
+
+		a, _ := engine.Compile("$.a")
+		engine.Compile("$.b")
+		engine.Compile("$.a") // $.b is now the least recently used path
+		engine.Compile("$.c")
+
+		stats := engine.Cache().Stats()
+		if stats.Evictions != 1 || stats.Size != 2 {
+			t.Errorf("Unexpected cache stats %+v", stats)
+		}
+		if again, _ := engine.Compile("$.a"); again != a {
+			t.Error("Expected $.a to stay cached")
+		}
+		misses := engine.Cache().Stats().Misses
+		engine.Compile("$.b")
+		if engine.Cache().Stats().Misses != misses+1 {
+			t.Error("Expected $.b to have been evicted")
+		}
+	})
+
+	t.Run("Purge", func(t *testing.T) {
+		engine := NewJSONPathEngine()
+		engine.Compile("$.a")
+		engine.Compile("$.a")
+		engine.Cache().Purge()
+
+		stats := engine.Cache().Stats()
+		if stats.Size != 0 || stats.Hits != 1 || stats.Misses != 1 {
+			t.Errorf("Unexpected cache stats after Purge %+v", stats)
+		}
+
+		// Registering a function changes how calls of it compile
+		if _, err := engine.Query("$[?(double(@) == 4)]", `[1,2]`); err == nil {
+			t.Fatal("Expected double to be undefined before registration")
+		}
+		err := engine.RegisterFunction("double", func(args []interface{}) interface{} {
+			return args[0].(float64) * 2
+		}, FunctionSignature{Params: []FunctionType{ValueType}, Result: ValueType})
+		if err != nil {
+			t.Fatalf("RegisterFunction failed: %v", err)
+		}
+		if engine.Cache().Stats().Size != 0 {
+			t.Error("Expected RegisterFunction to purge the cache")
+		}
+		results, err := engine.Query("$[?(double(@) == 4)]", `[1,2]`)
+		if err != nil || len(results) != 1 {
+			t.Fatalf("Expected 1 result after registration, got %v, %v", results, err)
+		}
+	})
+
+	t.Run("Concurrent", func(t *testing.T) {
+		config := DefaultConfig()
+		config.CacheSize = 8
+		engine, err := NewJSONPathEngineWithConfig(config)
+		if err != nil {
+			t.Fatalf("NewJSONPathEngineWithConfig failed: %v", err)
+		}
+
+		var wg sync.WaitGroup
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for i := 0; i < 100; i++ {
+					path := fmt.Sprintf("$[%d]", (g+i)%16)
+					if _, err := engine.Query(path, `[0]`); err != nil {
+						t.Errorf("Query failed: %v", err)
+						return
+					}
+				}
+			}(g)
+		}
+		wg.Wait()
+
+		stats := engine.Cache().Stats()
+		if stats.Hits+stats.Misses != 800 || stats.Size > 8 {
+			t.Errorf("Unexpected cache stats %+v", stats)
+		}
+	})
+
+	t.Run("Default", func(t *testing.T) {
+		// The package-level functions share an engine, so their paths are compiled once
+		path := "$.c[?(@ != 'default cache')]"
+		before := defaultEngine.Cache().Stats()
+		if _, err := Query(path, data); err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		if _, err := QueryContext(context.Background(), path, data); err != nil {
+			t.Fatalf("QueryContext failed: %v", err)
+		}
+		if _, err := Evaluate(path, data, nil); err != nil {
+			t.Fatalf("Evaluate failed: %v", err)
+		}
+		after := defaultEngine.Cache().Stats()
+		if after.Misses-before.Misses != 1 || after.Hits-before.Hits != 2 {
+			t.Errorf("Expected one miss and two hits, got %+v then %+v", before, after)
+		}
+	})
+
+	t.Run("ASTCopy", func(t *testing.T) {
+		// Callers share cached paths, so changing an AST must not change the query
+		jp, err := New("$.a")
+		if err != nil {
+			t.Fatalf("New failed: %v", err)
+		}
+		ast := jp.AST()
+		ast.Children[0].Value = "b"
+		ast.Children = nil
+		results, err := Query("$.a", data)
+		if err != nil || len(results) != 1 || results[0].Value != float64(1) {
+			t.Errorf("Expected the cached path to select a, got %+v, %v", results, err)
+		}
+		if again, _ := New("$.a"); again != jp || again.AST().Children[0].Value != "a" {
+			t.Error("Expected New to return the unchanged cached path")
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		config := DefaultConfig()
+		config.CacheSize = 0
+		engine, err := NewJSONPathEngineWithConfig(config)
+		if err != nil {
+			t.Fatalf("NewJSONPathEngineWithConfig failed: %v", err)
+		}
+		if engine.Cache() != nil {
+			t.Error("Expected no cache with CacheSize 0")
+		}
+		if _, err := engine.Query("$.a", data); err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+
+		config.CacheSize = -1
+		if _, err := NewJSONPathEngineWithConfig(config); err == nil {
+			t.Error("Expected a negative CacheSize to be rejected")
+		}
+	})
+}
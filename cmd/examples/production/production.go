@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -44,10 +45,16 @@ func main() {
 func createProductionEngine() *jp.JSONPathEngine {
 	fmt.Println("\n1. Creating Production Engine:")
 
-	// Use production configuration
-	engine := jp.NewJSONPathEngine()
+	// Use production configuration and validate every path against the security policy
+	config := jp.ProductionConfig()
+	config.Security = jp.DefaultSecurityConfig()
 
-	fmt.Printf("✓ Engine created with production config\n")
+	engine, err := jp.NewJSONPathEngineWithConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to create engine: %v", err)
+	}
+
+	fmt.Printf("✓ Engine created with production config and security policy\n")
 
 	return engine
 }
@@ -114,11 +121,7 @@ func basicUsage(engine *jp.JSONPathEngine) {
 func securityExample(engine *jp.JSONPathEngine) {
 	fmt.Println("\n3. Security Features:")
 
-	// Create security validator
-	secConfig := jp.DefaultSecurityConfig()
-	validator := jp.NewSecurityValidator(secConfig)
-
-	// Test various paths for security
+	// Test various paths for security; the engine validates every path it compiles
 	testPaths := []string{
 		"$.users[*].name",               // Safe
 		"$.users[?(@.age > 21)]",        // Safe
@@ -134,7 +137,7 @@ func securityExample(engine *jp.JSONPathEngine) {
 			displayPath = displayPath[:50] + "..."
 		}
 
-		err := validator.ValidatePath(path)
+		_, err := engine.Compile(path)
 		if err != nil {
 			fmt.Printf("  ❌ BLOCKED: %s - %v\n", displayPath, err)
 		} else {
@@ -143,6 +146,7 @@ func securityExample(engine *jp.JSONPathEngine) {
 	}
 
 	// Demonstrate path sanitization
+	validator := jp.NewSecurityValidator(jp.DefaultSecurityConfig())
 	maliciousPath := "$.users[?(eval('rm -rf /') && @.age > 21)]"
 	sanitizedPath := validator.SanitizePath(maliciousPath)
 	fmt.Printf("\nPath sanitization:\n")
@@ -188,9 +192,13 @@ func performanceMonitoring(engine *jp.JSONPathEngine) {
 	totalTime := time.Since(start)
 
 	// Get metrics
+	metrics := engine.GetMetrics()
 
 	fmt.Printf("\nPerformance Metrics:\n")
 	fmt.Printf("  Total execution time: %v\n", totalTime)
+	fmt.Printf("  Queries executed: %d\n", metrics.QueriesExecuted)
+	fmt.Printf("  Average time: %v\n", metrics.AverageExecutionTime)
+	fmt.Printf("  Filter evaluations: %d\n", metrics.NodeEvaluations["filter"])
 
 	// Production setup focuses on security and monitoring
 }
@@ -222,22 +230,21 @@ func rateLimitingExample() {
 
 // HTTPHandler handles JSONPath queries via HTTP
 type HTTPHandler struct {
-	engine    *jp.JSONPathEngine
-	validator *jp.SecurityValidator
-	limiter   *jp.RateLimiter
+	engine  *jp.JSONPathEngine
+	limiter *jp.RateLimiter
 }
 
 // createHTTPServer creates an HTTP server with JSONPath endpoints
 func createHTTPServer(engine *jp.JSONPathEngine) *http.Server {
 	handler := &HTTPHandler{
-		engine:    engine,
-		validator: jp.NewSecurityValidator(jp.DefaultSecurityConfig()),
-		limiter:   jp.NewRateLimiter(10, time.Minute), // 10 requests per minute
+		engine:  engine,
+		limiter: jp.NewRateLimiter(10, time.Minute), // 10 requests per minute
 	}
 
 	mux := http.NewServeMux()
 	mux.HandleFunc("/query", handler.handleQuery)
 	mux.HandleFunc("/health", handler.handleHealth)
+	mux.Handle("/metrics", engine.Metrics()) // Prometheus text format
 
 	return &http.Server{
 		Addr:           ":8080",
@@ -265,12 +272,6 @@ func (h *HTTPHandler) handleQuery(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validate path for security
-	if err := h.validator.ValidatePath(path); err != nil {
-		http.Error(w, fmt.Sprintf("Invalid path: %v", err), http.StatusBadRequest)
-		return
-	}
-
 	// Sample data for demo
 	data := map[string]interface{}{
 		"users": []interface{}{
@@ -284,9 +285,17 @@ func (h *HTTPHandler) handleQuery(w http.ResponseWriter, r *http.Request) {
 		},
 	}
 
-	// Execute query with timeout
+	// Execute query; the engine validates the path and enforces the execution budget
 	results, err := h.engine.Query(path, data)
-	if err != nil {
+	switch {
+	case errors.Is(err, &jp.JSONPathError{Type: jp.ErrInvalidPath}),
+		errors.Is(err, &jp.JSONPathError{Type: jp.ErrInvalidExpression}):
+		http.Error(w, fmt.Sprintf("Invalid path: %v", err), http.StatusBadRequest)
+		return
+	case errors.Is(err, &jp.JSONPathError{Type: jp.ErrExecutionBudget}):
+		http.Error(w, fmt.Sprintf("Query too expensive: %v", err), http.StatusServiceUnavailable)
+		return
+	case err != nil:
 		http.Error(w, fmt.Sprintf("Query failed: %v", err), http.StatusInternalServerError)
 		return
 	}
@@ -305,8 +314,6 @@ func (h *HTTPHandler) handleQuery(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// handleMetrics returns engine metrics
-
 // handleHealth returns health status
 func (h *HTTPHandler) handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
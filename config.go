@@ -1,6 +1,7 @@
 package jsonpathplus
 
 import (
+	"regexp"
 	"time"
 )
 
@@ -12,6 +13,7 @@ const (
 	DefaultMaxResultCount    = 10000
 	DefaultTimeout           = 30 * time.Second
 	DefaultMaxMemoryUsage    = 100 * 1024 * 1024 // 100MB
+	DefaultCacheSize         = 1000
 
 	// Production limits
 	ProductionMaxPathLength     = 500
@@ -26,10 +28,12 @@ type Config struct {
 	// MaxPathLength limits the maximum length of JSONPath expressions
 	MaxPathLength int
 
-	// MaxRecursionDepth limits the depth of recursive descent operations
+	// MaxRecursionDepth limits the depth of recursive descent operations, each filter
+	// nested in a filter expression counting as one more level
 	MaxRecursionDepth int
 
-	// MaxResultCount limits the number of results returned
+	// MaxResultCount limits the number of results returned. The evaluation stops as soon
+	// as the results it has collected exceed the limit.
 	MaxResultCount int
 
 	// Timeout for query execution
@@ -49,6 +53,14 @@ type Config struct {
 
 	// EnableMetrics enables performance metrics collection
 	EnableMetrics bool
+
+	// CacheSize is the number of compiled paths the engine keeps in its LRU cache
+	// (0 = no cache)
+	CacheSize int
+
+	// Security, when set, validates every path the engine compiles with a
+	// SecurityValidator and stops queries that run longer than its MaxExecutionTime
+	Security *SecurityConfig
 }
 
 // DefaultConfig returns the default configuration.
@@ -63,6 +75,7 @@ func DefaultConfig() *Config {
 		AllowUnsafeOperations: false,
 		MaxMemoryUsage:        DefaultMaxMemoryUsage,
 		EnableMetrics:         false,
+		CacheSize:             DefaultCacheSize,
 	}
 }
 
@@ -122,11 +135,39 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	if c.CacheSize < 0 {
+		return &ValidationError{
+			Field:   "CacheSize",
+			Value:   c.CacheSize,
+			Message: "must be >= 0",
+		}
+	}
+
+	if c.Security != nil && c.Security.MaxExecutionTime < 0 {
+		return &ValidationError{
+			Field:   "Security.MaxExecutionTime",
+			Value:   c.Security.MaxExecutionTime,
+			Message: "must be >= 0",
+		}
+	}
+
 	return nil
 }
 
-// Clone creates a copy of the configuration.
+// Clone creates a copy of the configuration that shares no slices or maps with it.
 func (c *Config) Clone() *Config {
 	clone := *c
+	if c.Security != nil {
+		security := *c.Security
+		security.AllowedFunctions = append([]string(nil), c.Security.AllowedFunctions...)
+		security.BlockedPatterns = append([]*regexp.Regexp(nil), c.Security.BlockedPatterns...)
+		if c.Security.NodeCosts != nil {
+			security.NodeCosts = make(map[string]int, len(c.Security.NodeCosts))
+			for kind, cost := range c.Security.NodeCosts {
+				security.NodeCosts[kind] = cost
+			}
+		}
+		clone.Security = &security
+	}
 	return &clone
 }
@@ -0,0 +1,366 @@
+package jsonpathplus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestEngineConfigLimits tests that an engine built from a Config enforces every limit.
+func TestEngineConfigLimits(t *testing.T) {
+	t.Run("MaxPathLength", func(t *testing.T) {
+		config := DefaultConfig()
+		config.MaxPathLength = 10
+
+		engine, err := NewJSONPathEngineWithConfig(config)
+		if err != nil {
+			t.Fatalf("NewJSONPathEngineWithConfig failed: %v", err)
+		}
+
+		_, err = engine.Query("$.store.book[*].author", `{}`)
+		var lengthErr *PathLengthError
+		if !errors.As(err, &lengthErr) {
+			t.Fatalf("Expected PathLengthError, got %v", err)
+		}
+	})
+
+	t.Run("MaxRecursionDepth", func(t *testing.T) {
+		config := DefaultConfig()
+		config.MaxRecursionDepth = 3
+
+		engine, err := NewJSONPathEngineWithConfig(config)
+		if err != nil {
+			t.Fatalf("NewJSONPathEngineWithConfig failed: %v", err)
+		}
+
+		_, err = engine.Query("$..*", `{"a":{"b":{"c":{"d":{"e":1}}}}}`)
+		var depthErr *RecursionLimitError
+		if !errors.As(err, &depthErr) {
+			t.Fatalf("Expected RecursionLimitError, got %v", err)
+		}
+		if !errors.Is(err, &JSONPathError{Type: ErrRecursionLimit}) {
+			t.Errorf("Expected error to match ErrRecursionLimit, got %v", err)
+		}
+
+		// Shallow documents stay within the limit
+		results, err := engine.Query("$..*", `{"a":{"b":1}}`)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		if len(results) != 2 {
+			t.Errorf("Expected 2 results, got %d", len(results))
+		}
+
+		// Nested filters and descendant segments in filter expressions count as levels too
+		deep := `[{"a":[{"b":[{"c":[{"d":[{"e":1}]}]}]}]}]`
+		for _, tt := range []struct {
+			path     string
+			standard bool
+		}{
+			{"$[?(@.a[?(@.b[?(@.c[?(@.d[?(@.e)])])])])]", false},
+			{"$[?(count(@..e) > 0)]", false},
+			{"$[?@.a[?@.b[?@.c[?@.d[?@.e]]]]]", true},
+			{"$[?count(@..e) > 0]", true},
+		} {
+			if _, err := engine.QueryWithOptions(tt.path, deep, &Options{Standard: tt.standard}); !errors.Is(err, &JSONPathError{Type: ErrRecursionLimit}) {
+				t.Errorf("%s: expected ErrRecursionLimit, got %v", tt.path, err)
+			}
+		}
+		if results, err := engine.Query("$[?(@.a[?(@.b)])]", deep); err != nil || len(results) != 1 {
+			t.Errorf("Expected a shallow nested filter to run, got %v, %v", results, err)
+		}
+	})
+
+	t.Run("MaxResultCount", func(t *testing.T) {
+		config := DefaultConfig()
+		config.MaxResultCount = 2
+
+		engine, err := NewJSONPathEngineWithConfig(config)
+		if err != nil {
+			t.Fatalf("NewJSONPathEngineWithConfig failed: %v", err)
+		}
+
+		_, err = engine.Query("$[*]", `[1,2,3]`)
+		if !errors.Is(err, &JSONPathError{Type: ErrResultLimit}) {
+			t.Fatalf("Expected ErrResultLimit, got %v", err)
+		}
+
+		jp, err := engine.Compile("$[0:2]")
+		if err != nil {
+			t.Fatalf("Compile failed: %v", err)
+		}
+		results, err := jp.Execute([]interface{}{1, 2, 3})
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if len(results) != 2 {
+			t.Errorf("Expected 2 results, got %d", len(results))
+		}
+
+		// The evaluation stops at the first result over the limit instead of
+		// collecting every match first
+		evaluations := 0
+		err = engine.RegisterFunction("seen", func(args []interface{}) interface{} {
+			evaluations++
+			return float64(1)
+		}, FunctionSignature{Params: []FunctionType{ValueType}, Result: ValueType})
+		if err != nil {
+			t.Fatalf("RegisterFunction failed: %v", err)
+		}
+		large := "[" + strings.Repeat("1,", 999) + "1]"
+		for _, query := range []struct {
+			path     string
+			standard bool
+		}{
+			{"$[?(seen(@) == 1)]", false},
+			{"$[?seen(@) == 1]", true},
+		} {
+			evaluations = 0
+			_, err = engine.QueryWithOptions(query.path, large, &Options{Standard: query.standard})
+			if !errors.Is(err, &JSONPathError{Type: ErrResultLimit}) {
+				t.Errorf("%s: expected ErrResultLimit, got %v", query.path, err)
+			}
+			if evaluations != 3 {
+				t.Errorf("%s: expected the filter to stop after 3 of 1000 elements, got %d", query.path, evaluations)
+			}
+		}
+	})
+
+	t.Run("MaxMemoryUsage", func(t *testing.T) {
+		config := DefaultConfig()
+		config.MaxMemoryUsage = 1024
+
+		engine, err := NewJSONPathEngineWithConfig(config)
+		if err != nil {
+			t.Fatalf("NewJSONPathEngineWithConfig failed: %v", err)
+		}
+
+		_, err = engine.Query("$[*]", "["+strings.Repeat("1,", 100)+"1]")
+		if !errors.Is(err, &JSONPathError{Type: ErrMemoryLimit}) {
+			t.Fatalf("Expected ErrMemoryLimit, got %v", err)
+		}
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		config := DefaultConfig()
+		config.Timeout = 1 // one nanosecond
+
+		engine, err := NewJSONPathEngineWithConfig(config)
+		if err != nil {
+			t.Fatalf("NewJSONPathEngineWithConfig failed: %v", err)
+		}
+
+		_, err = engine.Query("$..*", buildDeepDocument(20, 20))
+		if !errors.Is(err, &JSONPathError{Type: ErrTimeout}) {
+			t.Fatalf("Expected ErrTimeout, got %v", err)
+		}
+	})
+
+	t.Run("Security", func(t *testing.T) {
+		config := DefaultConfig()
+		config.Security = DefaultSecurityConfig()
+
+		engine, err := NewJSONPathEngineWithConfig(config)
+		if err != nil {
+			t.Fatalf("NewJSONPathEngineWithConfig failed: %v", err)
+		}
+
+		if _, err := engine.Query("$.users[?(eval('x'))]", `{}`); !errors.Is(err, &JSONPathError{Type: ErrInvalidExpression}) {
+			t.Errorf("Expected Query to reject eval with ErrInvalidExpression, got %v", err)
+		}
+		if _, err := engine.CompileStandard("$..a..b..c..d..e..f..g..h..i..j..k..l..m..n"); !errors.Is(err, &JSONPathError{Type: ErrInvalidPath}) {
+			t.Errorf("Expected CompileStandard to reject a complex path with ErrInvalidPath, got %v", err)
+		}
+		// Standard queries are validated on the RFC 9535 grammar they are written in
+		if results, err := engine.QueryWithOptions("$[?@.*]", `[{"a":1},{},[2]]`, &Options{Standard: true}); err != nil || len(results) != 2 {
+			t.Errorf("Expected the standard filter to pass validation, got %v, %v", results, err)
+		}
+		if _, err := engine.CompileStandard("$[?search(@.a, 'file://x')]"); !errors.Is(err, &JSONPathError{Type: ErrInvalidExpression}) {
+			t.Errorf("Expected CompileStandard to reject a file URL with ErrInvalidExpression, got %v", err)
+		}
+		results, err := engine.Query("$.users[?(@.age > 21)].name", `{"users":[{"name":"a","age":30}]}`)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		if len(results) != 1 {
+			t.Errorf("Expected 1 result, got %d", len(results))
+		}
+
+		// Registered functions are validated as the calls the engine evaluates:
+		// a call of double scores 10, where a sandbox call would score 11
+		registered := config.Clone()
+		registered.Security.MaxPathComplexity = 10
+		registered.Security.AllowedFunctions = append(registered.Security.AllowedFunctions, "double")
+		engine, err = NewJSONPathEngineWithConfig(registered)
+		if err != nil {
+			t.Fatalf("NewJSONPathEngineWithConfig failed: %v", err)
+		}
+		err = engine.RegisterFunction("double", func(args []interface{}) interface{} {
+			return args[0].(float64) * 2
+		}, FunctionSignature{Params: []FunctionType{ValueType}, Result: ValueType})
+		if err != nil {
+			t.Fatalf("RegisterFunction failed: %v", err)
+		}
+		if results, err := engine.Query("$[?(double(@) == 4)]", `[1,2]`); err != nil || len(results) != 1 {
+			t.Errorf("Expected the registered function to pass validation, got %v, %v", results, err)
+		}
+
+		// Clones share no slices or maps with the configuration they copy
+		if len(config.Security.AllowedFunctions) == len(registered.Security.AllowedFunctions) {
+			t.Error("Expected appending to the clone's AllowedFunctions to leave the original alone")
+		}
+		config.Security.NodeCosts = DefaultNodeCosts()
+		patterns := config.Clone()
+		patterns.Security.BlockedPatterns[0] = nil
+		patterns.Security.NodeCosts = nil
+		costs := config.Clone()
+		costs.Security.NodeCosts["filter"] = 100
+		if config.Security.BlockedPatterns[0] == nil || config.Security.NodeCosts["filter"] == 100 {
+			t.Error("Expected Clone to deep-copy BlockedPatterns and NodeCosts")
+		}
+
+		config.Security.MaxExecutionTime = 1 // one nanosecond
+		engine, err = NewJSONPathEngineWithConfig(config)
+		if err != nil {
+			t.Fatalf("NewJSONPathEngineWithConfig failed: %v", err)
+		}
+		_, err = engine.Query("$..*", buildDeepDocument(20, 20))
+		if !errors.Is(err, &JSONPathError{Type: ErrExecutionBudget}) {
+			t.Fatalf("Expected ErrExecutionBudget, got %v", err)
+		}
+
+		config.Security.MaxExecutionTime = -1
+		if _, err := NewJSONPathEngineWithConfig(config); err == nil {
+			t.Error("Expected validation error for a negative MaxExecutionTime")
+		}
+	})
+
+	t.Run("InvalidConfig", func(t *testing.T) {
+		config := DefaultConfig()
+		config.MaxResultCount = 0
+
+		if _, err := NewJSONPathEngineWithConfig(config); err == nil {
+			t.Fatal("Expected validation error for invalid config")
+		}
+	})
+}
+
+// TestQueryContext tests that canceled and expired contexts stop evaluation.
+func TestQueryContext(t *testing.T) {
+	jsonStr := buildDeepDocument(10, 10)
+
+	t.Run("Canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := QueryContext(ctx, "$..*", jsonStr)
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Expected context.Canceled, got %v", err)
+		}
+		if !errors.Is(err, &JSONPathError{Type: ErrCanceled}) {
+			t.Errorf("Expected error to match ErrCanceled, got %v", err)
+		}
+	})
+
+	t.Run("DeadlineExceeded", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), -time.Second)
+		defer cancel()
+
+		jp, err := New("$..*")
+		if err != nil {
+			t.Fatalf("New failed: %v", err)
+		}
+		data, err := JSONParse(jsonStr)
+		if err != nil {
+			t.Fatalf("JSONParse failed: %v", err)
+		}
+
+		_, err = jp.ExecuteContext(ctx, data)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+		}
+		if !errors.Is(err, &JSONPathError{Type: ErrTimeout}) {
+			t.Errorf("Expected error to match ErrTimeout, got %v", err)
+		}
+	})
+
+	t.Run("CanceledMidway", func(t *testing.T) {
+		// tick cancels the context on its tenth call; the evaluation must stop at the
+		// next checkpoint instead of visiting the remaining values
+		engine := NewJSONPathEngine()
+		var cancel context.CancelFunc
+		calls := 0
+		err := engine.RegisterFunction("tick", func(args []interface{}) interface{} {
+			if calls++; calls == 10 {
+				cancel()
+			}
+			return float64(1)
+		}, FunctionSignature{Params: []FunctionType{ValueType}, Result: ValueType})
+		if err != nil {
+			t.Fatalf("RegisterFunction failed: %v", err)
+		}
+		data, err := JSONParse("[" + strings.Repeat(`{"a":[1,2]},`, 999) + `{"a":[1,2]}]`)
+		if err != nil {
+			t.Fatalf("JSONParse failed: %v", err)
+		}
+
+		for _, query := range []struct {
+			path     string
+			standard bool
+		}{
+			{"$..*[?(tick(@) == 1)]", false},
+			{"$[*].a[?(tick(@) == 1)]", false},
+			{"$..[?tick(@) == 1]", true},
+		} {
+			var ctx context.Context
+			ctx, cancel = context.WithCancel(context.Background())
+			calls = 0
+			jp, err := engine.Compile(query.path)
+			if query.standard {
+				jp, err = engine.CompileStandard(query.path)
+			}
+			if err != nil {
+				t.Fatalf("Compile %s failed: %v", query.path, err)
+			}
+			_, err = jp.ExecuteContext(ctx, data)
+			cancel()
+			if !errors.Is(err, &JSONPathError{Type: ErrCanceled}) {
+				t.Errorf("%s: expected ErrCanceled, got %v", query.path, err)
+			}
+			if calls != 10 {
+				t.Errorf("%s: expected the evaluation to stop after 10 of thousands of filter tests, got %d", query.path, calls)
+			}
+		}
+	})
+
+	t.Run("Active", func(t *testing.T) {
+		results, err := QueryContext(context.Background(), "$.k0.k0", jsonStr)
+		if err != nil {
+			t.Fatalf("QueryContext failed: %v", err)
+		}
+		if len(results) != 1 {
+			t.Errorf("Expected 1 result, got %d", len(results))
+		}
+	})
+}
+
+// buildDeepDocument builds a JSON object with the given breadth at each of depth levels.
+func buildDeepDocument(depth, breadth int) string {
+	if depth == 0 {
+		return "1"
+	}
+	var sb strings.Builder
+	sb.WriteByte('{')
+	child := buildDeepDocument(depth-1, 1)
+	for i := 0; i < breadth; i++ {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		fmt.Fprintf(&sb, `"k%d":%s`, i, child)
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
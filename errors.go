@@ -25,8 +25,43 @@ const (
 	ErrTypeError
 	// ErrRecursionLimit indicates recursion depth limit exceeded.
 	ErrRecursionLimit
+	// ErrResultLimit indicates the result count limit was exceeded.
+	ErrResultLimit
+	// ErrTimeout indicates the query ran longer than its time budget.
+	ErrTimeout
+	// ErrMemoryLimit indicates the memory usage limit was exceeded.
+	ErrMemoryLimit
+	// ErrCanceled indicates the query was canceled through its context.
+	ErrCanceled
+	// ErrExecutionBudget indicates the query spent the MaxExecutionTime of its security policy.
+	ErrExecutionBudget
 )
 
+// errorTypeNames are the names String gives error types.
+var errorTypeNames = map[ErrorType]string{
+	ErrInvalidPath:       "invalid_path",
+	ErrParseError:        "parse_error",
+	ErrEvaluationError:   "evaluation_error",
+	ErrInvalidJSON:       "invalid_json",
+	ErrInvalidExpression: "invalid_expression",
+	ErrOutOfBounds:       "out_of_bounds",
+	ErrTypeError:         "type_error",
+	ErrRecursionLimit:    "recursion_limit",
+	ErrResultLimit:       "result_limit",
+	ErrTimeout:           "timeout",
+	ErrMemoryLimit:       "memory_limit",
+	ErrCanceled:          "canceled",
+	ErrExecutionBudget:   "execution_budget",
+}
+
+// String returns the snake_case name of the error type, such as "invalid_path".
+func (t ErrorType) String() string {
+	if name, ok := errorTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("error_type_%d", int(t))
+}
+
 // JSONPathError represents an error that occurred during JSONPath operations.
 type JSONPathError struct {
 	Type     ErrorType
@@ -56,6 +91,16 @@ func (e *JSONPathError) Error() string {
 		parts = append(parts, "type error")
 	case ErrRecursionLimit:
 		parts = append(parts, "recursion limit exceeded")
+	case ErrResultLimit:
+		parts = append(parts, "result limit exceeded")
+	case ErrTimeout:
+		parts = append(parts, "timeout")
+	case ErrMemoryLimit:
+		parts = append(parts, "memory limit exceeded")
+	case ErrCanceled:
+		parts = append(parts, "canceled")
+	case ErrExecutionBudget:
+		parts = append(parts, "execution budget exceeded")
 	}
 
 	if e.Path != "" {
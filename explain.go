@@ -0,0 +1,232 @@
+package jsonpathplus
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/reclaimprotocol/jsonpathplus-go/internal/evaluator"
+	"github.com/reclaimprotocol/jsonpathplus-go/internal/rfc9535"
+	"github.com/reclaimprotocol/jsonpathplus-go/pkg/types"
+)
+
+// Warnings Explain reports for paths that are expensive to evaluate.
+const (
+	WarnRecursiveFilter   = "recursive descent followed by filter scans entire document"
+	WarnRecursiveWildcard = "recursive descent followed by wildcard selects every value in the document"
+	WarnNestedRecursive   = "nested recursive descents revisit every subtree"
+)
+
+// fanOutKinds are the node types that can select many values from one input.
+var fanOutKinds = map[string]bool{
+	"wildcard":       true,
+	"index_wildcard": true,
+	"recursive":      true,
+	"filter":         true,
+	"slice":          true,
+	"union":          true,
+	"property_names": true,
+}
+
+// Plan describes how a compiled path is evaluated, step by step.
+type Plan struct {
+	Path       string
+	Steps      []*PlanStep
+	Complexity int      // Sum of the step costs, as scored by a SecurityValidator
+	Warnings   []string // Constructs that make the path expensive, such as WarnRecursiveFilter
+	Analyzed   bool     // Whether the steps carry the statistics of ExplainAnalyze
+}
+
+// PlanStep is one node of the path's syntax tree. Steps are listed in evaluation
+// order; each step is applied to the values the step before it selected.
+type PlanStep struct {
+	Depth    int    // Nesting depth; the members of unions and chains are one deeper
+	Kind     string // AST node type, such as "property", "recursive" or "filter"
+	Selector string // Property name, index, slice or expression of the step
+	FansOut  bool   // Whether the step can select many values from one input
+	Cost     int    // Estimated complexity of the step, including its expression
+
+	// Set by ExplainAnalyze
+	Evaluations int           // Times the step was evaluated
+	Inputs      int           // Values the step was applied to, in total
+	Outputs     int           // Values the step itself selected, in total
+	Elapsed     time.Duration // Time spent in the step and the steps after it
+
+	node *types.AstNode
+}
+
+// Explain returns the evaluation plan of the path, with a complexity estimate
+// computed with the engine's security costs and warnings for expensive constructs.
+func (jp *JSONPath) Explain() *Plan {
+	validator := jp.engine.security
+	if validator == nil {
+		validator = NewSecurityValidator(nil)
+	}
+
+	plan := &Plan{Path: jp.path}
+	if jp.standard != nil {
+		explainStandard(plan, jp.standard, jp.nodes, validator)
+	} else {
+		explainNode(plan, jp.ast, 0, false, validator)
+	}
+	for _, step := range plan.Steps {
+		plan.Complexity += step.Cost
+	}
+	return plan
+}
+
+// ExplainAnalyze evaluates the path against data, like Execute, and returns its plan
+// with the number of values each step was applied to and the values it selected.
+func (jp *JSONPath) ExplainAnalyze(data interface{}) (*Plan, error) {
+	plan := jp.Explain()
+	tracer := &planTracer{steps: make(map[*types.AstNode]*PlanStep, len(plan.Steps))}
+	for _, step := range plan.Steps {
+		tracer.steps[step.node] = step
+	}
+	if _, err := jp.run(context.Background(), data, &Options{Tracer: tracer}); err != nil {
+		return nil, err
+	}
+	plan.Analyzed = true
+	return plan, nil
+}
+
+// explainNode appends the steps of node and its children to plan. underRecursive is
+// set when a recursive descent precedes node.
+func explainNode(plan *Plan, node *types.AstNode, depth int, underRecursive bool, validator *SecurityValidator) {
+	if node == nil {
+		return
+	}
+	plan.Steps = append(plan.Steps, &PlanStep{
+		Depth:    depth,
+		Kind:     node.Type,
+		Selector: node.Value,
+		FansOut:  fanOutKinds[node.Type],
+		Cost:     validator.nodeCost(node),
+		node:     node,
+	})
+	if underRecursive {
+		plan.warnFor(node.Type)
+	}
+
+	childDepth := depth
+	if node.Type == "union" || node.Type == "chain" {
+		childDepth++
+	}
+	for _, child := range node.Children {
+		explainNode(plan, child, childDepth, underRecursive || node.Type == "recursive", validator)
+	}
+}
+
+// explainStandard appends the steps of an RFC 9535 query to plan, one per descendant
+// segment and one per selector, each for the node its spans are reported for
+func explainStandard(plan *Plan, query *rfc9535.Query, nodes *evaluator.StandardNodes, validator *SecurityValidator) {
+	plan.Steps = append(plan.Steps, &PlanStep{Kind: "root", Selector: "$", Cost: validator.cost("root"), node: nodes.Root})
+
+	underRecursive := false
+	for _, segment := range query.Segments {
+		if segment.Descendant {
+			if underRecursive {
+				plan.warnFor("recursive")
+			}
+			plan.Steps = append(plan.Steps, &PlanStep{
+				Kind:     "recursive",
+				Selector: "..",
+				FansOut:  true,
+				Cost:     validator.cost("recursive"),
+				node:     nodes.Segment(segment),
+			})
+			underRecursive = true
+		}
+		for _, selector := range segment.Selectors {
+			kind := standardStepKind(selector)
+			plan.Steps = append(plan.Steps, &PlanStep{
+				Kind:     kind,
+				Selector: selector.String(),
+				FansOut:  fanOutKinds[kind],
+				Cost:     validator.selectorCost(selector),
+				node:     nodes.Selector(selector),
+			})
+			if underRecursive {
+				plan.warnFor(kind)
+			}
+		}
+	}
+}
+
+// standardStepKind names an RFC 9535 selector after the AST node type it corresponds to
+func standardStepKind(selector rfc9535.Selector) string {
+	switch selector.(type) {
+	case *rfc9535.NameSelector:
+		return "property"
+	case *rfc9535.WildcardSelector:
+		return "wildcard"
+	case *rfc9535.IndexSelector:
+		return "index"
+	case *rfc9535.SliceSelector:
+		return "slice"
+	default:
+		return "filter"
+	}
+}
+
+// warnFor records the warning, if any, for a step of the given kind that follows a
+// recursive descent
+func (p *Plan) warnFor(kind string) {
+	var warning string
+	switch kind {
+	case "filter":
+		warning = WarnRecursiveFilter
+	case "wildcard", "index_wildcard":
+		warning = WarnRecursiveWildcard
+	case "recursive":
+		warning = WarnNestedRecursive
+	default:
+		return
+	}
+	for _, existing := range p.Warnings {
+		if existing == warning {
+			return
+		}
+	}
+	p.Warnings = append(p.Warnings, warning)
+}
+
+// String renders the plan with one line per step, followed by its warnings.
+func (p *Plan) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s (complexity %d)\n", p.Path, p.Complexity)
+	for i, step := range p.Steps {
+		fmt.Fprintf(&sb, "%3d. %s%s %s cost=%d", i+1, strings.Repeat("  ", step.Depth), step.Kind, step.Selector, step.Cost)
+		if step.FansOut {
+			sb.WriteString(" fans-out")
+		}
+		if p.Analyzed {
+			fmt.Fprintf(&sb, " evaluations=%d in=%d out=%d elapsed=%v", step.Evaluations, step.Inputs, step.Outputs, step.Elapsed)
+		}
+		sb.WriteString("\n")
+	}
+	for _, warning := range p.Warnings {
+		fmt.Fprintf(&sb, "warning: %s\n", warning)
+	}
+	return sb.String()
+}
+
+// planTracer adds the spans of an evaluation to the steps of their nodes
+type planTracer struct {
+	steps map[*types.AstNode]*PlanStep
+}
+
+func (t *planTracer) StartSpan(node *types.AstNode, inputs int) {
+	if step, ok := t.steps[node]; ok {
+		step.Evaluations++
+		step.Inputs += inputs
+	}
+}
+
+func (t *planTracer) EndSpan(node *types.AstNode, outputs int, elapsed time.Duration) {
+	if step, ok := t.steps[node]; ok {
+		step.Outputs += outputs
+		step.Elapsed += elapsed
+	}
+}
@@ -0,0 +1,105 @@
+package jsonpathplus
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	t.Run("Plan", func(t *testing.T) {
+		jp, err := New("$..book[?(@.price < 10)].title")
+		if err != nil {
+			t.Fatalf("New failed: %v", err)
+		}
+		plan := jp.Explain()
+
+		kinds := []string{"root", "recursive", "property", "filter", "property"}
+		if len(plan.Steps) != len(kinds) {
+			t.Fatalf("Expected %d steps, got:\n%s", len(kinds), plan)
+		}
+		for i, kind := range kinds {
+			if plan.Steps[i].Kind != kind {
+				t.Errorf("Step %d: expected %s, got %s", i, kind, plan.Steps[i].Kind)
+			}
+		}
+		if !plan.Steps[1].FansOut || plan.Steps[2].FansOut || !plan.Steps[3].FansOut {
+			t.Errorf("Unexpected fan-out flags:\n%s", plan)
+		}
+		complexity, _ := NewSecurityValidator(nil).Complexity(jp.Path())
+		if plan.Complexity != complexity {
+			t.Errorf("Expected complexity %d, got %d", complexity, plan.Complexity)
+		}
+		if len(plan.Warnings) != 1 || plan.Warnings[0] != WarnRecursiveFilter {
+			t.Errorf("Expected the recursive filter warning, got %v", plan.Warnings)
+		}
+		if !strings.Contains(plan.String(), "warning: "+WarnRecursiveFilter) {
+			t.Errorf("Expected the warning in:\n%s", plan)
+		}
+
+		jp, _ = New("$.store.book[0:2].title")
+		if plan := jp.Explain(); len(plan.Warnings) != 0 {
+			t.Errorf("Expected no warnings, got %v", plan.Warnings)
+		}
+	})
+
+	t.Run("Standard", func(t *testing.T) {
+		jp, err := NewJSONPathEngine().CompileStandard("$..a..*")
+		if err != nil {
+			t.Fatalf("CompileStandard failed: %v", err)
+		}
+		plan := jp.Explain()
+		if len(plan.Steps) != 5 || len(plan.Warnings) != 2 {
+			t.Errorf("Expected 5 steps and 2 warnings, got:\n%s", plan)
+		}
+
+		// Filter steps are scored with their expression, as under Compile
+		standard, err := NewJSONPathEngine().CompileStandard("$.store..book[?@.price < 10].title")
+		if err != nil {
+			t.Fatalf("CompileStandard failed: %v", err)
+		}
+		compiled, _ := New("$.store..book[?(@.price < 10)].title")
+		if got, want := standard.Explain().Steps[4].Cost, compiled.Explain().Steps[4].Cost; got != want {
+			t.Errorf("Expected the standard filter to cost %d, got %d", want, got)
+		}
+
+		data, _ := JSONParse(`{"store":{"book":[{"price":5,"title":"a"},{"price":15,"title":"b"},{"price":8,"title":"c"}]}}`)
+		plan, err = standard.ExplainAnalyze(data)
+		if err != nil {
+			t.Fatalf("ExplainAnalyze failed: %v", err)
+		}
+		// Selectors are evaluated once per value they are applied to
+		analyzed := []struct{ evaluations, inputs, outputs int }{{1, 1, 1}, {1, 1, 1}, {1, 1, 11}, {11, 11, 1}, {1, 1, 2}, {2, 2, 2}}
+		for i, step := range plan.Steps {
+			want := analyzed[i]
+			if step.Evaluations != want.evaluations || step.Inputs != want.inputs || step.Outputs != want.outputs {
+				t.Errorf("Step %d (%s): expected evaluations=%d in=%d out=%d\n%s", i, step.Kind, want.evaluations, want.inputs, want.outputs, plan)
+			}
+		}
+	})
+
+	t.Run("Analyze", func(t *testing.T) {
+		data, _ := JSONParse(`{"store":{"book":[{"price":5,"title":"a"},{"price":15,"title":"b"},{"price":8,"title":"c"}]}}`)
+		jp, err := New("$.store..book[?(@.price < 10)].title")
+		if err != nil {
+			t.Fatalf("New failed: %v", err)
+		}
+		plan, err := jp.ExplainAnalyze(data)
+		if err != nil {
+			t.Fatalf("ExplainAnalyze failed: %v", err)
+		}
+		if !plan.Analyzed {
+			t.Error("Expected an analyzed plan")
+		}
+
+		// The descent visits store and the ten values below it, book is tried at each of
+		// them and the filter keeps two books
+		inputs := []int{1, 1, 1, 11, 1, 2}
+		outputs := []int{1, 1, 11, 1, 2, 2}
+		for i, step := range plan.Steps {
+			if step.Inputs != inputs[i] || step.Outputs != outputs[i] {
+				t.Errorf("Step %d (%s): expected in=%d out=%d, got in=%d out=%d\n%s",
+					i, step.Kind, inputs[i], outputs[i], step.Inputs, step.Outputs, plan)
+			}
+		}
+	})
+}
@@ -0,0 +1,414 @@
+package jsonpathplus
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/reclaimprotocol/jsonpathplus-go/internal/filters"
+)
+
+const filterTestJSON = `{"limit": 10, "store": {"book": [
+	{"category": "reference", "author": "Nigel Rees", "title": "Sayings of the Century", "price": 8.95, "discount": 9, "tags": []},
+	{"category": "fiction", "author": "Evelyn Waugh", "title": "Sword of Honour", "price": 12.99, "discount": 2, "tags": ["a", "b"]},
+	{"category": "fiction", "author": "Herman Melville", "title": "Moby Dick", "isbn": "0-553-21311-3", "price": 8.99, "tags": ["a"]},
+	{"category": "fiction", "author": "J. R. R. Tolkien", "title": "The Lord of the Rings", "isbn": "0-395-19395-8", "price": 22.99, "tags": ["c"]}
+]}}`
+
+// TestFilterExpressions tests filter expressions against results produced by JSONPath-Plus
+func TestFilterExpressions(t *testing.T) {
+	tests := []struct {
+		filter   string
+		expected []int // Indices of the matching books
+	}{
+		{"@.price > 10 && (@.category == 'fiction' || @.author.length > 12)", []int{1, 3}},
+		{"@.price < @.discount", []int{0}},
+		{"@.price * 2 > 20", []int{1, 3}},
+		{"@.price - @.discount > 0", []int{1}},
+		{"!(@.price > 10)", []int{0, 2}},
+		{"@.price > 10 ? @.isbn : true", []int{0, 2, 3}},
+		{"typeof @.isbn === 'undefined'", []int{0, 1}},
+		{"@.price == '8.95'", []int{0}},
+		{"@.price === '8.95'", []int{}},
+		{"@property == 1", []int{1}},
+		{"@.category + '!' === 'fiction!'", []int{1, 2, 3}},
+		{"@['price'] < 9", []int{0, 2}},
+		{"-@.price < -20", []int{3}},
+		{"@.author.match(/^N/)", []int{0}},
+		{"@.author.match('^H')", []int{2}},
+		{"@.category.match(@.tags[0])", []int{3}},
+		{"@.price.toFixed(0) === '9'", []int{0, 2}},
+		{"@.price.toFixed('x') === '9'", []int{0, 2}},
+		{"/tolkien/i.test(@.author)", []int{3}},
+		{"@.title.toLowerCase().includes('the')", []int{0, 3}},
+		{"@.tags.length === 0", []int{0}},
+		{"@.tags.includes('a')", []int{1, 2}},
+		{"@.tags", []int{0, 1, 2, 3}},
+		{"@.tags[?(@ === 'c')]", []int{3}},
+		{"@.missing.deep", []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			got, err := Evaluate("$.store.book[?("+tt.filter+")]", filterTestJSON, &Options{ResultType: ResultTypeParentProperty})
+			if err != nil {
+				t.Fatalf("Evaluate failed: %v", err)
+			}
+			indices := []int{}
+			for _, prop := range got {
+				indices = append(indices, prop.(int))
+			}
+			if !reflect.DeepEqual(indices, tt.expected) {
+				t.Errorf("Expected books %v, got %v", tt.expected, indices)
+			}
+		})
+	}
+}
+
+// TestFilterErrors tests that broken filters report errors instead of matching nothing
+func TestFilterErrors(t *testing.T) {
+	syntaxErrors := []string{
+		"$.store.book[?(@.price ==)]",
+		"$.store.book[?(@.tags[*] === 'a')]",
+		"$.store.book[?(@.price = 10)]",
+		"$.store.book[?(@.title.match(/(?<=S)w/))]",
+		"$.store.book[?(@unknown)]",
+	}
+	for _, path := range syntaxErrors {
+		_, err := Query(path, filterTestJSON)
+		if !errors.Is(err, &JSONPathError{Type: ErrInvalidExpression}) {
+			t.Errorf("%s: expected ErrInvalidExpression, got %v", path, err)
+		}
+	}
+
+	runtimeErrors := []struct {
+		path    string
+		json    string
+		message string
+	}{
+		{"$[?(@.length > 3)]", `[[1, 2, 3, 4], null]`, "Cannot read properties of null (reading 'length')"},
+		{"$.store.book[?(eval('1'))]", filterTestJSON, "eval is not defined"},
+		{"$.store.book[?(@.title.nosuch())]", filterTestJSON, "is not a function"},
+		{"$.store.book[?(@.price.startsWith(8))]", filterTestJSON, "is not a function"},
+		{"$.store.book[?(@.price.toFixed(1e9))]", filterTestJSON, "RangeError: toFixed() digits argument must be between 0 and 100"},
+		{"$.store.book[?(@.price.toFixed(-1))]", filterTestJSON, "RangeError: toFixed() digits argument"},
+	}
+	for _, tt := range runtimeErrors {
+		_, err := Query(tt.path, tt.json)
+		if !errors.Is(err, &JSONPathError{Type: ErrEvaluationError}) {
+			t.Errorf("%s: expected ErrEvaluationError, got %v", tt.path, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.message) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.path, tt.message, err)
+		}
+	}
+}
+
+// TestFilterCompiledAtParseTime tests that filters are compiled by New and Validate
+func TestFilterCompiledAtParseTime(t *testing.T) {
+	jp, err := New("$.store.book[?(@.price < 10)].title")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	filter := jp.AST().Children[0].Children[0].Children[0]
+	if filter.Type != "filter" || filter.Expr == nil {
+		t.Fatalf("Expected a compiled filter node, got %v", filter)
+	}
+
+	// String patterns of match are compiled with the filter, not per value
+	jp, err = New("$.store.book[?(@.author.match('^N'))]")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	call, ok := jp.AST().Children[0].Children[0].Children[0].Expr.(*filters.Call)
+	if !ok || call.Pattern == nil || call.Pattern.String() != "^N" {
+		t.Errorf("Expected the pattern of match to be compiled, got %#v", jp.AST().Children[0].Children[0].Children[0].Expr)
+	}
+
+	path := "$..book[?(@.price < )]"
+	if _, err := New(path); !errors.Is(err, &JSONPathError{Type: ErrInvalidExpression}) {
+		t.Errorf("New: expected ErrInvalidExpression, got %v", err)
+	}
+
+	err = Validate(path)
+	var pathErr *JSONPathError
+	if !errors.As(err, &pathErr) || pathErr.Type != ErrInvalidExpression {
+		t.Fatalf("Validate: expected ErrInvalidExpression, got %v", err)
+	}
+	if pathErr.Position != strings.Index(path, ")]") {
+		t.Errorf("Expected error at position %d, got %d", strings.Index(path, ")]"), pathErr.Position)
+	}
+}
+
+// TestFilterFunctionExtensions tests the RFC 9535 function extensions inside filters
+func TestFilterFunctionExtensions(t *testing.T) {
+	tests := []struct {
+		filter   string
+		expected []int
+	}{
+		{"length(@.title) > 15", []int{0, 3}},
+		{"length(@.tags) == 1", []int{2, 3}},
+		{"length(@.missing) == 0", []int{}},
+		{"count(@.tags.*) > 0", []int{1, 2, 3}},
+		{"count(@..*) == 8", []int{1}},
+		{"count(@.tags[?(@ == 'a')]) == 1", []int{1, 2}},
+		{"match(@.category, 'fic.*')", []int{1, 2, 3}},
+		{"match(@.category, 'fic')", []int{}},
+		{"search(@.author, 'R. R')", []int{3}},
+		{"!search(@.title, '[Tt]he')", []int{1, 2}},
+		{"value(@.tags[-1]) == 'a'", []int{2}},
+		{"value(@..isbn) == '0-553-21311-3'", []int{2}},
+		{"length(@.isbn) > 0 && @.price < 10", []int{2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			got, err := Evaluate("$.store.book[?("+tt.filter+")]", filterTestJSON, &Options{ResultType: ResultTypeParentProperty})
+			if err != nil {
+				t.Fatalf("Evaluate failed: %v", err)
+			}
+			indices := []int{}
+			for _, prop := range got {
+				indices = append(indices, prop.(int))
+			}
+			if !reflect.DeepEqual(indices, tt.expected) {
+				t.Errorf("Expected books %v, got %v", tt.expected, indices)
+			}
+		})
+	}
+
+	// Ill-typed calls are rejected when the path is compiled
+	illTyped := []string{
+		"$.store.book[?(length(@.title))]",
+		"$.store.book[?(match(@.title, 'S.*') == true)]",
+		"$.store.book[?(length(@.tags.*) > 1)]",
+		"$.store.book[?(count(@.title, 1) > 1)]",
+		"$.store.book[?(count('abc') > 1)]",
+		"$.store.book[?(@.tags.* == 'a')]",
+	}
+	for _, path := range illTyped {
+		if _, err := New(path); !errors.Is(err, &JSONPathError{Type: ErrInvalidExpression}) {
+			t.Errorf("%s: expected ErrInvalidExpression, got %v", path, err)
+		}
+	}
+}
+
+// TestTypeSelectors tests the JSONPath-Plus type selectors in paths and in filters
+func TestTypeSelectors(t *testing.T) {
+	jsonStr := `{"a": 1, "b": 2.5, "c": "x", "d": true, "e": null, "f": [3, "y"], "g": {"h": -4}}`
+	tests := []struct {
+		path     string
+		expected []string
+	}{
+		{"$..*@number()", []string{"$['a']", "$['b']", "$['f'][0]", "$['g']['h']"}},
+		{"$..*@integer()", []string{"$['a']", "$['f'][0]", "$['g']['h']"}},
+		{"$..*@string()", []string{"$['c']", "$['f'][1]"}},
+		{"$..*@boolean()", []string{"$['d']"}},
+		{"$..*@null()", []string{"$['e']"}},
+		{"$..*@scalar()", []string{"$['a']", "$['b']", "$['c']", "$['d']", "$['e']", "$['f'][0]", "$['f'][1]", "$['g']['h']"}},
+		{"$..*@array()", []string{"$['f']"}},
+		{"$..*@object()", []string{"$['f']", "$['g']"}},
+		{"$..*@other()", []string{}},
+		{"$..@object()", []string{"$", "$['f']", "$['g']"}},
+		{"$.f[*]@string()", []string{"$['f'][1]"}},
+		{"$.b@integer()", []string{}},
+		{"$.f@array()[0]", []string{"$['f'][0]"}},
+		{"$.*[?(@number())]", []string{"$['a']", "$['b']"}},
+		{"$.f[?(@string() || @integer() && @ > 5)]", []string{"$['f'][1]"}},
+		{"$.*[?(!@scalar())]", []string{"$['f']", "$['g']"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			results, err := Query(tt.path, jsonStr)
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			paths := []string{}
+			for _, result := range results {
+				paths = append(paths, result.Path)
+			}
+			if !reflect.DeepEqual(paths, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, paths)
+			}
+		})
+	}
+
+	if err := NewSecurityValidator(DefaultSecurityConfig()).ValidatePath("$..*[?(@number())]"); err != nil {
+		t.Errorf("Expected type selectors to pass validation, got %v", err)
+	}
+	if _, err := New("$..*[?(@numbers())]"); !errors.Is(err, &JSONPathError{Type: ErrInvalidExpression}) {
+		t.Errorf("Expected ErrInvalidExpression for an unknown type selector, got %v", err)
+	}
+}
+
+// TestContextVariables tests context variables used as values, against results
+// produced by JSONPath-Plus
+func TestContextVariables(t *testing.T) {
+	jsonStr := `{"config": {"threshold": 5}, "limit": 4, "users": [{"name": "a", "score": 3, "count": 1},
+		{"name": "b", "score": 7, "count": 9}], "teams": {"x": {"count": 3}, "y": {"count": 6}}}`
+	tests := []struct {
+		path     string
+		expected []string
+	}{
+		{"$.users[?(@root.config.threshold < @.score)]", []string{"$['users'][1]"}},
+		{"$.users[?(@.name === @root.users[1].name)]", []string{"$['users'][1]"}},
+		{"$.users[?(@root === $)]", []string{"$['users'][0]", "$['users'][1]"}},
+		{"$.users[?(count(@root.users[*]) == 2 && @.score > 5)]", []string{"$['users'][1]"}},
+		{"$.users[?(@parent.limit > @.count)]", []string{"$['users'][0]"}},
+		{"$.users[?(@parentProperty === 'users')]", []string{"$['users'][0]", "$['users'][1]"}},
+		{"$.teams.x[?(@parentProperty === 'x')]", []string{"$['teams']['x']['count']"}},
+		{"$.users[?(@path === \"$['users'][1]\")]", []string{"$['users'][1]"}},
+		{"$.users[?(@path.endsWith('[0]') || @property + 1 === 2)]", []string{"$['users'][0]", "$['users'][1]"}},
+		{"$.teams[?(@property.toUpperCase() === 'Y')]", []string{"$['teams']['y']"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			results, err := Query(tt.path, jsonStr)
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			paths := []string{}
+			for _, result := range results {
+				paths = append(paths, result.Path)
+			}
+			if !reflect.DeepEqual(paths, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, paths)
+			}
+		})
+	}
+}
+
+// TestScriptSelectors tests [(expr)] selectors against results produced by JSONPath-Plus
+func TestScriptSelectors(t *testing.T) {
+	jsonStr := `{"store": {"book": [{"t": "a", "k": "t"}, {"t": "b", "k": "k"}, {"t": "c", "k": "t"}], "pick": "book"},
+		"arr": [[1, 2], [3, 4, 5]]}`
+	tests := []struct {
+		path     string
+		expected []string
+	}{
+		{"$.store.book[(@.length-1)]", []string{"$['store']['book'][2]"}},
+		{"$.store.book[(@.length-1)].t", []string{"$['store']['book'][2]['t']"}},
+		{"$.store[(@.pick)][0]", []string{"$['store']['book'][0]"}},
+		{"$.store.book[*][(@.k)]", []string{"$['store']['book'][0]['t']", "$['store']['book'][1]['k']", "$['store']['book'][2]['t']"}},
+		{"$..book[(@.length-2)]", []string{"$['store']['book'][1]"}},
+		{"$.arr[*][(@.length-1)]", []string{"$['arr'][0][1]", "$['arr'][1][2]"}},
+		{"$.store.book[(@.length ? 0 : 1)]", []string{"$['store']['book'][0]"}},
+		{"$.store.book[(@.length-1)][?(@ === 'c')]", []string{"$['store']['book'][2]['t']"}},
+		{"$.store.book[(@.length+1)]", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			results, err := Query(tt.path, jsonStr)
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			paths := []string{}
+			for _, result := range results {
+				paths = append(paths, result.Path)
+			}
+			if !reflect.DeepEqual(paths, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, paths)
+			}
+		})
+	}
+
+	if _, err := New("$.store.book[(@.length -)]"); !errors.Is(err, &JSONPathError{Type: ErrInvalidExpression}) {
+		t.Errorf("Expected ErrInvalidExpression for a broken script, got %v", err)
+	}
+	if _, err := Query("$.store[(@.pick.nope())]", jsonStr); !errors.Is(err, &JSONPathError{Type: ErrEvaluationError}) {
+		t.Errorf("Expected ErrEvaluationError for a failing script, got %v", err)
+	}
+}
+
+// TestEvalModes tests disabling expressions and defining sandbox names for them
+func TestEvalModes(t *testing.T) {
+	sandbox := map[string]interface{}{
+		"limits": map[string]interface{}{"cheap": 9},
+		"genres": []interface{}{"reference"},
+		"initials": FilterFunc(func(args []interface{}) interface{} {
+			name, _ := args[0].(string)
+			var initials string
+			for _, word := range strings.Fields(name) {
+				initials += word[:1]
+			}
+			return initials
+		}),
+		"discounted": func(args []interface{}) interface{} {
+			return args[0] != nil
+		},
+		"rate": 0.5,
+	}
+	sandboxed := &Options{EvalMode: EvalModeSandbox, Sandbox: sandbox, ResultType: ResultTypeParentProperty}
+
+	tests := []struct {
+		path     string
+		expected []interface{}
+	}{
+		{"$.store.book[?(@.price < limits.cheap)]", []interface{}{0, 2}},
+		{"$.store.book[?(genres.includes(@.category))]", []interface{}{0}},
+		{"$.store.book[?(initials(@.author) === 'HM')]", []interface{}{2}},
+		{"$.store.book[?(discounted(@.discount) && @.price * rate < 5)]", []interface{}{0}},
+		{"$.store.book[?(@.tags[?(@ === initials('a b') || @ === 'c')])]", []interface{}{3}},
+		{"$.store.book[(limits.cheap - 7)].author", []interface{}{"author"}},
+	}
+	for _, tt := range tests {
+		got, err := Evaluate(tt.path, filterTestJSON, sandboxed)
+		if err != nil {
+			t.Fatalf("Evaluate %s failed: %v", tt.path, err)
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("Evaluate %s: expected %v, got %v", tt.path, tt.expected, got)
+		}
+	}
+
+	// Sandbox names are only defined in sandbox mode, and only functions can be called
+	if _, err := QueryWithOptions("$.store.book[?(@.price < limits.cheap)]", filterTestJSON, &Options{Sandbox: sandbox}); !errors.Is(err, &JSONPathError{Type: ErrEvaluationError}) {
+		t.Errorf("Expected ErrEvaluationError outside sandbox mode, got %v", err)
+	}
+	if _, err := QueryWithOptions("$.store.book[?(rate())]", filterTestJSON, sandboxed); !errors.Is(err, &JSONPathError{Type: ErrEvaluationError}) {
+		t.Errorf("Expected ErrEvaluationError calling a sandbox value, got %v", err)
+	}
+
+	disabled := &Options{EvalMode: EvalModeDisabled}
+	for _, path := range []string{"$.store.book[?(@.price < 10)]", "$..book[(@.length-1)]", "$.store[?(@)].title"} {
+		if _, err := QueryWithOptions(path, `{"store": {"book": []}}`, disabled); !errors.Is(err, &JSONPathError{Type: ErrInvalidExpression}) {
+			t.Errorf("%s: expected ErrInvalidExpression with expressions disabled, got %v", path, err)
+		}
+	}
+	if _, err := QueryWithOptions("$.store.book[?@.price < 10]", filterTestJSON, &Options{EvalMode: EvalModeDisabled, Standard: true}); !errors.Is(err, &JSONPathError{Type: ErrInvalidExpression}) {
+		t.Errorf("Expected ErrInvalidExpression in standard mode with expressions disabled, got %v", err)
+	}
+	if results, err := QueryWithOptions("$.store.book[0,1].price", filterTestJSON, disabled); err != nil || len(results) != 2 {
+		t.Errorf("Expected paths without expressions to run, got %v, %v", results, err)
+	}
+	if _, err := QueryWithOptions("$.store", filterTestJSON, &Options{EvalMode: "native"}); !errors.Is(err, &JSONPathError{Type: ErrTypeError}) {
+		t.Errorf("Expected ErrTypeError for an unknown eval mode, got %v", err)
+	}
+
+	// A security config decides whether sandbox mode may be used
+	config := DefaultConfig()
+	config.Security = DefaultSecurityConfig()
+	config.Security.EnableSandbox = false
+	engine, err := NewJSONPathEngineWithConfig(config)
+	if err != nil {
+		t.Fatalf("NewJSONPathEngineWithConfig failed: %v", err)
+	}
+	if _, err := engine.QueryWithOptions("$.store.book[?(@.price < limits.cheap)]", filterTestJSON, sandboxed); !errors.Is(err, &JSONPathError{Type: ErrInvalidExpression}) {
+		t.Errorf("Expected ErrInvalidExpression with the sandbox not enabled, got %v", err)
+	}
+	if results, err := engine.QueryWithOptions("$.store.book[?(@.price < 9)]", filterTestJSON, nil); err != nil || len(results) != 2 {
+		t.Errorf("Expected safe mode to run with the sandbox not enabled, got %v, %v", results, err)
+	}
+	config.Security.EnableSandbox = true
+	if engine, err = NewJSONPathEngineWithConfig(config); err != nil {
+		t.Fatalf("NewJSONPathEngineWithConfig failed: %v", err)
+	}
+	if results, err := engine.QueryWithOptions("$.store.book[?(@.price < limits.cheap)]", filterTestJSON, sandboxed); err != nil || len(results) != 2 {
+		t.Errorf("Expected sandbox mode to run with the sandbox enabled, got %v, %v", results, err)
+	}
+}
@@ -0,0 +1,112 @@
+package jsonpathplus
+
+import (
+	"github.com/reclaimprotocol/jsonpathplus-go/internal/rfc9535"
+)
+
+// FunctionType is the declared type of a function parameter or result (RFC 9535 section 2.4.1)
+type FunctionType = rfc9535.Type
+
+const (
+	// ValueType arguments are a JSON value or Nothing
+	ValueType = rfc9535.ValueType
+	// LogicalType arguments are true or false
+	LogicalType = rfc9535.LogicalType
+	// NodesType arguments are the Nodes a query selected
+	NodesType = rfc9535.NodesType
+)
+
+// Nothing is passed for a ValueType argument that has no value, such as a query that
+// selects no node. A function returns it when its result is undefined; comparisons
+// with Nothing only hold for ==, against another Nothing.
+var Nothing = rfc9535.Nothing
+
+// Nodes is the argument passed for a NodesType parameter
+type Nodes = rfc9535.Nodes
+
+// FilterFunc implements a custom filter function. It receives one argument per
+// parameter of its signature: a value or Nothing for ValueType, a bool for LogicalType
+// and Nodes for NodesType.
+type FilterFunc func(args []interface{}) interface{}
+
+// FunctionSignature declares the parameter and result types of a custom filter function
+type FunctionSignature struct {
+	Params []FunctionType
+	Result FunctionType
+}
+
+// reservedNames cannot be used as function names because filters read them as literals
+// or operators
+var reservedNames = map[string]bool{
+	"true": true, "false": true, "null": true, "undefined": true,
+	"NaN": true, "Infinity": true, "typeof": true,
+}
+
+// RegisterFunction makes fn callable from the filters of this engine as name(args...).
+// Calls are checked against signature when a path is compiled: the number of
+// arguments, queries passed for NodesType parameters and, as for the RFC 9535
+// functions, that a ValueType result is compared and a LogicalType result is not.
+// Names made of lowercase letters, digits and underscores, starting with a letter,
+// are also callable in standard mode.
+//
+// A SecurityValidator only accepts calls of functions listed in
+// SecurityConfig.AllowedFunctions, so add name there when validating paths.
+func (engine *JSONPathEngine) RegisterFunction(name string, fn FilterFunc, signature FunctionSignature) error {
+	if !isFunctionName(name) || reservedNames[name] {
+		return &ValidationError{Field: "name", Value: name, Message: "must be an identifier such as isEmail"}
+	}
+	if fn == nil {
+		return &ValidationError{Field: "fn", Value: name, Message: "must not be nil"}
+	}
+
+	params := append([]FunctionType(nil), signature.Params...)
+	result := signature.Result
+	err := engine.functions.Register(&rfc9535.Function{
+		Name:   name,
+		Params: params,
+		Result: result,
+		Call: func(args []interface{}) interface{} {
+			return conformResult(fn(args), result)
+		},
+	})
+	if err != nil {
+		return &ValidationError{Field: "signature", Value: name, Message: err.Error()}
+	}
+	// Paths compiled before may have parsed a call of name as a sandbox call
+	if engine.cache != nil {
+		engine.cache.Purge()
+	}
+	return nil
+}
+
+// conformResult makes the value a custom function returned fit its declared result
+// type, so that a misbehaving function cannot break the evaluation
+func conformResult(value interface{}, result FunctionType) interface{} {
+	switch result {
+	case LogicalType:
+		b, _ := value.(bool)
+		return b
+	case NodesType:
+		nodes, _ := value.(Nodes)
+		return nodes
+	default:
+		if _, ok := value.(Nodes); ok {
+			return Nothing
+		}
+		return value
+	}
+}
+
+// isFunctionName reports whether name is an identifier filters can call
+func isFunctionName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		letter := c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+		if !letter && (i == 0 || c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
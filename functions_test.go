@@ -0,0 +1,123 @@
+package jsonpathplus
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRegisterFunction(t *testing.T) {
+	jsonStr := `{"users":[{"name":"a","email":"a@example.com","tags":["admin","ops"]},` +
+		`{"name":"b","email":"not-an-email","tags":["ops"]},{"name":"c","tags":[]}]}`
+
+	engine := NewJSONPathEngine()
+	isEmail := func(args []interface{}) interface{} {
+		s, ok := args[0].(string)
+		return ok && strings.Count(s, "@") == 1 && !strings.HasPrefix(s, "@")
+	}
+	if err := engine.RegisterFunction("isEmail", isEmail, FunctionSignature{
+		Params: []FunctionType{ValueType}, Result: LogicalType,
+	}); err != nil {
+		t.Fatalf("RegisterFunction(isEmail) failed: %v", err)
+	}
+	hasTag := func(args []interface{}) interface{} {
+		for _, node := range args[0].(Nodes) {
+			if node == args[1] {
+				return true
+			}
+		}
+		return false
+	}
+	if err := engine.RegisterFunction("has_tag", hasTag, FunctionSignature{
+		Params: []FunctionType{NodesType, ValueType}, Result: LogicalType,
+	}); err != nil {
+		t.Fatalf("RegisterFunction(has_tag) failed: %v", err)
+	}
+	missing := func(args []interface{}) interface{} {
+		return args[0] == Nothing
+	}
+	if err := engine.RegisterFunction("missing", missing, FunctionSignature{
+		Params: []FunctionType{ValueType}, Result: LogicalType,
+	}); err != nil {
+		t.Fatalf("RegisterFunction(missing) failed: %v", err)
+	}
+
+	t.Run("Calls", func(t *testing.T) {
+		tests := []struct {
+			path     string
+			options  *Options
+			expected []interface{}
+		}{
+			{"$.users[?(isEmail(@.email))].name", nil, []interface{}{"a"}},
+			{"$.users[?(!isEmail(@.email) && @.email)].name", nil, []interface{}{"b"}},
+			{"$.users[?(has_tag(@.tags[*], 'ops'))].name", nil, []interface{}{"a", "b"}},
+			{"$.users[?(missing(@.email))].name", nil, []interface{}{"c"}},
+			{"$.users[?has_tag(@.tags[*], 'admin')].name", &Options{Standard: true}, []interface{}{"a"}},
+		}
+		for _, test := range tests {
+			results, err := engine.QueryWithOptions(test.path, jsonStr, test.options)
+			if err != nil {
+				t.Fatalf("Query %s failed: %v", test.path, err)
+			}
+			values := make([]interface{}, len(results))
+			for i, result := range results {
+				values[i] = result.Value
+			}
+			if !reflect.DeepEqual(values, test.expected) {
+				t.Errorf("Query %s: expected %v, got %v", test.path, test.expected, values)
+			}
+		}
+	})
+
+	t.Run("CompileTimeChecks", func(t *testing.T) {
+		paths := []string{
+			"$.users[?(isEmail(@.email, 1))]",
+			"$.users[?(isEmail())]",
+			"$.users[?(isEmail(@.email) == true)]",
+			"$.users[?(has_tag('admin', 'ops'))]",
+			"$.users[?(isEmail(@.tags[*]))]",
+		}
+		for _, path := range paths {
+			if _, err := engine.Compile(path); !errors.Is(err, &JSONPathError{Type: ErrInvalidExpression}) {
+				t.Errorf("Compile %s: expected ErrInvalidExpression, got %v", path, err)
+			}
+		}
+	})
+
+	t.Run("EngineScope", func(t *testing.T) {
+		// Other engines do not see the function, so calling it is a runtime error as in JavaScript
+		_, err := Query("$.users[?(isEmail(@.email))]", jsonStr)
+		if !errors.Is(err, &JSONPathError{Type: ErrEvaluationError}) {
+			t.Errorf("Expected ErrEvaluationError from another engine, got %v", err)
+		}
+	})
+
+	t.Run("InvalidRegistrations", func(t *testing.T) {
+		signature := FunctionSignature{Params: []FunctionType{ValueType}, Result: LogicalType}
+		for _, name := range []string{"", "1st", "is-email", "typeof", "null", "isEmail", "length"} {
+			if err := engine.RegisterFunction(name, isEmail, signature); err == nil {
+				t.Errorf("RegisterFunction(%q): expected error", name)
+			}
+		}
+		if err := engine.RegisterFunction("nilFunc", nil, signature); err == nil {
+			t.Error("RegisterFunction with a nil function: expected error")
+		}
+		bad := FunctionSignature{Params: []FunctionType{FunctionType(7)}, Result: LogicalType}
+		if err := engine.RegisterFunction("badType", isEmail, bad); err == nil {
+			t.Error("RegisterFunction with an invalid parameter type: expected error")
+		}
+	})
+
+	t.Run("SecurityAllowList", func(t *testing.T) {
+		path := "$.users[?(isEmail(@.email))]"
+		config := DefaultSecurityConfig()
+		if err := NewSecurityValidator(config).ValidatePath(path); err == nil {
+			t.Error("Expected the default allow-list to reject isEmail")
+		}
+		config.AllowedFunctions = append(config.AllowedFunctions, "isEmail")
+		if err := NewSecurityValidator(config).ValidatePath(path); err != nil {
+			t.Errorf("Expected isEmail to be allowed, got %v", err)
+		}
+	})
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/reclaimprotocol/jsonpathplus-go/internal/filters"
 	"github.com/reclaimprotocol/jsonpathplus-go/internal/operators"
+	"github.com/reclaimprotocol/jsonpathplus-go/internal/rfc9535"
 	"github.com/reclaimprotocol/jsonpathplus-go/pkg/types"
 	"github.com/reclaimprotocol/jsonpathplus-go/pkg/utils"
 )
@@ -20,16 +21,36 @@ func formatPath(basePath, key string, isArrayIndex bool) string {
 		}
 	}
 
-	// JavaScript JSONPath-Plus converts numeric string keys to numeric format in paths
-	// e.g., $['users']['1'] becomes $['users'][1] to match JS behavior
-	if _, err := strconv.Atoi(key); err == nil {
-		if idx, err := strconv.Atoi(key); err == nil {
-			return fmt.Sprintf("%s[%d]", basePath, idx)
-		}
+	// JavaScript JSONPath-Plus writes keys made only of digits without quotes, e.g.
+	// $['users'][1]. The key is kept verbatim, so "01" and "1" stay $[01] and $[1];
+	// signs and spaces are quoted like any other key.
+	if isDigits(key) {
+		return basePath + "[" + key + "]"
 	}
 
 	// Non-numeric object keys use quoted strings: $['users']['name']
-	return fmt.Sprintf("%s['%s']", basePath, key)
+	return memberPath(basePath, key)
+}
+
+// isDigits reports whether key is a non-empty run of ASCII digits
+func isDigits(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < '0' || key[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// memberPath appends a quoted member name to a path, escaping backslashes and
+// single quotes so that a key such as it's cannot end the name early
+func memberPath(basePath, key string) string {
+	key = strings.ReplaceAll(key, `\`, `\\`)
+	key = strings.ReplaceAll(key, `'`, `\'`)
+	return basePath + "['" + key + "']"
 }
 
 // Evaluator handles JSONPath expression evaluation
@@ -37,18 +58,33 @@ type Evaluator struct {
 	filterEval     *filters.FilterEvaluator
 	operatorEval   *operators.OperatorEvaluator
 	contextualEval *operators.ContextualEvaluator
+	limits         Limits
+	run            *run // per-evaluation bookkeeping, only set on the copy made by Run
 }
 
 // NewEvaluator creates a new evaluator
 func NewEvaluator() *Evaluator {
+	return NewEvaluatorWithLimits(Limits{})
+}
+
+// NewEvaluatorWithLimits creates a new evaluator whose Run enforces the given limits
+func NewEvaluatorWithLimits(limits Limits) *Evaluator {
+	return NewEvaluatorWithFunctions(limits, nil)
+}
+
+// NewEvaluatorWithFunctions creates an evaluator that enforces limits and whose
+// filters may call the function extensions in functions
+func NewEvaluatorWithFunctions(limits Limits, functions *rfc9535.Registry) *Evaluator {
 	return &Evaluator{
-		filterEval:     filters.NewFilterEvaluator(),
+		filterEval:     filters.NewFilterEvaluatorWithFunctions(functions),
 		operatorEval:   operators.NewOperatorEvaluator(),
 		contextualEval: operators.NewContextualEvaluator(),
+		limits:         limits,
 	}
 }
 
-// Evaluate evaluates an AST against data
+// Evaluate evaluates an AST against data.
+// Filter errors abort with a panic that only Run recovers, so callers should prefer Run.
 func (e *Evaluator) Evaluate(ast *types.AstNode, data interface{}, options *types.Options) []types.Result {
 	if options == nil {
 		options = &types.Options{}
@@ -71,9 +107,11 @@ func (e *Evaluator) Evaluate(ast *types.AstNode, data interface{}, options *type
 	return e.evaluateNode(ast, []types.Result{rootResult}, options)
 }
 
-// evaluateNode evaluates a single AST node
-func (e *Evaluator) evaluateNode(node *types.AstNode, contexts []types.Result, options *types.Options) []types.Result {
-	var results []types.Result
+// evaluateNode evaluates a single AST node against each of contexts
+func (e *Evaluator) evaluateNode(node *types.AstNode, contexts []types.Result, options *types.Options) (results []types.Result) {
+	if end := e.startSpan(node, len(contexts)); end != nil {
+		defer func() { end(len(results)) }()
+	}
 
 	// Special handling for filters applied to multiple contexts (e.g., after wildcard)
 	if node.Type == "filter" && len(contexts) > 1 {
@@ -81,36 +119,59 @@ func (e *Evaluator) evaluateNode(node *types.AstNode, contexts []types.Result, o
 	}
 
 	for _, ctx := range contexts {
-		nodeResults := e.evaluateSingleNode(node, ctx, options)
-		results = append(results, nodeResults...)
+		results = append(results, e.evaluateChild(node, ctx, options)...)
+		e.limitResults(len(results))
 	}
 
 	return results
 }
 
+// evaluateChild evaluates node against ctx as evaluateNode does for each of its
+// contexts, without a span of its own
+func (e *Evaluator) evaluateChild(node *types.AstNode, ctx types.Result, options *types.Options) []types.Result {
+	e.checkpoint()
+	results := e.evaluateSingleNode(node, ctx, options)
+	e.charge(results...)
+	return results
+}
+
 // evaluateFilterOnResults applies a filter to a collection of results
 func (e *Evaluator) evaluateFilterOnResults(node *types.AstNode, contexts []types.Result, options *types.Options) []types.Result {
 	var results []types.Result
 
 	for _, ctx := range contexts {
+		e.checkpoint()
+		e.count(node.Type)
 		// Create enhanced context for filter evaluation
 		itemContext := e.contextualEval.CreateContext(ctx, options.Root)
 
-		if e.filterEval.EvaluateFilter(node.Value, itemContext) {
+		if e.matchFilter(node, itemContext, options) {
 			if len(node.Children) > 0 {
 				childResults := e.evaluateNode(node.Children[0], []types.Result{ctx}, options)
 				results = append(results, childResults...)
 			} else {
 				results = append(results, ctx)
 			}
+			e.limitResults(len(results))
 		}
 	}
 
 	return results
 }
 
+// matchFilter reports whether a filter holds for ctx, aborting the evaluation when
+// the filter cannot be parsed or fails at runtime
+func (e *Evaluator) matchFilter(node *types.AstNode, ctx *types.Context, options *types.Options) bool {
+	matched, err := e.filterEval.EvaluateFilter(node, withSandbox(ctx, options), filterDepth{e})
+	if err != nil {
+		e.fail(err)
+	}
+	return matched
+}
+
 // evaluateSingleNode evaluates a node against a single context
 func (e *Evaluator) evaluateSingleNode(node *types.AstNode, ctx types.Result, options *types.Options) []types.Result {
+	e.count(node.Type)
 	switch node.Type {
 	case "root":
 		return e.evaluateRoot(node, ctx, options)
@@ -136,6 +197,10 @@ func (e *Evaluator) evaluateSingleNode(node *types.AstNode, ctx types.Result, op
 		return e.evaluatePropertyNames(node, ctx, options)
 	case "parent":
 		return e.evaluateParent(node, ctx, options)
+	case "type_selector":
+		return e.evaluateTypeSelector(node, ctx, options)
+	case "script":
+		return e.evaluateScript(node, ctx, options)
 	default:
 		return nil
 	}
@@ -176,6 +241,7 @@ func (e *Evaluator) evaluateProperty(node *types.AstNode, ctx types.Result, opti
 			result := types.Result{
 				Value:          value,
 				Path:           formatPath(ctx.Path, property, false),
+				Pointer:        utils.MemberPointer(ctx.Pointer, property),
 				Parent:         ctx.Value,
 				ParentProperty: property,
 				Index:          0,
@@ -192,6 +258,7 @@ func (e *Evaluator) evaluateProperty(node *types.AstNode, ctx types.Result, opti
 			result := types.Result{
 				Value:          value,
 				Path:           formatPath(ctx.Path, property, false),
+				Pointer:        utils.MemberPointer(ctx.Pointer, property),
 				Parent:         ctx.Value,
 				ParentProperty: property,
 				Index:          0,
@@ -209,6 +276,7 @@ func (e *Evaluator) evaluateProperty(node *types.AstNode, ctx types.Result, opti
 			result := types.Result{
 				Value:          v[idx],
 				Path:           formatPath(ctx.Path, strconv.Itoa(idx), true),
+				Pointer:        utils.ElementPointer(ctx.Pointer, idx),
 				Parent:         ctx.Value,
 				ParentProperty: strconv.Itoa(idx),
 				Index:          idx,
@@ -232,9 +300,11 @@ func (e *Evaluator) evaluateWildcard(node *types.AstNode, ctx types.Result, opti
 	case *utils.OrderedMap:
 		index := 0
 		v.Range(func(key string, value interface{}) bool {
+			e.checkpoint()
 			result := types.Result{
 				Value:          value,
 				Path:           formatPath(ctx.Path, key, false),
+				Pointer:        utils.MemberPointer(ctx.Pointer, key),
 				Parent:         ctx.Value,
 				ParentProperty: key, // Use the property name itself
 				Index:          index,
@@ -247,9 +317,11 @@ func (e *Evaluator) evaluateWildcard(node *types.AstNode, ctx types.Result, opti
 	case map[string]interface{}:
 		index := 0
 		for key, value := range v {
+			e.checkpoint()
 			result := types.Result{
 				Value:          value,
 				Path:           formatPath(ctx.Path, key, false),
+				Pointer:        utils.MemberPointer(ctx.Pointer, key),
 				Parent:         ctx.Value,
 				ParentProperty: key, // Use the property name itself
 				Index:          index,
@@ -267,6 +339,7 @@ func (e *Evaluator) evaluateWildcard(node *types.AstNode, ctx types.Result, opti
 		if isPropertyWildcard {
 			// Property wildcard: $.store.book.* should return all properties of all books
 			for i, value := range v {
+				e.checkpoint()
 				if orderedMap, ok := value.(*utils.OrderedMap); ok {
 					// For each OrderedMap in the array, add all its properties in order
 					propIndex := 0
@@ -274,6 +347,7 @@ func (e *Evaluator) evaluateWildcard(node *types.AstNode, ctx types.Result, opti
 						result := types.Result{
 							Value:          propValue,
 							Path:           fmt.Sprintf("%s[%d].%s", ctx.Path, i, key),
+							Pointer:        utils.MemberPointer(utils.ElementPointer(ctx.Pointer, i), key),
 							Parent:         value,
 							ParentProperty: strconv.Itoa(i), // Array index of this book
 							Index:          propIndex,
@@ -294,6 +368,7 @@ func (e *Evaluator) evaluateWildcard(node *types.AstNode, ctx types.Result, opti
 						result := types.Result{
 							Value:          propValue,
 							Path:           fmt.Sprintf("%s[%d].%s", ctx.Path, i, key),
+							Pointer:        utils.MemberPointer(utils.ElementPointer(ctx.Pointer, i), key),
 							Parent:         value,
 							ParentProperty: strconv.Itoa(i), // Array index of this book
 							Index:          propIndex,
@@ -307,6 +382,7 @@ func (e *Evaluator) evaluateWildcard(node *types.AstNode, ctx types.Result, opti
 					result := types.Result{
 						Value:          value,
 						Path:           fmt.Sprintf("%s[%d]", ctx.Path, i),
+						Pointer:        utils.ElementPointer(ctx.Pointer, i),
 						Parent:         ctx.Value,
 						ParentProperty: strconv.Itoa(i),
 						Index:          i,
@@ -318,9 +394,11 @@ func (e *Evaluator) evaluateWildcard(node *types.AstNode, ctx types.Result, opti
 		} else {
 			// Index wildcard: $.store.book[*] should return array elements
 			for i, value := range v {
+				e.checkpoint()
 				result := types.Result{
 					Value:          value,
 					Path:           fmt.Sprintf("%s[%d]", ctx.Path, i),
+					Pointer:        utils.ElementPointer(ctx.Pointer, i),
 					Parent:         ctx.Value,
 					ParentProperty: strconv.Itoa(i),
 					Index:          i,
@@ -347,9 +425,11 @@ func (e *Evaluator) evaluateIndexWildcard(node *types.AstNode, ctx types.Result,
 		// For OrderedMap, index wildcard behaves like property wildcard
 		index := 0
 		v.Range(func(key string, value interface{}) bool {
+			e.checkpoint()
 			result := types.Result{
 				Value:          value,
-				Path:           fmt.Sprintf("%s['%s']", ctx.Path, key),
+				Path:           memberPath(ctx.Path, key),
+				Pointer:        utils.MemberPointer(ctx.Pointer, key),
 				Parent:         ctx.Value,
 				ParentProperty: key,
 				Index:          index,
@@ -363,9 +443,11 @@ func (e *Evaluator) evaluateIndexWildcard(node *types.AstNode, ctx types.Result,
 		// For objects, index wildcard behaves like property wildcard
 		index := 0
 		for key, value := range v {
+			e.checkpoint()
 			result := types.Result{
 				Value:          value,
-				Path:           fmt.Sprintf("%s['%s']", ctx.Path, key),
+				Path:           memberPath(ctx.Path, key),
+				Pointer:        utils.MemberPointer(ctx.Pointer, key),
 				Parent:         ctx.Value,
 				ParentProperty: key,
 				Index:          index,
@@ -377,9 +459,11 @@ func (e *Evaluator) evaluateIndexWildcard(node *types.AstNode, ctx types.Result,
 	case []interface{}:
 		// For arrays, index wildcard returns array elements themselves
 		for i, value := range v {
+			e.checkpoint()
 			result := types.Result{
 				Value:          value,
 				Path:           fmt.Sprintf("%s[%d]", ctx.Path, i),
+				Pointer:        utils.ElementPointer(ctx.Pointer, i),
 				Parent:         ctx.Value,
 				ParentProperty: strconv.Itoa(i),
 				Index:          i,
@@ -420,6 +504,7 @@ func (e *Evaluator) evaluateIndex(node *types.AstNode, ctx types.Result, options
 		result := types.Result{
 			Value:          arr[idx],
 			Path:           fmt.Sprintf("%s[%d]", ctx.Path, idx),
+			Pointer:        utils.ElementPointer(ctx.Pointer, idx),
 			Parent:         ctx.Value,
 			ParentProperty: strconv.Itoa(idx),
 			Index:          idx,
@@ -452,6 +537,7 @@ func (e *Evaluator) evaluateSlice(node *types.AstNode, ctx types.Result, options
 				result := types.Result{
 					Value:          arr[i],
 					Path:           fmt.Sprintf("%s[%d]", ctx.Path, i),
+					Pointer:        utils.ElementPointer(ctx.Pointer, i),
 					Parent:         ctx.Value,
 					ParentProperty: strconv.Itoa(i),
 					Index:          len(results),
@@ -472,6 +558,7 @@ func (e *Evaluator) evaluateSlice(node *types.AstNode, ctx types.Result, options
 				result := types.Result{
 					Value:          arr[i],
 					Path:           fmt.Sprintf("%s[%d]", ctx.Path, i),
+					Pointer:        utils.ElementPointer(ctx.Pointer, i),
 					Parent:         ctx.Value,
 					ParentProperty: strconv.Itoa(i),
 					Index:          len(results),
@@ -497,7 +584,9 @@ func (e *Evaluator) evaluateFilter(node *types.AstNode, ctx types.Result, option
 	// Handle array filtering
 	if arr, ok := ctx.Value.([]interface{}); ok {
 		for i, item := range arr {
+			e.checkpoint()
 			// For array elements:
+			// - The result's parent is the array itself, as in JSONPath-Plus result records
 			// - @parent should refer to the parent of the array (ctx.Parent) for @parent filters
 			// - But for @property to work, we need to know the parent is an array
 			// - @property should be the array index (i)
@@ -505,7 +594,8 @@ func (e *Evaluator) evaluateFilter(node *types.AstNode, ctx types.Result, option
 			itemResult := types.Result{
 				Value:          item,
 				Path:           fmt.Sprintf("%s[%d]", ctx.Path, i),
-				Parent:         ctx.Parent,      // Parent is the parent of the array (for @parent)
+				Pointer:        utils.ElementPointer(ctx.Pointer, i),
+				Parent:         ctx.Value,       // Parent is the array containing the element
 				ParentProperty: strconv.Itoa(i), // Property is the array index (for @property)
 				Index:          i,
 				OriginalIndex:  i,
@@ -513,21 +603,32 @@ func (e *Evaluator) evaluateFilter(node *types.AstNode, ctx types.Result, option
 
 			// Create context with special handling for array elements
 			// We need to track that this element came from an array for @property to work
-			itemContext := e.contextualEval.CreateArrayElementContext(itemResult, options.Root, ctx.Value)
+			itemContext := types.NewArrayElementContext(
+				options.Root,
+				itemResult.Value,
+				ctx.Parent, // @parent is the parent of the array
+				itemResult.ParentProperty,
+				itemResult.Path,
+				itemResult.Index,
+				ctx.Value,
+			)
+			itemContext.ParentOfParentProperty = ctx.ParentProperty // @parentProperty names the array
 
-			if e.filterEval.EvaluateFilter(node.Value, itemContext) {
+			if e.matchFilter(node, itemContext, options) {
 				if len(node.Children) > 0 {
 					childResults := e.evaluateNode(node.Children[0], []types.Result{itemResult}, options)
 					results = append(results, childResults...)
 				} else {
 					results = append(results, itemResult)
 				}
+				e.limitResults(len(results))
 			}
 		}
 	} else if orderedMap, ok := ctx.Value.(*utils.OrderedMap); ok {
 		// Handle OrderedMap filtering
 		index := 0
 		orderedMap.Range(func(key string, value interface{}) bool {
+			e.checkpoint()
 			// For object properties:
 			// - @parent should refer to the object itself (ctx.Value)
 			// - @property should be the object key (key)
@@ -535,6 +636,7 @@ func (e *Evaluator) evaluateFilter(node *types.AstNode, ctx types.Result, option
 			itemResult := types.Result{
 				Value:          value,
 				Path:           formatPath(ctx.Path, key, false),
+				Pointer:        utils.MemberPointer(ctx.Pointer, key),
 				Parent:         ctx.Value, // Parent is the object itself (for @parent)
 				ParentProperty: key,       // Property is the object key (for @property)
 				Index:          index,
@@ -555,13 +657,14 @@ func (e *Evaluator) evaluateFilter(node *types.AstNode, ctx types.Result, option
 				ctx.ParentProperty, // This becomes ParentOfParentProperty (the "1" from "$.users.1")
 			)
 
-			if e.filterEval.EvaluateFilter(node.Value, itemContext) {
+			if e.matchFilter(node, itemContext, options) {
 				if len(node.Children) > 0 {
 					childResults := e.evaluateNode(node.Children[0], []types.Result{itemResult}, options)
 					results = append(results, childResults...)
 				} else {
 					results = append(results, itemResult)
 				}
+				e.limitResults(len(results))
 			}
 			index++
 			return true
@@ -570,6 +673,7 @@ func (e *Evaluator) evaluateFilter(node *types.AstNode, ctx types.Result, option
 		// Handle object filtering
 		index := 0
 		for key, value := range obj {
+			e.checkpoint()
 			// For object properties:
 			// - @parent should refer to the object itself (ctx.Value)
 			// - @property should be the object key (key)
@@ -577,6 +681,7 @@ func (e *Evaluator) evaluateFilter(node *types.AstNode, ctx types.Result, option
 			itemResult := types.Result{
 				Value:          value,
 				Path:           formatPath(ctx.Path, key, false),
+				Pointer:        utils.MemberPointer(ctx.Pointer, key),
 				Parent:         ctx.Value, // Parent is the object itself (for @parent)
 				ParentProperty: key,       // Property is the object key (for @property)
 				Index:          index,
@@ -597,13 +702,14 @@ func (e *Evaluator) evaluateFilter(node *types.AstNode, ctx types.Result, option
 				ctx.ParentProperty, // This becomes ParentOfParentProperty (the "1" from "$.users.1")
 			)
 
-			if e.filterEval.EvaluateFilter(node.Value, itemContext) {
+			if e.matchFilter(node, itemContext, options) {
 				if len(node.Children) > 0 {
 					childResults := e.evaluateNode(node.Children[0], []types.Result{itemResult}, options)
 					results = append(results, childResults...)
 				} else {
 					results = append(results, itemResult)
 				}
+				e.limitResults(len(results))
 			}
 			index++
 		}
@@ -612,7 +718,7 @@ func (e *Evaluator) evaluateFilter(node *types.AstNode, ctx types.Result, option
 		// Create context for the single item
 		itemContext := e.contextualEval.CreateContext(ctx, options.Root)
 
-		if e.filterEval.EvaluateFilter(node.Value, itemContext) {
+		if e.matchFilter(node, itemContext, options) {
 			if len(node.Children) > 0 {
 				childResults := e.evaluateNode(node.Children[0], []types.Result{ctx}, options)
 				results = append(results, childResults...)
@@ -629,247 +735,27 @@ func (e *Evaluator) evaluateRecursive(node *types.AstNode, ctx types.Result, opt
 	var results []types.Result
 	visited := make(map[string]bool)
 
+	// A type selector after ..* keeps the descendants of that type, e.g. $..*@number()
+	if len(node.Children) == 2 && node.Children[0].Type == "wildcard" && node.Children[1].Type == "type_selector" {
+		descendants := e.descendantWildcard(node.Children[0], e.containerDescendants(ctx), false)
+		return e.evaluateNode(node.Children[1], descendants, options)
+	}
+
 	// Special case: if we have exactly one child that is a wildcard, treat this as $..*
 	// which should return all descendants at all levels using breadth-first traversal to match JavaScript
 	if len(node.Children) == 1 && node.Children[0].Type == "wildcard" {
 		// JavaScript JSONPath-Plus EXACT algorithm replication
-		// Based on: else if (loc === '..') in _trace method
-
-		var processRecursiveDescent func(current types.Result)
-		processRecursiveDescent = func(current types.Result) {
-			// Phase 1: Process current expression (equivalent to this._trace(x, val, ...))
-			// where x is the remaining expression after '..', which is ['*']
-			if current.Path != "$" {
-				// Add current node to results
-				if !visited[current.Path] {
-					results = append(results, current)
-					visited[current.Path] = true
-				}
-			}
-
-			// Process '*' on current value - add all direct children
-			switch v := current.Value.(type) {
-			case *utils.OrderedMap:
-				v.Range(func(key string, val interface{}) bool {
-					child := types.Result{
-						Value:          val,
-						Path:           fmt.Sprintf("%s['%s']", current.Path, key),
-						Parent:         current.Value,
-						ParentProperty: key,
-						Index:          0,
-						OriginalIndex:  0,
-					}
-					if !visited[child.Path] {
-						results = append(results, child)
-						visited[child.Path] = true
-					}
-					return true
-				})
-			case map[string]interface{}:
-				for key, val := range v {
-					child := types.Result{
-						Value:          val,
-						Path:           fmt.Sprintf("%s['%s']", current.Path, key),
-						Parent:         current.Value,
-						ParentProperty: key,
-						Index:          0,
-						OriginalIndex:  0,
-					}
-					if !visited[child.Path] {
-						results = append(results, child)
-						visited[child.Path] = true
-					}
-				}
-			case []interface{}:
-				for i, val := range v {
-					child := types.Result{
-						Value:         val,
-						Path:          fmt.Sprintf("%s[%d]", current.Path, i),
-						Parent:        current.Value,
-						Index:         i,
-						OriginalIndex: i,
-					}
-					if !visited[child.Path] {
-						results = append(results, child)
-						visited[child.Path] = true
-					}
-				}
-			}
-
-			// Phase 2: Walk through children and recursively apply full expression
-			// (equivalent to this._walk(val, (m) => { this._trace(expr.slice(), val[m], ...) }))
-			switch v := current.Value.(type) {
-			case *utils.OrderedMap:
-				v.Range(func(key string, val interface{}) bool {
-					// Only recurse into objects (matching JavaScript: if (typeof val[m] === 'object'))
-					if val != nil {
-						switch val.(type) {
-						case map[string]interface{}, *utils.OrderedMap, []interface{}:
-							child := types.Result{
-								Value:          val,
-								Path:           fmt.Sprintf("%s['%s']", current.Path, key),
-								Parent:         current.Value,
-								ParentProperty: key,
-								Index:          0,
-								OriginalIndex:  0,
-							}
-							processRecursiveDescent(child)
-						}
-					}
-					return true
-				})
-			case map[string]interface{}:
-				for key, val := range v {
-					if val != nil {
-						switch val.(type) {
-						case map[string]interface{}, *utils.OrderedMap, []interface{}:
-							child := types.Result{
-								Value:          val,
-								Path:           fmt.Sprintf("%s['%s']", current.Path, key),
-								Parent:         current.Value,
-								ParentProperty: key,
-								Index:          0,
-								OriginalIndex:  0,
-							}
-							processRecursiveDescent(child)
-						}
-					}
-				}
-			case []interface{}:
-				for i, val := range v {
-					child := types.Result{
-						Value:         val,
-						Path:          fmt.Sprintf("%s[%d]", current.Path, i),
-						Parent:        current.Value,
-						Index:         i,
-						OriginalIndex: i,
-					}
-					processRecursiveDescent(child)
-				}
-			}
-		}
-
-		// Start the recursive descent from root
-		processRecursiveDescent(ctx)
-
-		return results
+		// Based on: else if (loc === '..') in _trace method: '*' is traced on the value
+		// and on each of its descendants that is an object or array
+		return e.descendantWildcard(node.Children[0], e.containerDescendants(ctx), true)
 	}
 
 	// Special case: if we have wildcard+filter as children, this is $..*[?(...)]
 	// which should apply the filter to all property values found via recursive descent
 	if len(node.Children) == 2 && node.Children[0].Type == "wildcard" && node.Children[1].Type == "filter" {
 		// Use the EXACT same two-phase algorithm as $..*
-		var allProperties []types.Result
-
-		var processRecursiveDescent func(current types.Result)
-		processRecursiveDescent = func(current types.Result) {
-			// Phase 1: Process current expression (equivalent to this._trace(x, val, ...))
-			// where x is the remaining expression after '..', which is ['*']
-
-			// Process '*' on current value - add all direct children to allProperties
-			switch v := current.Value.(type) {
-			case *utils.OrderedMap:
-				v.Range(func(key string, val interface{}) bool {
-					child := types.Result{
-						Value:          val,
-						Path:           fmt.Sprintf("%s['%s']", current.Path, key),
-						Parent:         current.Value,
-						ParentProperty: key,
-						Index:          0,
-						OriginalIndex:  0,
-					}
-					if !visited[child.Path] {
-						allProperties = append(allProperties, child)
-						visited[child.Path] = true
-					}
-					return true
-				})
-			case map[string]interface{}:
-				for key, val := range v {
-					child := types.Result{
-						Value:          val,
-						Path:           fmt.Sprintf("%s['%s']", current.Path, key),
-						Parent:         current.Value,
-						ParentProperty: key,
-						Index:          0,
-						OriginalIndex:  0,
-					}
-					if !visited[child.Path] {
-						allProperties = append(allProperties, child)
-						visited[child.Path] = true
-					}
-				}
-			case []interface{}:
-				for i, val := range v {
-					child := types.Result{
-						Value:         val,
-						Path:          fmt.Sprintf("%s[%d]", current.Path, i),
-						Parent:        current.Value,
-						Index:         i,
-						OriginalIndex: i,
-					}
-					if !visited[child.Path] {
-						allProperties = append(allProperties, child)
-						visited[child.Path] = true
-					}
-				}
-			}
-
-			// Phase 2: Walk through children and recursively apply full expression
-			// (equivalent to this._walk(val, (m) => { this._trace(expr.slice(), val[m], ...) }))
-			switch v := current.Value.(type) {
-			case *utils.OrderedMap:
-				v.Range(func(key string, val interface{}) bool {
-					// Only recurse into objects (matching JavaScript: if (typeof val[m] === 'object'))
-					if val != nil {
-						switch val.(type) {
-						case map[string]interface{}, *utils.OrderedMap, []interface{}:
-							child := types.Result{
-								Value:          val,
-								Path:           fmt.Sprintf("%s['%s']", current.Path, key),
-								Parent:         current.Value,
-								ParentProperty: key,
-								Index:          0,
-								OriginalIndex:  0,
-							}
-							processRecursiveDescent(child)
-						}
-					}
-					return true
-				})
-			case map[string]interface{}:
-				for key, val := range v {
-					if val != nil {
-						switch val.(type) {
-						case map[string]interface{}, *utils.OrderedMap, []interface{}:
-							child := types.Result{
-								Value:          val,
-								Path:           fmt.Sprintf("%s['%s']", current.Path, key),
-								Parent:         current.Value,
-								ParentProperty: key,
-								Index:          0,
-								OriginalIndex:  0,
-							}
-							processRecursiveDescent(child)
-						}
-					}
-				}
-			case []interface{}:
-				for i, val := range v {
-					child := types.Result{
-						Value:         val,
-						Path:          fmt.Sprintf("%s[%d]", current.Path, i),
-						Parent:        current.Value,
-						Index:         i,
-						OriginalIndex: i,
-					}
-					processRecursiveDescent(child)
-				}
-			}
-		}
-
-		// Start the recursive descent from root
-		processRecursiveDescent(ctx)
+		allProperties := e.descendantWildcard(node.Children[0], e.containerDescendants(ctx), false)
+		e.charge(allProperties...)
 
 		// Apply JavaScript's specific ordering for recursive descent filters
 		// JavaScript processes object properties before array elements in filters
@@ -889,8 +775,9 @@ func (e *Evaluator) evaluateRecursive(node *types.AstNode, ctx types.Result, opt
 		allProperties = append(objectProps, arrayProps...)
 
 		filterNode := node.Children[1]
-		results = e.evaluateFilterOnResults(filterNode, allProperties, options)
-		return results
+		return e.traced(filterNode, len(allProperties), func() []types.Result {
+			return e.evaluateFilterOnResults(filterNode, allProperties, options)
+		})
 	}
 
 	// If we have children, we need to find all nodes that match the child criteria
@@ -904,9 +791,13 @@ func (e *Evaluator) evaluateRecursive(node *types.AstNode, ctx types.Result, opt
 				return
 			}
 			visited[current.Path] = true
+			e.enter()
+			defer e.leave()
+			e.checkpoint()
 
 			// Add current node to potential matches
 			allNodes = append(allNodes, current)
+			e.charge(current)
 
 			// Recursively traverse children
 			switch v := current.Value.(type) {
@@ -914,7 +805,8 @@ func (e *Evaluator) evaluateRecursive(node *types.AstNode, ctx types.Result, opt
 				v.Range(func(key string, val interface{}) bool {
 					childResult := types.Result{
 						Value:          val,
-						Path:           fmt.Sprintf("%s['%s']", current.Path, key),
+						Path:           memberPath(current.Path, key),
+						Pointer:        utils.MemberPointer(current.Pointer, key),
 						Parent:         current.Value,
 						ParentProperty: key,
 						Index:          0,
@@ -927,7 +819,8 @@ func (e *Evaluator) evaluateRecursive(node *types.AstNode, ctx types.Result, opt
 				for key, val := range v {
 					childResult := types.Result{
 						Value:          val,
-						Path:           fmt.Sprintf("%s['%s']", current.Path, key),
+						Path:           memberPath(current.Path, key),
+						Pointer:        utils.MemberPointer(current.Pointer, key),
 						Parent:         current.Value,
 						ParentProperty: key,
 						Index:          0,
@@ -940,6 +833,7 @@ func (e *Evaluator) evaluateRecursive(node *types.AstNode, ctx types.Result, opt
 					childResult := types.Result{
 						Value:          val,
 						Path:           fmt.Sprintf("%s[%d]", current.Path, i),
+						Pointer:        utils.ElementPointer(current.Pointer, i),
 						Parent:         current.Value,
 						ParentProperty: strconv.Itoa(i),
 						Index:          i,
@@ -958,23 +852,34 @@ func (e *Evaluator) evaluateRecursive(node *types.AstNode, ctx types.Result, opt
 			var allWildcardResults []types.Result
 
 			// Apply wildcard to each collected node to get all properties
-			for _, nodeResult := range allNodes {
-				wildcardResults := e.evaluateWildcard(node.Children[0], nodeResult, options)
-				allWildcardResults = append(allWildcardResults, wildcardResults...)
-			}
+			wildcardNode := node.Children[0]
+			allWildcardResults = e.traced(wildcardNode, len(allNodes), func() []types.Result {
+				var wildcardResults []types.Result
+				for _, nodeResult := range allNodes {
+					wildcardResults = append(wildcardResults, e.evaluateWildcard(wildcardNode, nodeResult, options)...)
+				}
+				return wildcardResults
+			})
 
 			// Deduplicate wildcard results by path
 			allWildcardResults = e.deduplicateResults(allWildcardResults)
 
 			// Now apply the filter to all wildcard results
 			filterNode := node.Children[1]
-			results = e.evaluateFilterOnResults(filterNode, allWildcardResults, options)
+			results = e.traced(filterNode, len(allWildcardResults), func() []types.Result {
+				return e.evaluateFilterOnResults(filterNode, allWildcardResults, options)
+			})
 		} else {
 			// Normal case: apply child node to each collected node
-			for _, nodeResult := range allNodes {
-				childResults := e.evaluateNode(node.Children[0], []types.Result{nodeResult}, options)
-				results = append(results, childResults...)
-			}
+			childNode := node.Children[0]
+			results = e.traced(childNode, len(allNodes), func() []types.Result {
+				var childResults []types.Result
+				for _, nodeResult := range allNodes {
+					childResults = append(childResults, e.evaluateChild(childNode, nodeResult, options)...)
+					e.limitResults(len(childResults))
+				}
+				return childResults
+			})
 
 			// Deduplicate results by path
 			results = e.deduplicateResults(results)
@@ -987,6 +892,9 @@ func (e *Evaluator) evaluateRecursive(node *types.AstNode, ctx types.Result, opt
 				return
 			}
 			visited[current.Path] = true
+			e.enter()
+			defer e.leave()
+			e.checkpoint()
 			// Include the current node itself
 			results = append(results, current)
 
@@ -995,7 +903,8 @@ func (e *Evaluator) evaluateRecursive(node *types.AstNode, ctx types.Result, opt
 				v.Range(func(key string, val interface{}) bool {
 					childResult := types.Result{
 						Value:          val,
-						Path:           fmt.Sprintf("%s['%s']", current.Path, key),
+						Path:           memberPath(current.Path, key),
+						Pointer:        utils.MemberPointer(current.Pointer, key),
 						Parent:         current.Value,
 						ParentProperty: key,
 						Index:          0,
@@ -1008,7 +917,8 @@ func (e *Evaluator) evaluateRecursive(node *types.AstNode, ctx types.Result, opt
 				for key, val := range v {
 					childResult := types.Result{
 						Value:          val,
-						Path:           fmt.Sprintf("%s['%s']", current.Path, key),
+						Path:           memberPath(current.Path, key),
+						Pointer:        utils.MemberPointer(current.Pointer, key),
 						Parent:         current.Value,
 						ParentProperty: key,
 						Index:          0,
@@ -1021,6 +931,7 @@ func (e *Evaluator) evaluateRecursive(node *types.AstNode, ctx types.Result, opt
 					childResult := types.Result{
 						Value:          val,
 						Path:           fmt.Sprintf("%s[%d]", current.Path, i),
+						Pointer:        utils.ElementPointer(current.Pointer, i),
 						Parent:         current.Value,
 						ParentProperty: strconv.Itoa(i),
 						Index:          i,
@@ -1037,11 +948,105 @@ func (e *Evaluator) evaluateRecursive(node *types.AstNode, ctx types.Result, opt
 	return results
 }
 
+// containerDescendants returns ctx followed by each of its descendants that is an
+// object or array, parents before their children, as the .. of JavaScript JSONPath-Plus
+// walks them (if (typeof val[m] === 'object'))
+func (e *Evaluator) containerDescendants(ctx types.Result) []types.Result {
+	var nodes []types.Result
+
+	var walk func(current types.Result)
+	walk = func(current types.Result) {
+		e.enter()
+		defer e.leave()
+		e.checkpoint()
+		nodes = append(nodes, current)
+		for _, child := range descendantChildren(current) {
+			switch child.Value.(type) {
+			case map[string]interface{}, *utils.OrderedMap, []interface{}:
+				walk(child)
+			}
+		}
+	}
+	walk(ctx)
+
+	return nodes
+}
+
+// descendantWildcard applies the wildcard of .. to each of nodes, as one span of the
+// wildcard, keeping the first result for each path. final is set when its results are
+// the results of the path, which MaxResultCount then limits as they are collected.
+func (e *Evaluator) descendantWildcard(wildcard *types.AstNode, nodes []types.Result, final bool) []types.Result {
+	return e.traced(wildcard, len(nodes), func() []types.Result {
+		var results []types.Result
+		visited := make(map[string]bool)
+		for _, node := range nodes {
+			e.checkpoint()
+			e.count(wildcard.Type)
+			for _, child := range descendantChildren(node) {
+				if !visited[child.Path] {
+					visited[child.Path] = true
+					results = append(results, child)
+				}
+			}
+			if final {
+				e.limitResults(len(results))
+			}
+		}
+		return results
+	})
+}
+
+// descendantChildren returns the member values of an object or the elements of an
+// array, as the recursive descent visits them
+func descendantChildren(current types.Result) []types.Result {
+	var children []types.Result
+	switch v := current.Value.(type) {
+	case *utils.OrderedMap:
+		v.Range(func(key string, val interface{}) bool {
+			children = append(children, types.Result{
+				Value:          val,
+				Path:           memberPath(current.Path, key),
+				Pointer:        utils.MemberPointer(current.Pointer, key),
+				Parent:         current.Value,
+				ParentProperty: key,
+				Index:          0,
+				OriginalIndex:  0,
+			})
+			return true
+		})
+	case map[string]interface{}:
+		for key, val := range v {
+			children = append(children, types.Result{
+				Value:          val,
+				Path:           memberPath(current.Path, key),
+				Pointer:        utils.MemberPointer(current.Pointer, key),
+				Parent:         current.Value,
+				ParentProperty: key,
+				Index:          0,
+				OriginalIndex:  0,
+			})
+		}
+	case []interface{}:
+		for i, val := range v {
+			children = append(children, types.Result{
+				Value:          val,
+				Path:           fmt.Sprintf("%s[%d]", current.Path, i),
+				Pointer:        utils.ElementPointer(current.Pointer, i),
+				Parent:         current.Value,
+				ParentProperty: strconv.Itoa(i),
+				Index:          i,
+				OriginalIndex:  i,
+			})
+		}
+	}
+	return children
+}
+
 func (e *Evaluator) evaluateUnion(node *types.AstNode, ctx types.Result, options *types.Options) []types.Result {
 	var results []types.Result
 
 	for _, child := range node.Children {
-		childResults := e.evaluateSingleNode(child, ctx, options)
+		childResults := e.evaluateSingleTraced(child, ctx, options)
 		results = append(results, childResults...)
 	}
 
@@ -1054,7 +1059,7 @@ func (e *Evaluator) evaluateChain(node *types.AstNode, ctx types.Result, options
 	}
 
 	// Start with the first operation
-	currentResults := e.evaluateSingleNode(node.Children[0], ctx, options)
+	currentResults := e.evaluateSingleTraced(node.Children[0], ctx, options)
 
 	// Apply subsequent operations to the results
 	return e.operatorEval.EvaluateChainedOperations(
@@ -1102,6 +1107,36 @@ func (e *Evaluator) evaluateParent(node *types.AstNode, ctx types.Result, option
 	return e.operatorEval.EvaluateParent(ctx, options)
 }
 
+// withSandbox defines the names of options.Sandbox in ctx when options select
+// EvalModeSandbox
+func withSandbox(ctx *types.Context, options *types.Options) *types.Context {
+	if options.EvalMode == types.EvalModeSandbox {
+		ctx.Sandbox = options.Sandbox
+	}
+	return ctx
+}
+
+// evaluateScript selects the member or element named by the value of a script
+// expression, which sees the current value as @
+func (e *Evaluator) evaluateScript(node *types.AstNode, ctx types.Result, options *types.Options) []types.Result {
+	key, err := e.filterEval.EvaluateScript(node, withSandbox(e.contextualEval.CreateContext(ctx, options.Root), options), filterDepth{e})
+	if err != nil {
+		e.fail(err)
+	}
+	return e.evaluateProperty(&types.AstNode{Type: "property", Value: key, Children: node.Children}, ctx, options)
+}
+
+// evaluateTypeSelector keeps ctx when its value has the type the selector names
+func (e *Evaluator) evaluateTypeSelector(node *types.AstNode, ctx types.Result, options *types.Options) []types.Result {
+	if !filters.MatchesType(node.Value, ctx.Value) {
+		return nil
+	}
+	if len(node.Children) > 0 {
+		return e.evaluateNode(node.Children[0], []types.Result{ctx}, options)
+	}
+	return []types.Result{ctx}
+}
+
 // parseSliceParams parses slice parameters with support for reverse iteration
 func (e *Evaluator) parseSliceParams(slice string, arrLen int) (start, end, step int) {
 	slice = strings.TrimSpace(slice)
@@ -0,0 +1,248 @@
+package evaluator
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+	"unsafe"
+
+	"github.com/reclaimprotocol/jsonpathplus-go/pkg/types"
+)
+
+// Limits bounds the resources a single evaluation may consume.
+// A zero value disables the corresponding limit.
+type Limits struct {
+	MaxRecursionDepth int           // Maximum nesting depth of recursive descent and nested filters
+	MaxResultCount    int           // Maximum number of results returned
+	Timeout           time.Duration // Maximum wall-clock time of one evaluation
+	MaxMemoryUsage    int64         // Approximate bytes of result bookkeeping allowed
+	MaxExecutionTime  time.Duration // Execution budget of one evaluation set by a security policy
+}
+
+// LimitKind identifies which limit stopped an evaluation
+type LimitKind int
+
+const (
+	// LimitRecursionDepth means recursive descent or nested filters went deeper than MaxRecursionDepth
+	LimitRecursionDepth LimitKind = iota
+	// LimitResultCount means the evaluation produced more than MaxResultCount results
+	LimitResultCount
+	// LimitTimeout means the evaluation ran longer than Timeout
+	LimitTimeout
+	// LimitMemoryUsage means the evaluation allocated more than MaxMemoryUsage bytes of results
+	LimitMemoryUsage
+	// LimitExecutionTime means the evaluation spent its MaxExecutionTime budget
+	LimitExecutionTime
+)
+
+// LimitError reports that an evaluation was aborted because it breached a limit
+type LimitError struct {
+	Kind   LimitKind
+	Limit  int64
+	Actual int64
+}
+
+func (e *LimitError) Error() string {
+	switch e.Kind {
+	case LimitRecursionDepth:
+		return fmt.Sprintf("recursion depth %d exceeds limit of %d", e.Actual, e.Limit)
+	case LimitResultCount:
+		return fmt.Sprintf("result count %d exceeds limit of %d", e.Actual, e.Limit)
+	case LimitTimeout:
+		return fmt.Sprintf("evaluation exceeded timeout of %v", time.Duration(e.Limit))
+	case LimitMemoryUsage:
+		return fmt.Sprintf("memory usage %d bytes exceeds limit of %d bytes", e.Actual, e.Limit)
+	case LimitExecutionTime:
+		return fmt.Sprintf("evaluation exceeded execution budget of %v", time.Duration(e.Limit))
+	default:
+		return "evaluation limit exceeded"
+	}
+}
+
+// resultFootprint is the fixed cost charged for every result the evaluator materializes
+const resultFootprint = int64(unsafe.Sizeof(types.Result{}))
+
+// deadlineCheckInterval is how many checkpoints pass between clock reads
+const deadlineCheckInterval = 64
+
+// abort carries an error out of the recursive evaluation; Run recovers it
+type abort struct {
+	err error
+}
+
+// PanicError reports a panic raised during an evaluation, for example by a custom function
+type PanicError struct {
+	Value interface{}
+	Stack []byte // Stack of the goroutine that panicked
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic during evaluation: %v", e.Value)
+}
+
+// run holds the mutable bookkeeping of a single evaluation
+type run struct {
+	ctx      context.Context
+	limits   Limits
+	deadline time.Time
+	budget   time.Time // End of the MaxExecutionTime budget
+	depth    int
+	memory   int64
+	steps    int
+	stats    *Stats         // Counts of the run, set when its context carries a Stats
+	tracer   types.Tracer   // Receives a span per node evaluation, from Options.Tracer
+	spans    []*spanFrame   // Spans of the tracer that are open, innermost last
+	nodes    *StandardNodes // Nodes the spans of a traced RFC 9535 query are reported for
+}
+
+func newRun(ctx context.Context, limits Limits) *run {
+	r := &run{ctx: ctx, limits: limits, stats: statsFrom(ctx)}
+	if limits.Timeout > 0 {
+		r.deadline = time.Now().Add(limits.Timeout)
+	}
+	if limits.MaxExecutionTime > 0 {
+		r.budget = time.Now().Add(limits.MaxExecutionTime)
+	}
+	return r
+}
+
+// fail aborts the current evaluation with err
+func (e *Evaluator) fail(err error) {
+	panic(abort{err: err})
+}
+
+// enter records one more level of recursive descent or of filter nesting and aborts
+// when the depth limit is breached
+func (e *Evaluator) enter() {
+	if e.run == nil {
+		return
+	}
+	e.run.depth++
+	if max := e.run.limits.MaxRecursionDepth; max > 0 && e.run.depth > max {
+		e.fail(&LimitError{Kind: LimitRecursionDepth, Limit: int64(max), Actual: int64(e.run.depth)})
+	}
+}
+
+// leave undoes a matching enter
+func (e *Evaluator) leave() {
+	if e.run != nil {
+		e.run.depth--
+	}
+}
+
+// filterDepth charges the nested filters and descendant segments of a filter
+// expression to the depth of the run
+type filterDepth struct {
+	e *Evaluator
+}
+
+func (d filterDepth) Enter() {
+	d.e.enter()
+}
+
+func (d filterDepth) Leave() {
+	d.e.leave()
+}
+
+// checkpoint aborts the evaluation once its context is done or its time budget is spent
+func (e *Evaluator) checkpoint() {
+	if e.run == nil {
+		return
+	}
+	select {
+	case <-e.run.ctx.Done():
+		e.fail(e.run.ctx.Err())
+	default:
+	}
+	if e.run.deadline.IsZero() && e.run.budget.IsZero() {
+		return
+	}
+	e.run.steps++
+	if e.run.steps%deadlineCheckInterval != 0 {
+		return
+	}
+	now := time.Now()
+	if !e.run.budget.IsZero() && now.After(e.run.budget) {
+		e.fail(&LimitError{Kind: LimitExecutionTime, Limit: int64(e.run.limits.MaxExecutionTime)})
+	}
+	if !e.run.deadline.IsZero() && now.After(e.run.deadline) {
+		e.fail(&LimitError{Kind: LimitTimeout, Limit: int64(e.run.limits.Timeout)})
+	}
+}
+
+// charge accounts for materialized results and aborts when the memory limit is breached
+func (e *Evaluator) charge(results ...types.Result) {
+	if e.run == nil || e.run.limits.MaxMemoryUsage <= 0 {
+		return
+	}
+	for i := range results {
+		e.run.memory += resultFootprint + int64(len(results[i].Path))
+	}
+	if e.run.memory > e.run.limits.MaxMemoryUsage {
+		e.fail(&LimitError{Kind: LimitMemoryUsage, Limit: e.run.limits.MaxMemoryUsage, Actual: e.run.memory})
+	}
+}
+
+// Run evaluates an AST against data while enforcing the evaluator's limits.
+// Unlike Evaluate it reports limit breaches as errors instead of returning partial results.
+func (e *Evaluator) Run(ast *types.AstNode, data interface{}, options *types.Options) ([]types.Result, error) {
+	return e.RunContext(context.Background(), ast, data, options)
+}
+
+// RunContext is like Run but also stops as soon as ctx is done, returning ctx.Err()
+func (e *Evaluator) RunContext(ctx context.Context, ast *types.AstNode, data interface{}, options *types.Options) (results []types.Result, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Each run works on a shallow copy so concurrent runs never share bookkeeping
+	ev := *e
+	ev.run = newRun(ctx, e.limits)
+	if options != nil {
+		ev.run.tracer = options.Tracer
+	}
+
+	defer recoverAbort(&results, &err)
+
+	results = ev.Evaluate(ast, data, options)
+
+	if err := e.checkResultCount(results); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// recoverAbort turns an abort raised by fail into the error a run returns, and any
+// other panic into a *PanicError. It must be deferred directly so that recover sees
+// the panic.
+func recoverAbort(results *[]types.Result, err *error) {
+	if r := recover(); r != nil {
+		*results = nil
+		if a, ok := r.(abort); ok {
+			*err = a.err
+			return
+		}
+		*err = &PanicError{Value: r, Stack: debug.Stack()}
+	}
+}
+
+// limitResults aborts the evaluation once a step has collected more than MaxResultCount
+// results, so that an oversized result set is never built in full
+func (e *Evaluator) limitResults(n int) {
+	if e.run == nil {
+		return
+	}
+	if max := e.run.limits.MaxResultCount; max > 0 && n > max {
+		e.fail(&LimitError{Kind: LimitResultCount, Limit: int64(max), Actual: int64(n)})
+	}
+}
+
+// checkResultCount reports a breach of MaxResultCount by a finished run
+func (e *Evaluator) checkResultCount(results []types.Result) error {
+	if max := e.limits.MaxResultCount; max > 0 && len(results) > max {
+		return &LimitError{Kind: LimitResultCount, Limit: int64(max), Actual: int64(len(results))}
+	}
+	return nil
+}
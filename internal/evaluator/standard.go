@@ -0,0 +1,373 @@
+package evaluator
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/reclaimprotocol/jsonpathplus-go/internal/rfc9535"
+	"github.com/reclaimprotocol/jsonpathplus-go/pkg/types"
+	"github.com/reclaimprotocol/jsonpathplus-go/pkg/utils"
+)
+
+// StandardNodes are the AST nodes a tracer is told about while an RFC 9535 query is
+// evaluated: the root, a recursive node per descendant segment and a node per selector.
+type StandardNodes struct {
+	Root  *types.AstNode
+	steps map[interface{}]*types.AstNode
+}
+
+// NewStandardNodes creates the nodes the spans of query are reported for
+func NewStandardNodes(query *rfc9535.Query) *StandardNodes {
+	nodes := &StandardNodes{
+		Root:  &types.AstNode{Type: "root", Value: "$"},
+		steps: make(map[interface{}]*types.AstNode),
+	}
+	for _, segment := range query.Segments {
+		if segment.Descendant {
+			nodes.steps[segment] = &types.AstNode{Type: "recursive", Value: ".."}
+		}
+		for _, selector := range segment.Selectors {
+			nodes.steps[selector] = &types.AstNode{Type: standardNodeType(selector), Value: selector.String()}
+		}
+	}
+	return nodes
+}
+
+// Segment returns the recursive node of a descendant segment of the query, or nil
+func (n *StandardNodes) Segment(segment *rfc9535.Segment) *types.AstNode {
+	return n.steps[segment]
+}
+
+// Selector returns the node of a selector of the query, or nil
+func (n *StandardNodes) Selector(selector rfc9535.Selector) *types.AstNode {
+	return n.steps[selector]
+}
+
+// RunStandard evaluates a query parsed by rfc9535.Parse with the semantics of RFC 9535.
+// Result paths are normalized paths, and the evaluator's limits apply as in RunContext.
+// Absolute queries in filters start at options.Root when it is set. When options.Tracer
+// is set, spans are reported for nodes, which NewStandardNodes creates when it is nil.
+func (e *Evaluator) RunStandard(ctx context.Context, query *rfc9535.Query, nodes *StandardNodes, data interface{}, options *types.Options) (results []types.Result, err error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Each run works on a shallow copy so concurrent runs never share bookkeeping
+	ev := *e
+	ev.run = newRun(ctx, e.limits)
+
+	defer recoverAbort(&results, &err)
+
+	root := data
+	if options != nil && options.Root != nil {
+		root = options.Root
+	}
+	if options != nil && options.Tracer != nil {
+		if nodes == nil {
+			nodes = NewStandardNodes(query)
+		}
+		ev.run.tracer = options.Tracer
+		ev.run.nodes = nodes
+		end := ev.startSpan(nodes.Root, 1)
+		defer end(1)
+	}
+	results = ev.selectSegments(query.Segments, []types.Result{{Value: data, Path: "$"}}, root, true)
+
+	if err := e.checkResultCount(results); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// selectSegments applies each segment in turn to the nodes the previous one selected.
+// Paths are only built when track is set; queries embedded in filters need values alone.
+func (e *Evaluator) selectSegments(segments []*rfc9535.Segment, nodes []types.Result, root interface{}, track bool) []types.Result {
+	for i, segment := range segments {
+		selected := e.selectSegment(segment, nodes, root, track, track && i == len(segments)-1)
+		if track {
+			e.charge(selected...)
+		}
+		nodes = selected
+	}
+	return nodes
+}
+
+// selectSegment applies the selectors of segment to each of nodes or, for a descendant
+// segment, to each of nodes and their descendants. final is set for the last segment of
+// the query, whose results MaxResultCount limits as they are selected.
+func (e *Evaluator) selectSegment(segment *rfc9535.Segment, nodes []types.Result, root interface{}, track, final bool) (selected []types.Result) {
+	if segment.Descendant {
+		var descendants []types.Result
+		if end := e.traceStep(segment, len(nodes), track); end != nil {
+			defer func() { end(len(descendants)) }()
+		}
+		for _, node := range nodes {
+			e.checkpoint()
+			e.count("recursive")
+			descendants = e.selectDescendants(node, track, descendants)
+		}
+		nodes = descendants
+	}
+
+	for _, node := range nodes {
+		e.checkpoint()
+		selected = e.applySelectors(segment.Selectors, node, root, track, final, selected)
+	}
+	return selected
+}
+
+// traceStep opens a span for a segment or selector of the query being evaluated. It
+// returns nil when the run is not traced or the query is embedded in a filter.
+func (e *Evaluator) traceStep(step interface{}, inputs int, track bool) func(outputs int) {
+	if !track || e.run.nodes == nil {
+		return nil
+	}
+	return e.startSpan(e.run.nodes.steps[step], inputs)
+}
+
+// selectDescendants appends node and each of its descendants, visiting parents before
+// their children and children in document order
+func (e *Evaluator) selectDescendants(node types.Result, track bool, out []types.Result) []types.Result {
+	e.enter()
+	defer e.leave()
+
+	out = append(out, node)
+	for _, child := range standardChildren(node, track) {
+		e.checkpoint()
+		out = e.selectDescendants(child, track, out)
+	}
+	return out
+}
+
+// applySelectors appends the children of node chosen by each selector, in selector order
+func (e *Evaluator) applySelectors(selectors []rfc9535.Selector, node types.Result, root interface{}, track, final bool, out []types.Result) []types.Result {
+	for _, selector := range selectors {
+		out = e.applySelector(selector, node, root, track, final, out)
+	}
+	return out
+}
+
+// applySelector appends the children of node that selector chooses. When final is set
+// they are results of the query, which MaxResultCount limits as they are appended.
+func (e *Evaluator) applySelector(selector rfc9535.Selector, node types.Result, root interface{}, track, final bool, out []types.Result) (selected []types.Result) {
+	if end := e.traceStep(selector, 1, track); end != nil {
+		before := len(out)
+		defer func() { end(len(selected) - before) }()
+	}
+
+	e.count(standardNodeType(selector))
+	switch s := selector.(type) {
+	case *rfc9535.NameSelector:
+		if value, ok := rfc9535.ObjectMember(node.Value, s.Name); ok {
+			out = append(out, memberNode(node, s.Name, value, track))
+		}
+	case *rfc9535.WildcardSelector:
+		out = append(out, standardChildren(node, track)...)
+	case *rfc9535.IndexSelector:
+		if arr, ok := node.Value.([]interface{}); ok {
+			idx := s.Index
+			if idx < 0 {
+				idx += len(arr)
+			}
+			if idx >= 0 && idx < len(arr) {
+				out = append(out, elementNode(node, idx, arr[idx], track))
+			}
+		}
+	case *rfc9535.SliceSelector:
+		if arr, ok := node.Value.([]interface{}); ok {
+			for _, idx := range sliceIndices(s, len(arr)) {
+				out = append(out, elementNode(node, idx, arr[idx], track))
+			}
+		}
+	case *rfc9535.FilterSelector:
+		if !track {
+			// A filter within a filter expression is one level deeper
+			e.enter()
+			defer e.leave()
+		}
+		for _, child := range standardChildren(node, track) {
+			e.checkpoint()
+			if e.test(s.Expr, child.Value, root) {
+				out = append(out, child)
+				if final {
+					e.limitResults(len(out))
+				}
+			}
+		}
+	}
+	if final {
+		e.limitResults(len(out))
+	}
+	return out
+}
+
+// standardChildren returns the member values of an object or the elements of an array
+func standardChildren(node types.Result, track bool) []types.Result {
+	if arr, ok := node.Value.([]interface{}); ok {
+		children := make([]types.Result, len(arr))
+		for i, value := range arr {
+			children[i] = elementNode(node, i, value, track)
+		}
+		return children
+	}
+	members, _ := rfc9535.ObjectMembers(node.Value)
+	children := make([]types.Result, len(members))
+	for i, member := range members {
+		children[i] = memberNode(node, member.Key, member.Value, track)
+	}
+	return children
+}
+
+func memberNode(parent types.Result, name string, value interface{}, track bool) types.Result {
+	child := types.Result{Value: value}
+	if track {
+		child.Path = rfc9535.NormalizedName(parent.Path, name)
+		child.Pointer = utils.MemberPointer(parent.Pointer, name)
+		child.Parent = parent.Value
+		child.ParentProperty = name
+	}
+	return child
+}
+
+func elementNode(parent types.Result, idx int, value interface{}, track bool) types.Result {
+	child := types.Result{Value: value, Index: idx, OriginalIndex: idx}
+	if track {
+		child.Path = rfc9535.NormalizedIndex(parent.Path, idx)
+		child.Pointer = utils.ElementPointer(parent.Pointer, idx)
+		child.Parent = parent.Value
+		child.ParentProperty = strconv.Itoa(idx)
+	}
+	return child
+}
+
+// sliceIndices lists the indices a slice selects from an array of length n,
+// following RFC 9535 section 2.3.4.2.2
+func sliceIndices(s *rfc9535.SliceSelector, n int) []int {
+	step := 1
+	if s.Step != nil {
+		step = *s.Step
+	}
+	if step == 0 {
+		return nil
+	}
+
+	normalize := func(i int) int {
+		if i < 0 {
+			return n + i
+		}
+		return i
+	}
+	clamp := func(i, lo, hi int) int {
+		if i < lo {
+			return lo
+		}
+		if i > hi {
+			return hi
+		}
+		return i
+	}
+
+	var indices []int
+	if step > 0 {
+		start, end := 0, n
+		if s.Start != nil {
+			start = normalize(*s.Start)
+		}
+		if s.End != nil {
+			end = normalize(*s.End)
+		}
+		for i := clamp(start, 0, n); i < clamp(end, 0, n); i += step {
+			indices = append(indices, i)
+		}
+		return indices
+	}
+
+	start, end := n-1, -n-1
+	if s.Start != nil {
+		start = normalize(*s.Start)
+	}
+	if s.End != nil {
+		end = normalize(*s.End)
+	}
+	for i := clamp(start, -1, n-1); i > clamp(end, -1, n-1); i += step {
+		indices = append(indices, i)
+	}
+	return indices
+}
+
+// test evaluates a logical expression against the current node
+func (e *Evaluator) test(expr rfc9535.Expr, current, root interface{}) bool {
+	switch x := expr.(type) {
+	case *rfc9535.QueryExpr:
+		return len(e.queryValues(x.Query, current, root)) > 0
+	case *rfc9535.FunctionExpr:
+		switch result := e.call(x, current, root).(type) {
+		case bool:
+			return result
+		case rfc9535.Nodes:
+			return len(result) > 0
+		}
+		return false
+	case *rfc9535.NotExpr:
+		return !e.test(x.Operand, current, root)
+	case *rfc9535.AndExpr:
+		return e.test(x.Left, current, root) && e.test(x.Right, current, root)
+	case *rfc9535.OrExpr:
+		return e.test(x.Left, current, root) || e.test(x.Right, current, root)
+	case *rfc9535.ComparisonExpr:
+		return rfc9535.Compare(x.Op, e.value(x.Left, current, root), e.value(x.Right, current, root))
+	}
+	return false
+}
+
+// value evaluates a comparable: a literal, a singular query or a function returning a value
+func (e *Evaluator) value(expr rfc9535.Expr, current, root interface{}) interface{} {
+	switch x := expr.(type) {
+	case *rfc9535.LiteralExpr:
+		return x.Value
+	case *rfc9535.QueryExpr:
+		values := e.queryValues(x.Query, current, root)
+		if len(values) != 1 {
+			return rfc9535.Nothing
+		}
+		return values[0]
+	case *rfc9535.FunctionExpr:
+		return e.call(x, current, root)
+	}
+	return rfc9535.Nothing
+}
+
+// call evaluates the arguments of a function according to its parameter types and calls it
+func (e *Evaluator) call(fn *rfc9535.FunctionExpr, current, root interface{}) interface{} {
+	args := make([]interface{}, len(fn.Args))
+	for i, arg := range fn.Args {
+		switch fn.Func.Params[i] {
+		case rfc9535.ValueType:
+			args[i] = e.value(arg, current, root)
+		case rfc9535.LogicalType:
+			args[i] = e.test(arg, current, root)
+		case rfc9535.NodesType:
+			if q, ok := arg.(*rfc9535.QueryExpr); ok {
+				args[i] = rfc9535.Nodes(e.queryValues(q.Query, current, root))
+			} else {
+				args[i] = e.call(arg.(*rfc9535.FunctionExpr), current, root)
+			}
+		}
+	}
+	return fn.Func.Call(args)
+}
+
+// queryValues evaluates a query embedded in a filter and returns the selected values
+func (e *Evaluator) queryValues(query *rfc9535.Query, current, root interface{}) []interface{} {
+	start := root
+	if query.Relative {
+		start = current
+	}
+	nodes := e.selectSegments(query.Segments, []types.Result{{Value: start}}, root, false)
+	values := make([]interface{}, len(nodes))
+	for i, node := range nodes {
+		values[i] = node.Value
+	}
+	return values
+}
@@ -0,0 +1,123 @@
+package evaluator
+
+import (
+	"context"
+	"time"
+
+	"github.com/reclaimprotocol/jsonpathplus-go/internal/rfc9535"
+	"github.com/reclaimprotocol/jsonpathplus-go/pkg/types"
+)
+
+// Stats accumulates counts over the runs whose context carries it. A Stats must not
+// be shared by runs that execute concurrently.
+type Stats struct {
+	NodeEvaluations map[string]int64 // Evaluations of each AST node type against one input
+}
+
+type statsKey struct{}
+
+// WithStats returns a copy of ctx whose runs add their counts to stats
+func WithStats(ctx context.Context, stats *Stats) context.Context {
+	return context.WithValue(ctx, statsKey{}, stats)
+}
+
+// statsFrom returns the Stats carried by ctx, or nil
+func statsFrom(ctx context.Context) *Stats {
+	stats, _ := ctx.Value(statsKey{}).(*Stats)
+	return stats
+}
+
+// count records one evaluation of a node of the given type
+func (e *Evaluator) count(nodeType string) {
+	if e.run == nil || e.run.stats == nil {
+		return
+	}
+	if e.run.stats.NodeEvaluations == nil {
+		e.run.stats.NodeEvaluations = make(map[string]int64)
+	}
+	e.run.stats.NodeEvaluations[nodeType]++
+}
+
+// spanFrame is a span of the run's tracer that has not ended yet
+type spanFrame struct {
+	node     *types.AstNode
+	produced int  // Values the node handed to its next step
+	handed   bool // Whether the node handed any values to its next step
+}
+
+// startSpan reports to the run's tracer that node is about to be evaluated against
+// inputs values. It returns the function that ends the span, or nil without a tracer.
+// A node that hands its values to a next step is reported with the number of values
+// it handed over instead of the outputs passed to the returned function, so that each
+// span counts the values its own node produced.
+func (e *Evaluator) startSpan(node *types.AstNode, inputs int) func(outputs int) {
+	if e.run == nil || e.run.tracer == nil {
+		return nil
+	}
+	if n := len(e.run.spans); n > 0 {
+		if parent := e.run.spans[n-1]; nextStep(parent.node) == node {
+			parent.produced += inputs
+			parent.handed = true
+		}
+	}
+	frame := &spanFrame{node: node}
+	e.run.spans = append(e.run.spans, frame)
+
+	tracer := e.run.tracer
+	tracer.StartSpan(node, inputs)
+	start := time.Now()
+	return func(outputs int) {
+		e.run.spans = e.run.spans[:len(e.run.spans)-1]
+		if frame.handed {
+			outputs = frame.produced
+		}
+		tracer.EndSpan(node, outputs, time.Since(start))
+	}
+}
+
+// nextStep returns the child a node hands the values it selects to, or nil when the
+// node has none or its children are its operands, as the members of a union are
+func nextStep(node *types.AstNode) *types.AstNode {
+	if len(node.Children) == 0 {
+		return nil
+	}
+	switch node.Type {
+	case "union", "chain", "parent":
+		return nil
+	}
+	return node.Children[0]
+}
+
+// traced runs evaluate, which applies node to inputs values, as a span of its own,
+// for nodes that are evaluated without going through evaluateNode
+func (e *Evaluator) traced(node *types.AstNode, inputs int, evaluate func() []types.Result) (results []types.Result) {
+	if end := e.startSpan(node, inputs); end != nil {
+		defer func() { end(len(results)) }()
+	}
+	return evaluate()
+}
+
+// evaluateSingleTraced is evaluateSingleNode reported to the tracer as a span of its own
+func (e *Evaluator) evaluateSingleTraced(node *types.AstNode, ctx types.Result, options *types.Options) []types.Result {
+	return e.traced(node, 1, func() []types.Result {
+		return e.evaluateSingleNode(node, ctx, options)
+	})
+}
+
+// standardNodeType names an RFC 9535 selector after the AST node type it corresponds to
+func standardNodeType(selector rfc9535.Selector) string {
+	switch selector.(type) {
+	case *rfc9535.NameSelector:
+		return "property"
+	case *rfc9535.WildcardSelector:
+		return "wildcard"
+	case *rfc9535.IndexSelector:
+		return "index"
+	case *rfc9535.SliceSelector:
+		return "slice"
+	case *rfc9535.FilterSelector:
+		return "filter"
+	default:
+		return "unknown"
+	}
+}
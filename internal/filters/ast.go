@@ -0,0 +1,242 @@
+package filters
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/reclaimprotocol/jsonpathplus-go/internal/rfc9535"
+)
+
+// Node is a node of a parsed filter expression
+type Node interface {
+	// String renders the node back as filter expression source
+	String() string
+}
+
+// Literal is a number, string, boolean, null or undefined constant
+type Literal struct {
+	Value interface{}
+}
+
+// RegexLiteral is a /pattern/flags literal, compiled when the expression is parsed
+type RegexLiteral struct {
+	Pattern string
+	Flags   string
+	Regexp  *regexp.Regexp
+}
+
+// ArrayLiteral is a [a, b, ...] literal
+type ArrayLiteral struct {
+	Elements []Node
+}
+
+// Identifier is a free name such as a function name
+type Identifier struct {
+	Name string
+}
+
+// ContextVar is one of the evaluation context variables: @, @property, @parent,
+// @parentProperty, @path, @root or $
+type ContextVar struct {
+	Name string
+}
+
+// Member is a dotted property access: object.property
+type Member struct {
+	Object   Node
+	Property string
+}
+
+// Index is a computed property access: object[index]
+type Index struct {
+	Object Node
+	Index  Node
+}
+
+// FilterSelector is a nested filter: object[?(predicate)]
+type FilterSelector struct {
+	Object    Node
+	Predicate Node
+}
+
+// Wildcard selects every member value or element of object: object.* or object[*].
+// It may only appear in a query passed to a function extension.
+type Wildcard struct {
+	Object Node
+}
+
+// Descendants selects the members named Property, or with an empty Property every
+// value, at any depth below object: object..property or object..*.
+// It may only appear in a query passed to a function extension.
+type Descendants struct {
+	Object   Node
+	Property string
+}
+
+// TypeSelector is a JSONPath-Plus type selector such as @string(), which tests the
+// type of the current value
+type TypeSelector struct {
+	Type string
+}
+
+// FunctionCall calls an RFC 9535 function extension such as length() or count()
+type FunctionCall struct {
+	Func *rfc9535.Function
+	Args []Node
+}
+
+// Call is a function or method call: callee(args...)
+type Call struct {
+	Callee  Node
+	Args    []Node
+	Pattern *regexp.Regexp // The string literal passed to a match method, compiled by the parser
+}
+
+// Unary is a prefix operation: !x, -x, +x or typeof x
+type Unary struct {
+	Op      string
+	Operand Node
+}
+
+// Binary is an arithmetic, comparison or logical operation
+type Binary struct {
+	Op    string
+	Left  Node
+	Right Node
+}
+
+// Conditional is a ternary test ? consequent : alternate
+type Conditional struct {
+	Test       Node
+	Consequent Node
+	Alternate  Node
+}
+
+func (n *Literal) String() string {
+	switch v := n.Value.(type) {
+	case nil:
+		return "null"
+	case undefinedValue:
+		return "undefined"
+	case string:
+		return "'" + strings.ReplaceAll(strings.ReplaceAll(v, `\`, `\\`), "'", `\'`) + "'"
+	case float64:
+		return numberToString(v)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return toJSString(v)
+	}
+}
+
+func (n *RegexLiteral) String() string {
+	return "/" + n.Pattern + "/" + n.Flags
+}
+
+func (n *ArrayLiteral) String() string {
+	return "[" + joinNodes(n.Elements) + "]"
+}
+
+func (n *Identifier) String() string {
+	return n.Name
+}
+
+func (n *ContextVar) String() string {
+	return n.Name
+}
+
+func (n *Member) String() string {
+	return n.Object.String() + "." + n.Property
+}
+
+func (n *Index) String() string {
+	return n.Object.String() + "[" + n.Index.String() + "]"
+}
+
+func (n *FilterSelector) String() string {
+	return n.Object.String() + "[?(" + n.Predicate.String() + ")]"
+}
+
+func (n *Wildcard) String() string {
+	return n.Object.String() + ".*"
+}
+
+func (n *Descendants) String() string {
+	if n.Property == "" {
+		return n.Object.String() + "..*"
+	}
+	return n.Object.String() + ".." + n.Property
+}
+
+func (n *TypeSelector) String() string {
+	return "@" + n.Type + "()"
+}
+
+func (n *FunctionCall) String() string {
+	return n.Func.Name + "(" + joinNodes(n.Args) + ")"
+}
+
+func (n *Call) String() string {
+	return n.Callee.String() + "(" + joinNodes(n.Args) + ")"
+}
+
+func (n *Unary) String() string {
+	if n.Op == "typeof" {
+		return "typeof " + n.Operand.String()
+	}
+	return n.Op + n.Operand.String()
+}
+
+func (n *Binary) String() string {
+	return "(" + n.Left.String() + " " + n.Op + " " + n.Right.String() + ")"
+}
+
+func (n *Conditional) String() string {
+	return "(" + n.Test.String() + " ? " + n.Consequent.String() + " : " + n.Alternate.String() + ")"
+}
+
+func joinNodes(nodes []Node) string {
+	parts := make([]string, len(nodes))
+	for i, node := range nodes {
+		parts[i] = node.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Walk calls visit for node and then for each node below it, in source order
+func Walk(node Node, visit func(Node)) {
+	visit(node)
+	for _, child := range children(node) {
+		Walk(child, visit)
+	}
+}
+
+// children returns the nodes directly below node
+func children(node Node) []Node {
+	switch n := node.(type) {
+	case *ArrayLiteral:
+		return n.Elements
+	case *Member:
+		return []Node{n.Object}
+	case *Index:
+		return []Node{n.Object, n.Index}
+	case *FilterSelector:
+		return []Node{n.Object, n.Predicate}
+	case *Wildcard:
+		return []Node{n.Object}
+	case *Descendants:
+		return []Node{n.Object}
+	case *FunctionCall:
+		return n.Args
+	case *Call:
+		return append([]Node{n.Callee}, n.Args...)
+	case *Unary:
+		return []Node{n.Operand}
+	case *Binary:
+		return []Node{n.Left, n.Right}
+	case *Conditional:
+		return []Node{n.Test, n.Consequent, n.Alternate}
+	}
+	return nil
+}
@@ -0,0 +1,872 @@
+package filters
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/reclaimprotocol/jsonpathplus-go/internal/rfc9535"
+	"github.com/reclaimprotocol/jsonpathplus-go/pkg/types"
+	"github.com/reclaimprotocol/jsonpathplus-go/pkg/utils"
+)
+
+// undefinedValue is the JavaScript undefined, distinct from null (nil)
+type undefinedValue struct{}
+
+// undefined is what missing properties evaluate to
+var undefined = undefinedValue{}
+
+// EvalError reports a filter expression that failed while being evaluated,
+// such as reading a property of null
+type EvalError struct {
+	Expr string // The filter expression
+	Msg  string
+}
+
+func (e *EvalError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Msg, e.Expr)
+}
+
+// evalFailure carries an EvalError out of the recursive evaluation
+type evalFailure struct {
+	err *EvalError
+}
+
+// Depth is told each time evaluation goes one level deeper, into the predicate of a
+// nested filter or down a descendant segment, and back up. Enter may abort the
+// evaluation by panicking; the panic reaches the caller of EvaluateFilter.
+type Depth interface {
+	Enter()
+	Leave()
+}
+
+// exprEvaluator evaluates one parsed expression against a context
+type exprEvaluator struct {
+	depth Depth // nil when the nesting depth is not tracked
+}
+
+// Eval evaluates a parsed filter expression against ctx and returns its JavaScript value.
+// Missing values are reported as nil.
+func Eval(node Node, ctx *types.Context) (value interface{}, err error) {
+	v, err := evalNode(node, ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if v == undefined {
+		return nil, nil
+	}
+	return v, nil
+}
+
+// Test evaluates a parsed filter expression against ctx and reports whether it is truthy
+func Test(node Node, ctx *types.Context) (bool, error) {
+	v, err := evalNode(node, ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	return isTruthy(v), nil
+}
+
+func evalNode(node Node, ctx *types.Context, depth Depth) (value interface{}, err error) {
+	ev := &exprEvaluator{depth: depth}
+
+	defer func() {
+		if r := recover(); r != nil {
+			if f, ok := r.(evalFailure); ok {
+				f.err.Expr = node.String()
+				value = nil
+				err = f.err
+				return
+			}
+			panic(r)
+		}
+	}()
+
+	return ev.eval(node, ctx), nil
+}
+
+// enter records one more level of nesting; leave undoes it
+func (ev *exprEvaluator) enter() {
+	if ev.depth != nil {
+		ev.depth.Enter()
+	}
+}
+
+func (ev *exprEvaluator) leave() {
+	if ev.depth != nil {
+		ev.depth.Leave()
+	}
+}
+
+func (ev *exprEvaluator) fail(format string, args ...interface{}) {
+	panic(evalFailure{err: &EvalError{Msg: fmt.Sprintf(format, args...)}})
+}
+
+func (ev *exprEvaluator) eval(node Node, ctx *types.Context) interface{} {
+	switch n := node.(type) {
+	case *Literal:
+		return n.Value
+	case *RegexLiteral:
+		return n.Regexp
+	case *ArrayLiteral:
+		arr := make([]interface{}, len(n.Elements))
+		for i, element := range n.Elements {
+			arr[i] = ev.eval(element, ctx)
+		}
+		return arr
+	case *ContextVar:
+		return contextValue(n.Name, ctx)
+	case *Identifier:
+		if value, ok := ctx.Sandbox[n.Name]; ok {
+			return value
+		}
+		ev.fail("%s is not defined", n.Name)
+	case *Member:
+		return ev.member(ev.eval(n.Object, ctx), n.Property)
+	case *Index:
+		object := ev.eval(n.Object, ctx)
+		return ev.member(object, toPropertyKey(ev.eval(n.Index, ctx)))
+	case *FilterSelector:
+		return ev.filter(ev.eval(n.Object, ctx), n.Predicate, ctx)
+	case *Call:
+		return ev.call(n, ctx)
+	case *FunctionCall:
+		return ev.callFunction(n, ctx)
+	case *TypeSelector:
+		return MatchesType(n.Type, ctx.Current)
+	case *Wildcard, *Descendants:
+		ev.fail("%s can only be passed to a function", node)
+	case *Unary:
+		return ev.unary(n.Op, ev.eval(n.Operand, ctx))
+	case *Binary:
+		return ev.binary(n, ctx)
+	case *Conditional:
+		if isTruthy(ev.eval(n.Test, ctx)) {
+			return ev.eval(n.Consequent, ctx)
+		}
+		return ev.eval(n.Alternate, ctx)
+	default:
+		ev.fail("unsupported expression %s", node)
+	}
+	return undefined
+}
+
+// contextValue resolves a context variable against ctx
+func contextValue(name string, ctx *types.Context) interface{} {
+	switch name {
+	case "@":
+		return ctx.Current
+	case "$", "@root":
+		return ctx.Root
+	case "@property":
+		return ctx.GetPropertyValue()
+	case "@parent":
+		if ctx.Parent == nil {
+			return undefined
+		}
+		return ctx.Parent
+	case "@parentProperty":
+		if prop := ctx.GetParentPropertyName(); prop != "" {
+			return prop
+		}
+		return undefined
+	case "@path":
+		return ctx.GetBracketPath()
+	}
+	return undefined
+}
+
+// member reads a property the way JavaScript does. Reading from null is an error;
+// reading from undefined yields undefined so that optional fields can be tested.
+func (ev *exprEvaluator) member(object interface{}, key string) interface{} {
+	switch v := object.(type) {
+	case nil:
+		ev.fail("Cannot read properties of null (reading '%s')", key)
+	case undefinedValue:
+		return undefined
+	case *utils.OrderedMap:
+		if value, ok := v.Get(key); ok {
+			return value
+		}
+	case map[string]interface{}:
+		if value, ok := v[key]; ok {
+			return value
+		}
+	case []interface{}:
+		if key == "length" {
+			return float64(len(v))
+		}
+		if idx, ok := arrayIndex(key); ok && idx < len(v) {
+			return v[idx]
+		}
+	case string:
+		if key == "length" {
+			return float64(len(utf16.Encode([]rune(v))))
+		}
+		if idx, ok := arrayIndex(key); ok {
+			runes := []rune(v)
+			if idx < len(runes) {
+				return string(runes[idx])
+			}
+		}
+	default:
+		// Go structs passed as data expose their exported fields
+		rv := reflect.ValueOf(object)
+		if rv.Kind() == reflect.Ptr {
+			rv = rv.Elem()
+		}
+		if rv.Kind() == reflect.Struct {
+			if field := rv.FieldByName(key); field.IsValid() && field.CanInterface() {
+				return field.Interface()
+			}
+		}
+	}
+	return undefined
+}
+
+// filter applies a nested filter to the elements of an array or the values of an object.
+// It yields the matching elements, or undefined when nothing matched so that the
+// selector can be used as an existence test.
+func (ev *exprEvaluator) filter(object interface{}, predicate Node, ctx *types.Context) interface{} {
+	ev.enter()
+	defer ev.leave()
+
+	var matches []interface{}
+	test := func(item interface{}, property string, index int) {
+		itemContext := types.NewArrayElementContext(ctx.Root, item, ctx.Current, property, "", index, object)
+		itemContext.Sandbox = ctx.Sandbox
+		if isTruthy(ev.eval(predicate, itemContext)) {
+			matches = append(matches, item)
+		}
+	}
+
+	switch v := object.(type) {
+	case nil:
+		ev.fail("Cannot read properties of null")
+	case []interface{}:
+		for i, item := range v {
+			test(item, strconv.Itoa(i), i)
+		}
+	case *utils.OrderedMap:
+		i := 0
+		v.Range(func(key string, item interface{}) bool {
+			test(item, key, i)
+			i++
+			return true
+		})
+	case map[string]interface{}:
+		i := 0
+		for key, item := range v {
+			test(item, key, i)
+			i++
+		}
+	}
+
+	if len(matches) == 0 {
+		return undefined
+	}
+	return matches
+}
+
+func (ev *exprEvaluator) call(n *Call, ctx *types.Context) interface{} {
+	member, ok := n.Callee.(*Member)
+	if !ok {
+		if ident, isIdent := n.Callee.(*Identifier); isIdent {
+			return ev.callSandbox(ident, n.Args, ctx)
+		}
+		ev.fail("%s is not a function", n.Callee)
+	}
+
+	receiver := ev.eval(member.Object, ctx)
+	switch receiver.(type) {
+	case nil:
+		ev.fail("Cannot read properties of null (reading '%s')", member.Property)
+	case undefinedValue:
+		return undefined
+	}
+
+	args := make([]interface{}, len(n.Args))
+	for i, arg := range n.Args {
+		args[i] = ev.eval(arg, ctx)
+	}
+	if n.Pattern != nil {
+		args[0] = n.Pattern
+	}
+
+	if result, ok := ev.callMethod(receiver, member.Property, args); ok {
+		return result
+	}
+	ev.fail("%s is not a function", n.Callee)
+	return undefined
+}
+
+// sandboxFuncType is the type a sandbox function must be convertible to
+var sandboxFuncType = reflect.TypeOf((func([]interface{}) interface{})(nil))
+
+// callSandbox calls a function defined in the sandbox. Undefined arguments are
+// passed as nil and a nil result is null.
+func (ev *exprEvaluator) callSandbox(ident *Identifier, argNodes []Node, ctx *types.Context) interface{} {
+	value, ok := ctx.Sandbox[ident.Name]
+	if !ok {
+		ev.fail("%s is not defined", ident.Name)
+	}
+	fn := reflect.ValueOf(value)
+	if !fn.IsValid() || fn.Kind() != reflect.Func || !fn.Type().ConvertibleTo(sandboxFuncType) {
+		ev.fail("%s is not a function", ident.Name)
+	}
+
+	args := make([]interface{}, len(argNodes))
+	for i, arg := range argNodes {
+		if args[i] = ev.eval(arg, ctx); args[i] == undefined {
+			args[i] = nil
+		}
+	}
+	return fn.Convert(sandboxFuncType).Interface().(func([]interface{}) interface{})(args)
+}
+
+// callMethod invokes a built-in method; ok is false when the receiver has no such method
+func (ev *exprEvaluator) callMethod(receiver interface{}, name string, args []interface{}) (result interface{}, ok bool) {
+	arg := func(i int) interface{} {
+		if i < len(args) {
+			return args[i]
+		}
+		return undefined
+	}
+
+	// typeof() is available on every value and reports JSON type names
+	if name == "typeof" {
+		return jsonTypeName(receiver), true
+	}
+	if name == "toString" {
+		return toJSString(receiver), true
+	}
+
+	switch v := receiver.(type) {
+	case string:
+		switch name {
+		case "includes", "contains":
+			return strings.Contains(v, toJSString(arg(0))), true
+		case "startsWith":
+			return strings.HasPrefix(v, toJSString(arg(0))), true
+		case "endsWith":
+			return strings.HasSuffix(v, toJSString(arg(0))), true
+		case "indexOf":
+			return float64(utf16Index(v, toJSString(arg(0)))), true
+		case "toLowerCase":
+			return strings.ToLower(v), true
+		case "toUpperCase":
+			return strings.ToUpper(v), true
+		case "trim":
+			return strings.TrimSpace(v), true
+		case "match":
+			return matchString(v, arg(0)), true
+		}
+
+	case *regexp.Regexp:
+		if name == "test" {
+			return v.MatchString(toJSString(arg(0))), true
+		}
+
+	case []interface{}:
+		switch name {
+		case "includes":
+			for _, item := range v {
+				if strictEquals(item, arg(0)) {
+					return true, true
+				}
+			}
+			return false, true
+		case "contains":
+			// contains also matches string elements that contain the argument
+			term := toJSString(arg(0))
+			for _, item := range v {
+				if str, isStr := item.(string); isStr && strings.Contains(str, term) {
+					return true, true
+				}
+				if strictEquals(item, arg(0)) {
+					return true, true
+				}
+			}
+			return false, true
+		case "indexOf":
+			for i, item := range v {
+				if strictEquals(item, arg(0)) {
+					return float64(i), true
+				}
+			}
+			return float64(-1), true
+		case "join":
+			sep := ","
+			if a := arg(0); a != undefined {
+				sep = toJSString(a)
+			}
+			return joinArray(v, sep), true
+		}
+
+	default:
+		if num, isNum := toNumberValue(receiver); isNum {
+			switch name {
+			case "floor":
+				return math.Floor(num), true
+			case "round":
+				return math.Floor(num + 0.5), true
+			case "ceil":
+				return math.Ceil(num), true
+			case "toFixed":
+				digits := 0.0
+				if d := arg(0); d != undefined {
+					digits = math.Trunc(toNumber(d))
+				}
+				if math.IsNaN(digits) {
+					digits = 0
+				}
+				if digits < 0 || digits > 100 {
+					ev.fail("RangeError: toFixed() digits argument must be between 0 and 100")
+				}
+				return strconv.FormatFloat(num, 'f', int(digits), 64), true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// patternCache holds the patterns match is called with that the parser could not
+// compile, such as values read from the document
+var patternCache = rfc9535.NewRegexpCache(patternCacheSize)
+
+// patternCacheSize bounds the number of compiled patterns in patternCache
+const patternCacheSize = 256
+
+// matchString implements String.prototype.match without the global flag:
+// the match followed by its groups, or null when the pattern does not match
+func matchString(s string, pattern interface{}) interface{} {
+	re, ok := pattern.(*regexp.Regexp)
+	if !ok {
+		source := toJSString(pattern)
+		var err error
+		if re, err = patternCache.Compile(source, func() (*regexp.Regexp, error) {
+			return regexp.Compile(source)
+		}); err != nil {
+			return nil
+		}
+	}
+
+	groups := re.FindStringSubmatch(s)
+	if groups == nil {
+		return nil
+	}
+	result := make([]interface{}, len(groups))
+	for i, group := range groups {
+		result[i] = group
+	}
+	return result
+}
+
+func (ev *exprEvaluator) unary(op string, operand interface{}) interface{} {
+	switch op {
+	case "!":
+		return !isTruthy(operand)
+	case "-":
+		return -toNumber(operand)
+	case "+":
+		return toNumber(operand)
+	case "typeof":
+		return typeOf(operand)
+	}
+	ev.fail("unsupported operator %s", op)
+	return undefined
+}
+
+func (ev *exprEvaluator) binary(n *Binary, ctx *types.Context) interface{} {
+	left := ev.eval(n.Left, ctx)
+
+	// Logical operators short-circuit and yield one of their operands
+	switch n.Op {
+	case "&&":
+		if !isTruthy(left) {
+			return left
+		}
+		return ev.eval(n.Right, ctx)
+	case "||":
+		if isTruthy(left) {
+			return left
+		}
+		return ev.eval(n.Right, ctx)
+	}
+
+	right := ev.eval(n.Right, ctx)
+
+	switch n.Op {
+	case "===":
+		return strictEquals(left, right)
+	case "!==":
+		return !strictEquals(left, right)
+	case "==":
+		return looseEquals(left, right)
+	case "!=":
+		return !looseEquals(left, right)
+	case "<", "<=", ">", ">=":
+		return compare(n.Op, left, right)
+	case "+":
+		l, r := toPrimitive(left), toPrimitive(right)
+		_, lStr := l.(string)
+		_, rStr := r.(string)
+		if lStr || rStr {
+			return toJSString(l) + toJSString(r)
+		}
+		return toNumber(l) + toNumber(r)
+	case "-":
+		return toNumber(left) - toNumber(right)
+	case "*":
+		return toNumber(left) * toNumber(right)
+	case "/":
+		return toNumber(left) / toNumber(right)
+	case "%":
+		return math.Mod(toNumber(left), toNumber(right))
+	}
+
+	ev.fail("unsupported operator %s", n.Op)
+	return undefined
+}
+
+// compare implements the JavaScript relational operators
+func compare(op string, left, right interface{}) bool {
+	l, r := toPrimitive(left), toPrimitive(right)
+	if ls, ok := l.(string); ok {
+		if rs, ok := r.(string); ok {
+			switch op {
+			case "<":
+				return ls < rs
+			case "<=":
+				return ls <= rs
+			case ">":
+				return ls > rs
+			default:
+				return ls >= rs
+			}
+		}
+	}
+
+	ln, rn := toNumber(l), toNumber(r)
+	switch op {
+	case "<":
+		return ln < rn
+	case "<=":
+		return ln <= rn
+	case ">":
+		return ln > rn
+	default:
+		return ln >= rn
+	}
+}
+
+// strictEquals implements ===: same type and value, and identity for objects and arrays
+func strictEquals(left, right interface{}) bool {
+	if ln, ok := toNumberValue(left); ok {
+		rn, ok := toNumberValue(right)
+		return ok && ln == rn
+	}
+
+	switch l := left.(type) {
+	case nil:
+		return right == nil
+	case undefinedValue:
+		return right == undefined
+	case string:
+		r, ok := right.(string)
+		return ok && l == r
+	case bool:
+		r, ok := right.(bool)
+		return ok && l == r
+	}
+
+	return sameReference(left, right)
+}
+
+// looseEquals implements == with JavaScript's type coercions
+func looseEquals(left, right interface{}) bool {
+	leftNullish := left == nil || left == undefined
+	rightNullish := right == nil || right == undefined
+	if leftNullish || rightNullish {
+		return leftNullish && rightNullish
+	}
+
+	if sameType(left, right) {
+		return strictEquals(left, right)
+	}
+
+	// Booleans compare as numbers
+	if b, ok := left.(bool); ok {
+		return looseEquals(boolToNumber(b), right)
+	}
+	if b, ok := right.(bool); ok {
+		return looseEquals(left, boolToNumber(b))
+	}
+
+	_, leftNum := toNumberValue(left)
+	_, rightNum := toNumberValue(right)
+	_, leftStr := left.(string)
+	_, rightStr := right.(string)
+	switch {
+	case leftNum && rightStr, leftStr && rightNum:
+		return toNumber(left) == toNumber(right)
+	case isObjectLike(left) && !isObjectLike(right):
+		return looseEquals(toPrimitive(left), right)
+	case isObjectLike(right) && !isObjectLike(left):
+		return looseEquals(left, toPrimitive(right))
+	}
+	return false
+}
+
+// sameType reports whether two values have the same JavaScript type
+func sameType(left, right interface{}) bool {
+	return typeOf(left) == typeOf(right)
+}
+
+// sameReference compares objects, arrays and regexps by identity like JavaScript does
+func sameReference(left, right interface{}) bool {
+	if left == nil || right == nil {
+		return false
+	}
+	lv, rv := reflect.ValueOf(left), reflect.ValueOf(right)
+	if lv.Type() != rv.Type() {
+		return false
+	}
+	switch lv.Kind() {
+	case reflect.Map, reflect.Ptr:
+		return lv.Pointer() == rv.Pointer()
+	case reflect.Slice:
+		return lv.Len() == rv.Len() && (lv.Len() == 0 || lv.Pointer() == rv.Pointer())
+	}
+	return reflect.DeepEqual(left, right)
+}
+
+func isObjectLike(v interface{}) bool {
+	switch v.(type) {
+	case []interface{}, *utils.OrderedMap, map[string]interface{}:
+		return true
+	}
+	return false
+}
+
+// typeOf implements the typeof operator
+func typeOf(v interface{}) string {
+	switch v.(type) {
+	case undefinedValue:
+		return "undefined"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	}
+	if _, ok := toNumberValue(v); ok {
+		return "number"
+	}
+	return "object"
+}
+
+// jsonTypeName reports the JSON type of a value, as used by the typeof() method
+func jsonTypeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case undefinedValue:
+		return "undefined"
+	case []interface{}:
+		return "array"
+	case *utils.OrderedMap, map[string]interface{}:
+		return "object"
+	}
+	return typeOf(v)
+}
+
+// isTruthy reports whether a value is truthy under JavaScript semantics
+func isTruthy(v interface{}) bool {
+	switch t := v.(type) {
+	case nil, undefinedValue:
+		return false
+	case bool:
+		return t
+	case string:
+		return t != ""
+	}
+	if num, ok := toNumberValue(v); ok {
+		return num != 0 && !math.IsNaN(num)
+	}
+	return true
+}
+
+// toNumberValue converts Go numeric types to float64; ok is false for non-numbers
+func toNumberValue(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// toNumber implements JavaScript's ToNumber
+func toNumber(v interface{}) float64 {
+	if num, ok := toNumberValue(v); ok {
+		return num
+	}
+	switch t := v.(type) {
+	case nil:
+		return 0
+	case bool:
+		return boolToNumber(t)
+	case string:
+		s := strings.TrimSpace(t)
+		if s == "" {
+			return 0
+		}
+		switch s {
+		case "Infinity", "+Infinity":
+			return math.Inf(1)
+		case "-Infinity":
+			return math.Inf(-1)
+		}
+		if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+			if n, err := strconv.ParseUint(s[2:], 16, 64); err == nil {
+				return float64(n)
+			}
+			return math.NaN()
+		}
+		// ParseFloat accepts spellings such as "inf" and "1_0" that JavaScript rejects
+		if strings.ContainsAny(s, "_nNiIxX") {
+			return math.NaN()
+		}
+		if n, err := strconv.ParseFloat(s, 64); err == nil {
+			return n
+		}
+		return math.NaN()
+	case []interface{}, *utils.OrderedMap, map[string]interface{}:
+		return toNumber(toPrimitive(t))
+	}
+	return math.NaN()
+}
+
+func boolToNumber(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// toPrimitive converts arrays and objects to their string form
+func toPrimitive(v interface{}) interface{} {
+	if isObjectLike(v) {
+		return toJSString(v)
+	}
+	return v
+}
+
+// toJSString implements JavaScript's ToString
+func toJSString(v interface{}) string {
+	if num, ok := toNumberValue(v); ok {
+		return numberToString(num)
+	}
+	switch t := v.(type) {
+	case nil:
+		return "null"
+	case undefinedValue:
+		return "undefined"
+	case string:
+		return t
+	case bool:
+		return strconv.FormatBool(t)
+	case []interface{}:
+		return joinArray(t, ",")
+	case *utils.OrderedMap, map[string]interface{}:
+		return "[object Object]"
+	case *regexp.Regexp:
+		return "/" + t.String() + "/"
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// joinArray implements Array.prototype.join, where null and undefined become empty
+func joinArray(arr []interface{}, sep string) string {
+	parts := make([]string, len(arr))
+	for i, item := range arr {
+		if item != nil && item != undefined {
+			parts[i] = toJSString(item)
+		}
+	}
+	return strings.Join(parts, sep)
+}
+
+// numberToString formats a number the way JavaScript prints it
+func numberToString(n float64) string {
+	switch {
+	case math.IsNaN(n):
+		return "NaN"
+	case math.IsInf(n, 1):
+		return "Infinity"
+	case math.IsInf(n, -1):
+		return "-Infinity"
+	case n == 0:
+		return "0"
+	}
+	abs := math.Abs(n)
+	if abs >= 1e21 || abs < 1e-6 {
+		s := strconv.FormatFloat(n, 'e', -1, 64)
+		// JavaScript writes 1e+21 and 1e-7 without zero padding
+		s = strings.Replace(s, "e+0", "e+", 1)
+		return strings.Replace(s, "e-0", "e-", 1)
+	}
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+// toPropertyKey converts a computed member key to the string JavaScript would use
+func toPropertyKey(v interface{}) string {
+	return toJSString(v)
+}
+
+// arrayIndex parses a canonical non-negative array index
+func arrayIndex(key string) (int, bool) {
+	if key == "" || (len(key) > 1 && key[0] == '0') {
+		return 0, false
+	}
+	idx, err := strconv.Atoi(key)
+	if err != nil || idx < 0 {
+		return 0, false
+	}
+	return idx, true
+}
+
+// utf16Index returns the UTF-16 offset of substr in s, or -1
+func utf16Index(s, substr string) int {
+	idx := strings.Index(s, substr)
+	if idx < 0 {
+		return -1
+	}
+	return len(utf16.Encode([]rune(s[:idx])))
+}
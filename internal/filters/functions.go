@@ -0,0 +1,413 @@
+package filters
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/reclaimprotocol/jsonpathplus-go/internal/rfc9535"
+	"github.com/reclaimprotocol/jsonpathplus-go/pkg/types"
+)
+
+// exprContext is the position an expression appears in, which decides what the
+// result of a function extension may be used for (RFC 9535 section 2.4.3)
+type exprContext int
+
+const (
+	// anyContext covers arithmetic operands, method arguments, indices and the like
+	anyContext exprContext = iota
+	// logicalContext covers filter predicates and the operands of !, && and ||
+	logicalContext
+	// comparisonContext covers the operands of comparison operators
+	comparisonContext
+)
+
+// comparisonOps are the operators whose operands must be values
+var comparisonOps = map[string]bool{
+	"==": true, "!=": true, "===": true, "!==": true,
+	"<": true, "<=": true, ">": true, ">=": true,
+}
+
+// parseFunctionCall parses the arguments of a function extension whose name was the
+// previous token and checks their number
+func (p *exprParser) parseFunctionCall(fn *rfc9535.Function) (Node, error) {
+	name := p.tokens[p.pos-1]
+	p.advance()
+
+	call := &FunctionCall{Func: fn}
+	if p.isPunct(")") {
+		p.advance()
+	} else {
+		for {
+			start := p.peek()
+			arg, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			p.positions[arg] = start.pos
+			call.Args = append(call.Args, arg)
+
+			if p.isPunct(",") {
+				p.advance()
+				continue
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	if len(call.Args) != len(fn.Params) {
+		return nil, p.errorf(name, "%s() takes %d argument(s) but got %d", fn.Name, len(fn.Params), len(call.Args))
+	}
+	p.positions[call] = name.pos
+	return call, nil
+}
+
+// checkTypes applies the well-typedness rules of function extensions to an expression
+// appearing in ctx, and rejects wildcards and descendant segments outside of function
+// arguments
+func (p *exprParser) checkTypes(node Node, ctx exprContext) error {
+	switch n := node.(type) {
+	case *FunctionCall:
+		switch {
+		case n.Func.Result == rfc9535.NodesType:
+			return p.errorAt(n, "the result of %s() can only be passed to a function", n.Func.Name)
+		case n.Func.Result == rfc9535.ValueType && ctx == logicalContext:
+			return p.errorAt(n, "the result of %s() must be compared", n.Func.Name)
+		case n.Func.Result == rfc9535.LogicalType && ctx == comparisonContext:
+			return p.errorAt(n, "the result of %s() cannot be compared", n.Func.Name)
+		}
+		return p.checkArguments(n)
+	case *Wildcard, *Descendants:
+		return p.errorAt(n, "wildcards and descendant segments are only supported in function arguments")
+	case *Member:
+		return p.checkTypes(n.Object, anyContext)
+	case *Index:
+		if err := p.checkTypes(n.Object, anyContext); err != nil {
+			return err
+		}
+		return p.checkTypes(n.Index, anyContext)
+	case *FilterSelector:
+		if err := p.checkTypes(n.Object, anyContext); err != nil {
+			return err
+		}
+		return p.checkTypes(n.Predicate, logicalContext)
+	case *Call:
+		if err := p.checkTypes(n.Callee, anyContext); err != nil {
+			return err
+		}
+		return p.checkAll(n.Args, anyContext)
+	case *ArrayLiteral:
+		return p.checkAll(n.Elements, anyContext)
+	case *Unary:
+		if n.Op == "!" {
+			return p.checkTypes(n.Operand, logicalContext)
+		}
+		return p.checkTypes(n.Operand, anyContext)
+	case *Binary:
+		operandContext := anyContext
+		switch {
+		case n.Op == "&&" || n.Op == "||":
+			operandContext = logicalContext
+		case comparisonOps[n.Op]:
+			operandContext = comparisonContext
+		}
+		if err := p.checkTypes(n.Left, operandContext); err != nil {
+			return err
+		}
+		return p.checkTypes(n.Right, operandContext)
+	case *Conditional:
+		if err := p.checkTypes(n.Test, logicalContext); err != nil {
+			return err
+		}
+		if err := p.checkTypes(n.Consequent, ctx); err != nil {
+			return err
+		}
+		return p.checkTypes(n.Alternate, ctx)
+	}
+	return nil
+}
+
+func (p *exprParser) checkAll(nodes []Node, ctx exprContext) error {
+	for _, node := range nodes {
+		if err := p.checkTypes(node, ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkArguments checks each argument of a function call against its parameter type
+func (p *exprParser) checkArguments(call *FunctionCall) error {
+	for i, arg := range call.Args {
+		query, singular := isQuery(arg)
+		inner, isCall := arg.(*FunctionCall)
+
+		switch call.Func.Params[i] {
+		case rfc9535.ValueType:
+			if query && !singular {
+				return p.errorAt(arg, "argument %d of %s() must be a single value, not a query that can select several", i+1, call.Func.Name)
+			}
+			if err := p.checkTypes(arg, comparisonContext); err != nil {
+				return err
+			}
+		case rfc9535.LogicalType:
+			switch {
+			case query:
+				if err := p.checkQuery(arg); err != nil {
+					return err
+				}
+			case isCall && inner.Func.Result == rfc9535.NodesType:
+				if err := p.checkArguments(inner); err != nil {
+					return err
+				}
+			default:
+				if _, literal := arg.(*Literal); literal {
+					return p.errorAt(arg, "argument %d of %s() must be a logical expression", i+1, call.Func.Name)
+				}
+				if err := p.checkTypes(arg, logicalContext); err != nil {
+					return err
+				}
+			}
+		case rfc9535.NodesType:
+			switch {
+			case query:
+				if err := p.checkQuery(arg); err != nil {
+					return err
+				}
+			case isCall && inner.Func.Result == rfc9535.NodesType:
+				if err := p.checkArguments(inner); err != nil {
+					return err
+				}
+			default:
+				return p.errorAt(arg, "argument %d of %s() must be a query", i+1, call.Func.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// checkQuery checks the expressions nested in a query passed to a function
+func (p *exprParser) checkQuery(node Node) error {
+	switch n := node.(type) {
+	case *Member:
+		return p.checkQuery(n.Object)
+	case *Index:
+		if err := p.checkQuery(n.Object); err != nil {
+			return err
+		}
+		return p.checkTypes(n.Index, anyContext)
+	case *FilterSelector:
+		if err := p.checkQuery(n.Object); err != nil {
+			return err
+		}
+		return p.checkTypes(n.Predicate, logicalContext)
+	case *Wildcard:
+		return p.checkQuery(n.Object)
+	case *Descendants:
+		return p.checkQuery(n.Object)
+	}
+	return nil
+}
+
+// errorAt reports an error at the recorded position of node
+func (p *exprParser) errorAt(node Node, format string, args ...interface{}) error {
+	return p.errorf(token{pos: p.positions[node]}, format, args...)
+}
+
+// isQuery reports whether node is a query rooted at @, $ or @root, made of member
+// access, indices, nested filters, wildcards and descendant segments, and whether it
+// selects at most one node
+func isQuery(node Node) (query, singular bool) {
+	singular = true
+	for {
+		switch n := node.(type) {
+		case *ContextVar:
+			return n.Name == "@" || n.Name == "$" || n.Name == "@root", singular
+		case *Member:
+			node = n.Object
+		case *Index:
+			node = n.Object
+		case *FilterSelector:
+			node, singular = n.Object, false
+		case *Wildcard:
+			node, singular = n.Object, false
+		case *Descendants:
+			node, singular = n.Object, false
+		default:
+			return false, false
+		}
+	}
+}
+
+// callFunction evaluates the arguments of a function extension according to its
+// parameter types and calls it. Nothing is returned as undefined.
+func (ev *exprEvaluator) callFunction(n *FunctionCall, ctx *types.Context) interface{} {
+	args := make([]interface{}, len(n.Args))
+	for i, arg := range n.Args {
+		args[i] = ev.functionArgument(arg, n.Func.Params[i], ctx)
+	}
+
+	result := n.Func.Call(args)
+	if result == rfc9535.Nothing {
+		return undefined
+	}
+	return result
+}
+
+// functionArgument converts an argument to its parameter type. Queries select nodes
+// with RFC 9535 semantics, so that reading below null yields Nothing rather than an error.
+func (ev *exprEvaluator) functionArgument(arg Node, param rfc9535.Type, ctx *types.Context) interface{} {
+	if query, _ := isQuery(arg); query {
+		nodes := ev.selectNodes(arg, ctx)
+		switch param {
+		case rfc9535.ValueType:
+			if len(nodes) != 1 {
+				return rfc9535.Nothing
+			}
+			return nodes[0]
+		case rfc9535.LogicalType:
+			return len(nodes) > 0
+		default:
+			return rfc9535.Nodes(nodes)
+		}
+	}
+
+	value := ev.eval(arg, ctx)
+	switch param {
+	case rfc9535.ValueType:
+		if value == undefined {
+			return rfc9535.Nothing
+		}
+		return value
+	case rfc9535.LogicalType:
+		if nodes, ok := value.(rfc9535.Nodes); ok {
+			return len(nodes) > 0
+		}
+		return isTruthy(value)
+	default:
+		return value
+	}
+}
+
+// selectNodes evaluates a query passed to a function and returns the selected values
+func (ev *exprEvaluator) selectNodes(node Node, ctx *types.Context) []interface{} {
+	switch n := node.(type) {
+	case *ContextVar:
+		return []interface{}{contextValue(n.Name, ctx)}
+	case *Member:
+		var selected []interface{}
+		for _, value := range ev.selectNodes(n.Object, ctx) {
+			if member, ok := rfc9535.ObjectMember(value, n.Property); ok {
+				selected = append(selected, member)
+			}
+		}
+		return selected
+	case *Index:
+		key := ev.eval(n.Index, ctx)
+		var selected []interface{}
+		for _, value := range ev.selectNodes(n.Object, ctx) {
+			if element, ok := selectIndex(value, key); ok {
+				selected = append(selected, element)
+			}
+		}
+		return selected
+	case *Wildcard:
+		var selected []interface{}
+		for _, value := range ev.selectNodes(n.Object, ctx) {
+			selected = append(selected, childValues(value)...)
+		}
+		return selected
+	case *Descendants:
+		var selected []interface{}
+		for _, value := range ev.selectNodes(n.Object, ctx) {
+			selected = ev.appendDescendants(selected, value, n.Property)
+		}
+		return selected
+	case *FilterSelector:
+		ev.enter()
+		defer ev.leave()
+
+		var selected []interface{}
+		for _, value := range ev.selectNodes(n.Object, ctx) {
+			eachChild(value, func(child interface{}, property string, index int) {
+				childContext := types.NewArrayElementContext(ctx.Root, child, value, property, "", index, value)
+				childContext.Sandbox = ctx.Sandbox
+				if isTruthy(ev.eval(n.Predicate, childContext)) {
+					selected = append(selected, child)
+				}
+			})
+		}
+		return selected
+	}
+	return nil
+}
+
+// selectIndex reads an array element by number, counting negative indices from the
+// end, or an object member by name
+func selectIndex(value, key interface{}) (interface{}, bool) {
+	switch k := key.(type) {
+	case float64:
+		arr, ok := value.([]interface{})
+		if !ok || k != math.Trunc(k) {
+			return nil, false
+		}
+		idx := int(k)
+		if idx < 0 {
+			idx += len(arr)
+		}
+		if idx < 0 || idx >= len(arr) {
+			return nil, false
+		}
+		return arr[idx], true
+	case string:
+		return rfc9535.ObjectMember(value, k)
+	}
+	return nil, false
+}
+
+// childValues returns the elements of an array or the member values of an object
+func childValues(value interface{}) []interface{} {
+	if arr, ok := value.([]interface{}); ok {
+		return arr
+	}
+	members, _ := rfc9535.ObjectMembers(value)
+	values := make([]interface{}, len(members))
+	for i, member := range members {
+		values[i] = member.Value
+	}
+	return values
+}
+
+// appendDescendants appends the members named property, or every child when property
+// is empty, of value and of all its descendants, parents before children
+func (ev *exprEvaluator) appendDescendants(selected []interface{}, value interface{}, property string) []interface{} {
+	ev.enter()
+	defer ev.leave()
+
+	if property == "" {
+		selected = append(selected, childValues(value)...)
+	} else if member, ok := rfc9535.ObjectMember(value, property); ok {
+		selected = append(selected, member)
+	}
+	for _, child := range childValues(value) {
+		selected = ev.appendDescendants(selected, child, property)
+	}
+	return selected
+}
+
+// eachChild calls visit for each element of an array or member of an object, with
+// its property name and position
+func eachChild(value interface{}, visit func(child interface{}, property string, index int)) {
+	if arr, ok := value.([]interface{}); ok {
+		for i, child := range arr {
+			visit(child, strconv.Itoa(i), i)
+		}
+		return
+	}
+	members, _ := rfc9535.ObjectMembers(value)
+	for i, member := range members {
+		visit(member.Value, member.Key, i)
+	}
+}
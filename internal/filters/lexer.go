@@ -0,0 +1,288 @@
+package filters
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// tokenKind classifies a lexical token of a filter expression
+type tokenKind int
+
+const (
+	tokEOF     tokenKind = iota
+	tokNumber            // 42, 3.14, 1e3
+	tokString            // 'text' or "text"
+	tokRegex             // /pattern/flags
+	tokIdent             // name, true, typeof, ...
+	tokContext           // @, @property, @parent, ..., $
+	tokPunct             // operators and punctuation
+)
+
+// token is a single lexical unit with its byte offset in the expression
+type token struct {
+	kind  tokenKind
+	text  string  // Raw text for identifiers, context variables and punctuation
+	num   float64 // Value of a number token
+	str   string  // Unescaped value of a string token, or the pattern of a regex token
+	flags string  // Flags of a regex token
+	pos   int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return strconv.Quote(t.str)
+	case tokRegex:
+		return "/" + t.str + "/" + t.flags
+	default:
+		return "'" + t.text + "'"
+	}
+}
+
+// punctuators are matched longest first
+var punctuators = []string{
+	"===", "!==",
+	"==", "!=", "<=", ">=", "&&", "||",
+	"<", ">", "!", "+", "-", "*", "/", "%", "(", ")", "[", "]", ".", ",", "?", ":",
+}
+
+// lexer splits a filter expression into tokens
+type lexer struct {
+	src    string
+	pos    int
+	tokens []token
+}
+
+// tokenize splits src into tokens, ending with a tokEOF token
+func tokenize(src string) ([]token, error) {
+	l := &lexer{src: src}
+	for {
+		tok, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		l.tokens = append(l.tokens, tok)
+		if tok.kind == tokEOF {
+			return l.tokens, nil
+		}
+	}
+}
+
+func (l *lexer) errorf(pos int, format string, args ...interface{}) error {
+	return &SyntaxError{Expr: l.src, Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.src) && isSpace(l.src[l.pos]) {
+		l.pos++
+	}
+	if l.pos >= len(l.src) {
+		return token{kind: tokEOF, pos: l.pos}, nil
+	}
+
+	start := l.pos
+	ch := l.src[l.pos]
+
+	switch {
+	case isDigit(ch) || (ch == '.' && l.pos+1 < len(l.src) && isDigit(l.src[l.pos+1])):
+		return l.number()
+	case ch == '\'' || ch == '"':
+		return l.string(ch)
+	case ch == '@':
+		l.pos++
+		for l.pos < len(l.src) && isIdentPart(l.src[l.pos]) {
+			l.pos++
+		}
+		return token{kind: tokContext, text: l.src[start:l.pos], pos: start}, nil
+	case ch == '$' && (l.pos+1 >= len(l.src) || !isIdentPart(l.src[l.pos+1])):
+		l.pos++
+		return token{kind: tokContext, text: "$", pos: start}, nil
+	case isIdentStart(ch):
+		for l.pos < len(l.src) && isIdentPart(l.src[l.pos]) {
+			l.pos++
+		}
+		return token{kind: tokIdent, text: l.src[start:l.pos], pos: start}, nil
+	case ch == '/' && l.regexAllowed():
+		return l.regex()
+	}
+
+	for _, p := range punctuators {
+		if strings.HasPrefix(l.src[l.pos:], p) {
+			l.pos += len(p)
+			return token{kind: tokPunct, text: p, pos: start}, nil
+		}
+	}
+
+	if ch == '=' {
+		return token{}, l.errorf(start, "assignment is not supported")
+	}
+	r, _ := utf8.DecodeRuneInString(l.src[l.pos:])
+	return token{}, l.errorf(start, "unexpected character %q", r)
+}
+
+// regexAllowed reports whether a '/' starts a regex literal rather than a division,
+// which is the case whenever the previous token cannot end an operand
+func (l *lexer) regexAllowed() bool {
+	if len(l.tokens) == 0 {
+		return true
+	}
+	prev := l.tokens[len(l.tokens)-1]
+	switch prev.kind {
+	case tokNumber, tokString, tokRegex, tokContext:
+		return false
+	case tokIdent:
+		return prev.text == "typeof"
+	case tokPunct:
+		return prev.text != ")" && prev.text != "]"
+	}
+	return true
+}
+
+func (l *lexer) number() (token, error) {
+	start := l.pos
+	for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+		l.pos++
+	}
+	if l.pos < len(l.src) && l.src[l.pos] == '.' {
+		l.pos++
+		for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+			l.pos++
+		}
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == 'e' || l.src[l.pos] == 'E') {
+		l.pos++
+		if l.pos < len(l.src) && (l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+			l.pos++
+		}
+		for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+			l.pos++
+		}
+	}
+	if l.pos < len(l.src) && isIdentStart(l.src[l.pos]) {
+		return token{}, l.errorf(l.pos, "identifier directly after number")
+	}
+
+	text := l.src[start:l.pos]
+	num, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return token{}, l.errorf(start, "invalid number %s", text)
+	}
+	return token{kind: tokNumber, text: text, num: num, pos: start}, nil
+}
+
+func (l *lexer) string(quote byte) (token, error) {
+	start := l.pos
+	l.pos++ // opening quote
+
+	var sb strings.Builder
+	for l.pos < len(l.src) {
+		ch := l.src[l.pos]
+		switch {
+		case ch == quote:
+			l.pos++
+			return token{kind: tokString, text: l.src[start:l.pos], str: sb.String(), pos: start}, nil
+		case ch == '\\':
+			if l.pos+1 >= len(l.src) {
+				return token{}, l.errorf(l.pos, "unterminated string")
+			}
+			l.pos++
+			esc := l.src[l.pos]
+			switch esc {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			case 'r':
+				sb.WriteByte('\r')
+			case 'b':
+				sb.WriteByte('\b')
+			case 'f':
+				sb.WriteByte('\f')
+			case 'v':
+				sb.WriteByte('\v')
+			case '0':
+				sb.WriteByte(0)
+			case 'u':
+				if l.pos+4 >= len(l.src) {
+					return token{}, l.errorf(l.pos, "invalid unicode escape")
+				}
+				code, err := strconv.ParseUint(l.src[l.pos+1:l.pos+5], 16, 32)
+				if err != nil {
+					return token{}, l.errorf(l.pos, "invalid unicode escape")
+				}
+				sb.WriteRune(rune(code))
+				l.pos += 4
+			default:
+				// \\, \', \", \/ and unknown escapes stand for the character itself
+				sb.WriteByte(esc)
+			}
+			l.pos++
+		default:
+			sb.WriteByte(ch)
+			l.pos++
+		}
+	}
+	return token{}, l.errorf(start, "unterminated string")
+}
+
+func (l *lexer) regex() (token, error) {
+	start := l.pos
+	l.pos++ // opening slash
+
+	var sb strings.Builder
+	inClass := false
+	for {
+		if l.pos >= len(l.src) {
+			return token{}, l.errorf(start, "unterminated regular expression")
+		}
+		ch := l.src[l.pos]
+		if ch == '\\' && l.pos+1 < len(l.src) {
+			sb.WriteString(l.src[l.pos : l.pos+2])
+			l.pos += 2
+			continue
+		}
+		l.pos++
+		if ch == '/' && !inClass {
+			break
+		}
+		switch ch {
+		case '[':
+			inClass = true
+		case ']':
+			inClass = false
+		}
+		sb.WriteByte(ch)
+	}
+
+	flagStart := l.pos
+	for l.pos < len(l.src) && isIdentPart(l.src[l.pos]) {
+		l.pos++
+	}
+	return token{
+		kind:  tokRegex,
+		text:  l.src[start:l.pos],
+		str:   sb.String(),
+		flags: l.src[flagStart:l.pos],
+		pos:   start,
+	}, nil
+}
+
+func isSpace(ch byte) bool {
+	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r'
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+func isIdentStart(ch byte) bool {
+	return ch == '_' || ch == '$' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || ch >= utf8.RuneSelf
+}
+
+func isIdentPart(ch byte) bool {
+	return isIdentStart(ch) || isDigit(ch)
+}
@@ -0,0 +1,476 @@
+package filters
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/reclaimprotocol/jsonpathplus-go/internal/rfc9535"
+)
+
+// maxNestingDepth bounds how deeply expressions may nest, so hostile input cannot exhaust the stack
+const maxNestingDepth = 128
+
+// binaryPrecedence gives the binding power of each binary operator; higher binds tighter
+var binaryPrecedence = map[string]int{
+	"||":  1,
+	"&&":  2,
+	"==":  3,
+	"!=":  3,
+	"===": 3,
+	"!==": 3,
+	"<":   4,
+	"<=":  4,
+	">":   4,
+	">=":  4,
+	"+":   5,
+	"-":   5,
+	"*":   6,
+	"/":   6,
+	"%":   6,
+}
+
+// contextVars are the context variables a filter may reference
+var contextVars = map[string]bool{
+	"@":               true,
+	"@property":       true,
+	"@parent":         true,
+	"@parentProperty": true,
+	"@path":           true,
+	"@root":           true,
+	"$":               true,
+}
+
+// SyntaxError reports a filter expression that cannot be parsed
+type SyntaxError struct {
+	Expr string // The filter expression
+	Pos  int    // Byte offset of the offending token
+	Msg  string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("invalid filter expression %q at position %d: %s", e.Expr, e.Pos, e.Msg)
+}
+
+// exprParser is a precedence-climbing parser over the tokens of one expression
+type exprParser struct {
+	src       string
+	tokens    []token
+	pos       int
+	depth     int
+	functions *rfc9535.Registry // Function extensions callable by name
+	positions map[Node]int      // Offsets of nodes the type check may report
+}
+
+// Parse parses a filter expression such as "@.price < 10 && @.isbn" into an AST
+func Parse(expr string) (Node, error) {
+	return ParseWithFunctions(expr, nil)
+}
+
+// ParseWithFunctions is like Parse but resolves calls of function extensions in
+// functions, which may be nil for the RFC 9535 builtins alone
+func ParseWithFunctions(expr string, functions *rfc9535.Registry) (Node, error) {
+	return parse(expr, functions, logicalContext)
+}
+
+// ParseScriptWithFunctions parses the content of a script selector such as
+// (@.length-1), whose value is a key rather than a test, resolving function
+// extensions in functions
+func ParseScriptWithFunctions(script string, functions *rfc9535.Registry) (Node, error) {
+	return parse(strings.TrimSpace(script), functions, anyContext)
+}
+
+// parse parses an expression whose value is used in ctx
+func parse(expr string, functions *rfc9535.Registry, ctx exprContext) (Node, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{src: expr, tokens: tokens, functions: functions, positions: make(map[Node]int)}
+
+	if p.peek().kind == tokEOF {
+		return nil, p.errorf(p.peek(), "empty expression")
+	}
+	node, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, p.errorf(tok, "unexpected %s", tok)
+	}
+	if err := p.checkTypes(node, ctx); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// ParseFilter parses the content of a filter selector, with or without the
+// leading "?" and the parentheses around the expression
+func ParseFilter(filter string) (Node, error) {
+	return ParseFilterWithFunctions(filter, nil)
+}
+
+// ParseFilterWithFunctions is like ParseFilter but resolves function extensions in functions
+func ParseFilterWithFunctions(filter string, functions *rfc9535.Registry) (Node, error) {
+	filter = strings.TrimSpace(filter)
+	filter = strings.TrimSpace(strings.TrimPrefix(filter, "?"))
+	return ParseWithFunctions(filter, functions)
+}
+
+func (p *exprParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) advance() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *exprParser) isPunct(text string) bool {
+	tok := p.peek()
+	return tok.kind == tokPunct && tok.text == text
+}
+
+func (p *exprParser) expect(text string) error {
+	if !p.isPunct(text) {
+		return p.errorf(p.peek(), "expected '%s' but found %s", text, p.peek())
+	}
+	p.advance()
+	return nil
+}
+
+func (p *exprParser) errorf(tok token, format string, args ...interface{}) error {
+	return &SyntaxError{Expr: p.src, Pos: tok.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// enter guards against runaway nesting; every call must be paired with leave
+func (p *exprParser) enter() error {
+	p.depth++
+	if p.depth > maxNestingDepth {
+		return p.errorf(p.peek(), "expression nested too deeply")
+	}
+	return nil
+}
+
+func (p *exprParser) leave() {
+	p.depth--
+}
+
+// parseExpression parses a full expression including the ternary operator
+func (p *exprParser) parseExpression() (Node, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	test, err := p.parseBinary(1)
+	if err != nil {
+		return nil, err
+	}
+	if !p.isPunct("?") {
+		return test, nil
+	}
+	p.advance()
+
+	consequent, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	alternate, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	return &Conditional{Test: test, Consequent: consequent, Alternate: alternate}, nil
+}
+
+// parseBinary parses binary operators that bind at least as tightly as minPrec
+func (p *exprParser) parseBinary(minPrec int) (Node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		tok := p.peek()
+		if tok.kind != tokPunct {
+			return left, nil
+		}
+		prec, ok := binaryPrecedence[tok.text]
+		if !ok || prec < minPrec {
+			return left, nil
+		}
+		p.advance()
+
+		right, err := p.parseBinary(prec + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &Binary{Op: tok.text, Left: left, Right: right}
+	}
+}
+
+// parseUnary parses prefix operators
+func (p *exprParser) parseUnary() (Node, error) {
+	tok := p.peek()
+	isUnary := (tok.kind == tokPunct && (tok.text == "!" || tok.text == "-" || tok.text == "+")) ||
+		(tok.kind == tokIdent && tok.text == "typeof")
+	if !isUnary {
+		return p.parsePostfix()
+	}
+
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	p.advance()
+	operand, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return &Unary{Op: tok.text, Operand: operand}, nil
+}
+
+// parsePostfix parses member access, computed access, nested filters and calls
+func (p *exprParser) parsePostfix() (Node, error) {
+	node, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		switch {
+		case p.isPunct("."):
+			dot := p.advance()
+			if p.isPunct(".") {
+				p.advance()
+				property := ""
+				if !p.isPunct("*") && p.peek().kind != tokIdent {
+					return nil, p.errorf(p.peek(), "expected property name or '*' after '..' but found %s", p.peek())
+				}
+				if tok := p.advance(); tok.kind == tokIdent {
+					property = tok.text
+				}
+				node = &Descendants{Object: node, Property: property}
+				p.positions[node] = dot.pos
+				continue
+			}
+			if p.isPunct("*") {
+				p.advance()
+				node = &Wildcard{Object: node}
+				p.positions[node] = dot.pos
+				continue
+			}
+			tok := p.advance()
+			if tok.kind != tokIdent {
+				return nil, p.errorf(tok, "expected property name after '.' but found %s", tok)
+			}
+			node = &Member{Object: node, Property: tok.text}
+
+		case p.isPunct("["):
+			bracket := p.advance()
+			if p.isPunct("*") {
+				p.advance()
+				if err := p.expect("]"); err != nil {
+					return nil, err
+				}
+				node = &Wildcard{Object: node}
+				p.positions[node] = bracket.pos
+				continue
+			}
+			if p.isPunct("?") {
+				p.advance()
+				predicate, err := p.parseExpression()
+				if err != nil {
+					return nil, err
+				}
+				node = &FilterSelector{Object: node, Predicate: predicate}
+			} else {
+				index, err := p.parseExpression()
+				if err != nil {
+					return nil, err
+				}
+				node = &Index{Object: node, Index: index}
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+
+		case p.isPunct("("):
+			if ident, ok := node.(*Identifier); ok {
+				if fn, ok := p.functions.Lookup(ident.Name); ok {
+					call, err := p.parseFunctionCall(fn)
+					if err != nil {
+						return nil, err
+					}
+					node = call
+					continue
+				}
+			}
+			p.advance()
+			args, err := p.parseList(")")
+			if err != nil {
+				return nil, err
+			}
+			node = &Call{Callee: node, Args: args, Pattern: literalPattern(node, args)}
+
+		default:
+			return node, nil
+		}
+	}
+}
+
+// parsePrimary parses literals, names, context variables and groups
+func (p *exprParser) parsePrimary() (Node, error) {
+	tok := p.advance()
+
+	switch tok.kind {
+	case tokNumber:
+		return &Literal{Value: tok.num}, nil
+
+	case tokString:
+		return &Literal{Value: tok.str}, nil
+
+	case tokRegex:
+		re, err := compileRegex(tok.str, tok.flags)
+		if err != nil {
+			return nil, p.errorf(tok, "%v", err)
+		}
+		return &RegexLiteral{Pattern: tok.str, Flags: tok.flags, Regexp: re}, nil
+
+	case tokContext:
+		if IsTypeSelector(tok.text[1:]) && p.isPunct("(") {
+			p.advance()
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return &TypeSelector{Type: tok.text[1:]}, nil
+		}
+		if !contextVars[tok.text] {
+			return nil, p.errorf(tok, "unknown context variable %s", tok.text)
+		}
+		return &ContextVar{Name: tok.text}, nil
+
+	case tokIdent:
+		switch tok.text {
+		case "true":
+			return &Literal{Value: true}, nil
+		case "false":
+			return &Literal{Value: false}, nil
+		case "null":
+			return &Literal{Value: nil}, nil
+		case "undefined":
+			return &Literal{Value: undefined}, nil
+		case "NaN":
+			return &Literal{Value: math.NaN()}, nil
+		case "Infinity":
+			return &Literal{Value: math.Inf(1)}, nil
+		}
+		return &Identifier{Name: tok.text}, nil
+
+	case tokPunct:
+		switch tok.text {
+		case "(":
+			node, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return node, nil
+		case "[":
+			elements, err := p.parseList("]")
+			if err != nil {
+				return nil, err
+			}
+			return &ArrayLiteral{Elements: elements}, nil
+		}
+	}
+
+	if tok.kind == tokEOF {
+		return nil, p.errorf(tok, "unexpected end of expression")
+	}
+	return nil, p.errorf(tok, "unexpected %s", tok)
+}
+
+// parseList parses comma-separated expressions up to and including the closing punctuator
+func (p *exprParser) parseList(closing string) ([]Node, error) {
+	var nodes []Node
+	if p.isPunct(closing) {
+		p.advance()
+		return nodes, nil
+	}
+
+	for {
+		node, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+
+		if p.isPunct(",") {
+			p.advance()
+			continue
+		}
+		if err := p.expect(closing); err != nil {
+			return nil, err
+		}
+		return nodes, nil
+	}
+}
+
+// literalPattern compiles the pattern of a call such as @.name.match('^a'), so that a
+// string literal is not compiled again for every value the filter is applied to
+func literalPattern(callee Node, args []Node) *regexp.Regexp {
+	member, ok := callee.(*Member)
+	if !ok || member.Property != "match" || len(args) == 0 {
+		return nil
+	}
+	literal, ok := args[0].(*Literal)
+	if !ok {
+		return nil
+	}
+	pattern, ok := literal.Value.(string)
+	if !ok {
+		return nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil
+	}
+	return re
+}
+
+// compileRegex compiles a JavaScript regex literal into a Go regexp
+func compileRegex(pattern, flags string) (*regexp.Regexp, error) {
+	prefix := ""
+	for _, flag := range flags {
+		switch flag {
+		case 'i', 'm', 's':
+			if !strings.ContainsRune(prefix, flag) {
+				prefix += string(flag)
+			}
+		case 'g', 'y', 'u', 'd':
+			// Global, sticky, unicode and indices flags do not change whether a string matches
+		default:
+			return nil, fmt.Errorf("invalid regular expression flag %q", flag)
+		}
+	}
+	if prefix != "" {
+		pattern = "(?" + prefix + ")" + pattern
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression: %v", err)
+	}
+	return re, nil
+}
@@ -0,0 +1,68 @@
+package filters
+
+import (
+	"math"
+)
+
+// typeSelectors are the names of the JSONPath-Plus type selectors, written @name()
+var typeSelectors = map[string]bool{
+	"null":      true,
+	"boolean":   true,
+	"number":    true,
+	"integer":   true,
+	"nonFinite": true,
+	"string":    true,
+	"scalar":    true,
+	"array":     true,
+	"object":    true,
+	"other":     true,
+}
+
+// IsTypeSelector reports whether name, such as "number", names a type selector
+func IsTypeSelector(name string) bool {
+	return typeSelectors[name]
+}
+
+// MatchesType reports whether value has the type a selector names, following
+// JSONPath-Plus: number excludes NaN and the infinities that nonFinite covers, object
+// includes arrays as JavaScript's typeof does, and other covers Go values that have
+// no JSON type
+func MatchesType(name string, value interface{}) bool {
+	num, isNumber := toNumberValue(value)
+	switch name {
+	case "null":
+		return value == nil
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		return isNumber && !math.IsNaN(num) && !math.IsInf(num, 0)
+	case "integer":
+		return isNumber && !math.IsInf(num, 0) && num == math.Trunc(num)
+	case "nonFinite":
+		return isNumber && (math.IsNaN(num) || math.IsInf(num, 0))
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "scalar":
+		return isScalar(value)
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "object":
+		return isObjectLike(value)
+	case "other":
+		return !isScalar(value) && !isObjectLike(value)
+	}
+	return false
+}
+
+// isScalar reports whether value is null, a boolean, a number or a string
+func isScalar(value interface{}) bool {
+	switch value.(type) {
+	case nil, bool, string:
+		return true
+	}
+	_, ok := toNumberValue(value)
+	return ok
+}
@@ -0,0 +1,102 @@
+// Package lru provides a concurrency-safe cache that holds a bounded number of
+// entries, dropping the least recently used one when it is full.
+package lru
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Cache maps comparable keys to values, keeping at most its capacity of them
+type Cache struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[interface{}]*list.Element
+	order    *list.List // Front is the most recently used entry
+	stats    Stats
+}
+
+// Stats reports the activity of a Cache
+type Stats struct {
+	Hits      int64 // Lookups that found an entry
+	Misses    int64 // Lookups that found none
+	Evictions int64 // Entries dropped to make room for others
+	Size      int   // Entries currently cached
+	Capacity  int   // Maximum number of entries
+}
+
+type entry struct {
+	key   interface{}
+	value interface{}
+}
+
+// New creates a cache holding up to capacity entries
+func New(capacity int) *Cache {
+	return &Cache{
+		capacity: capacity,
+		entries:  make(map[interface{}]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Get returns the value cached under key, marking it as recently used
+func (c *Cache) Get(key interface{}) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+	c.stats.Hits++
+	c.order.MoveToFront(element)
+	return element.Value.(*entry).value, true
+}
+
+// Add caches value under key, dropping the least recently used entry when the
+// cache is full
+func (c *Cache) Add(key, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*entry).value = value
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&entry{key: key, value: value})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*entry).key)
+		c.stats.Evictions++
+	}
+}
+
+// Len returns the number of cached entries
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Stats returns the cache counters along with its current size and capacity
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Size = c.order.Len()
+	stats.Capacity = c.capacity
+	return stats
+}
+
+// Purge removes every entry. The hit, miss and eviction counters are kept.
+func (c *Cache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[interface{}]*list.Element)
+	c.order.Init()
+}
@@ -28,6 +28,8 @@ func (o *OperatorEvaluator) EvaluatePropertyNames(ctx types.Result, options *typ
 			results = append(results, types.Result{
 				Value:          key,
 				Path:           fmt.Sprintf("%s~[%d]", ctx.Path, index),
+				Pointer:        utils.MemberPointer(ctx.Pointer, key),
+				PropertyName:   true,
 				Parent:         ctx.Value,
 				ParentProperty: strconv.Itoa(index),
 				Index:          index,
@@ -42,6 +44,8 @@ func (o *OperatorEvaluator) EvaluatePropertyNames(ctx types.Result, options *typ
 			results = append(results, types.Result{
 				Value:          key,
 				Path:           fmt.Sprintf("%s~[%d]", ctx.Path, index),
+				Pointer:        utils.MemberPointer(ctx.Pointer, key),
+				PropertyName:   true,
 				Parent:         ctx.Value,
 				ParentProperty: strconv.Itoa(index),
 				Index:          index,
@@ -56,6 +60,8 @@ func (o *OperatorEvaluator) EvaluatePropertyNames(ctx types.Result, options *typ
 			results = append(results, types.Result{
 				Value:          keyStr,
 				Path:           fmt.Sprintf("%s~[%d]", ctx.Path, index),
+				Pointer:        utils.MemberPointer(ctx.Pointer, keyStr),
+				PropertyName:   true,
 				Parent:         ctx.Value,
 				ParentProperty: strconv.Itoa(index),
 				Index:          index,
@@ -80,6 +86,7 @@ func (o *OperatorEvaluator) EvaluateParent(ctx types.Result, options *types.Opti
 		results = append(results, types.Result{
 			Value:          ctx.Parent,
 			Path:           parentPath,
+			Pointer:        utils.ParentPointer(ctx.Pointer),
 			Parent:         nil, // We don't track grandparents for now
 			ParentProperty: "",
 			Index:          0,
@@ -98,13 +105,15 @@ func (o *OperatorEvaluator) EvaluateParentWithDeduplication(contexts []types.Res
 	for _, ctx := range contexts {
 		if ctx.Parent != nil {
 			parentPath := o.calculateParentPath(ctx.Path)
+			parentPointer := utils.ParentPointer(ctx.Pointer)
 
-			// Only add if we haven't seen this parent path before
-			if !seen[parentPath] {
-				seen[parentPath] = true
+			// Only add if we haven't seen this parent before
+			if !seen[parentPointer] {
+				seen[parentPointer] = true
 				results = append(results, types.Result{
 					Value:          ctx.Parent,
 					Path:           parentPath,
+					Pointer:        parentPointer,
 					Parent:         nil,
 					ParentProperty: "",
 					Index:          0,
@@ -5,17 +5,27 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/reclaimprotocol/jsonpathplus-go/internal/filters"
+	"github.com/reclaimprotocol/jsonpathplus-go/internal/rfc9535"
 	"github.com/reclaimprotocol/jsonpathplus-go/pkg/types"
 )
 
 // Parser handles JSONPath expression parsing
-type Parser struct{}
+type Parser struct {
+	functions *rfc9535.Registry // Function extensions filters may call; nil for the builtins
+}
 
 // NewParser creates a new parser instance
 func NewParser() *Parser {
 	return &Parser{}
 }
 
+// NewParserWithFunctions creates a parser whose filters may call the function
+// extensions in functions
+func NewParserWithFunctions(functions *rfc9535.Registry) *Parser {
+	return &Parser{functions: functions}
+}
+
 // Parse parses a JSONPath expression into an AST
 func (p *Parser) Parse(path string) (*types.AstNode, error) {
 	if path == "" {
@@ -75,6 +85,8 @@ func (p *Parser) parseNextSegment(path string) (*types.AstNode, int, error) {
 		return p.parseDotSegment(path)
 	case '[':
 		return p.parseBracketSegment(path)
+	case '@':
+		return p.parseTypeSelector(path)
 	default:
 		return nil, 0, fmt.Errorf("unexpected character: %c at position 0", path[0])
 	}
@@ -96,6 +108,11 @@ func (p *Parser) parseDotSegment(path string) (*types.AstNode, int, error) {
 			return recursiveNode, 3, nil
 		}
 
+		// A type selector after .. tests every node, e.g. $..@number()
+		if typeSelectorLength(path[2:]) > 0 {
+			return recursiveNode, 2, nil
+		}
+
 		// Check if there's more after the ..
 		if len(path) > 2 {
 			// If the next character is not . or [, it's a direct property after ..
@@ -115,6 +132,9 @@ func (p *Parser) parseDotSegment(path string) (*types.AstNode, int, error) {
 						for remaining != "" {
 							nextNode, nextPos, err := p.parseNextSegment(remaining)
 							if err != nil {
+								if _, ok := err.(*filters.SyntaxError); ok {
+									return nil, 0, err
+								}
 								break
 							}
 							currentNode.Children = append(currentNode.Children, nextNode)
@@ -176,6 +196,16 @@ func (p *Parser) parseDotSegment(path string) (*types.AstNode, int, error) {
 	return &types.AstNode{Type: "property", Value: property}, end, nil
 }
 
+// parseTypeSelector parses a JSONPath-Plus type selector such as @number(), which
+// keeps the current nodes whose values have that type
+func (p *Parser) parseTypeSelector(path string) (*types.AstNode, int, error) {
+	n := typeSelectorLength(path)
+	if n == 0 {
+		return nil, 0, fmt.Errorf("unknown type selector at position 0")
+	}
+	return &types.AstNode{Type: "type_selector", Value: path[1 : n-2]}, n, nil
+}
+
 // parseBracketSegment parses segments starting with '['
 func (p *Parser) parseBracketSegment(path string) (*types.AstNode, int, error) {
 	end := p.findMatchingBracket(path, 0)
@@ -207,6 +237,9 @@ func (p *Parser) parseBracketSegment(path string) (*types.AstNode, int, error) {
 		chainContent := remaining[1:chainEnd]
 		chainNode, err := p.parseBracketContent(chainContent)
 		if err != nil {
+			if _, ok := err.(*filters.SyntaxError); ok {
+				return nil, 0, err
+			}
 			break
 		}
 
@@ -259,13 +292,26 @@ func (p *Parser) parseBracketContent(content string) (*types.AstNode, error) {
 
 	// Handle filter expressions
 	if strings.HasPrefix(content, "?") {
-		return &types.AstNode{Type: "filter", Value: content}, nil
+		expr, err := filters.ParseFilterWithFunctions(content, p.functions)
+		if err != nil {
+			return nil, err
+		}
+		return &types.AstNode{Type: "filter", Value: content, Expr: expr}, nil
+	}
+
+	// Handle script expressions, whose value is the key to select
+	if strings.HasPrefix(content, "(") && strings.HasSuffix(content, ")") {
+		expr, err := filters.ParseScriptWithFunctions(content, p.functions)
+		if err != nil {
+			return nil, err
+		}
+		return &types.AstNode{Type: "script", Value: content, Expr: expr}, nil
 	}
 
 	// Handle quoted property names
 	if (strings.HasPrefix(content, "'") && strings.HasSuffix(content, "'")) ||
 		(strings.HasPrefix(content, "\"") && strings.HasSuffix(content, "\"")) {
-		property := content[1 : len(content)-1]
+		property := unquoteName(content)
 
 		// Check for special operators
 		if strings.HasSuffix(property, "~") {
@@ -336,8 +382,7 @@ func (p *Parser) parseUnionPart(part string) (*types.AstNode, error) {
 	// Handle quoted strings
 	if (strings.HasPrefix(part, "'") && strings.HasSuffix(part, "'")) ||
 		(strings.HasPrefix(part, "\"") && strings.HasSuffix(part, "\"")) {
-		property := part[1 : len(part)-1]
-		return &types.AstNode{Type: "property", Value: property}, nil
+		return &types.AstNode{Type: "property", Value: unquoteName(part)}, nil
 	}
 
 	// Handle array indices
@@ -358,16 +403,46 @@ func (p *Parser) parseUnionPart(part string) (*types.AstNode, error) {
 
 // Helper functions
 
+// unquoteName strips the quotes of a quoted name and unescapes the quotes and
+// backslashes result paths escape in it. Other backslashes are kept as written.
+func unquoteName(quoted string) string {
+	name := quoted[1 : len(quoted)-1]
+	if !strings.Contains(name, "\\") {
+		return name
+	}
+	var sb strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] == '\\' && i+1 < len(name) && strings.IndexByte(`'"\\`, name[i+1]) >= 0 {
+			i++
+		}
+		sb.WriteByte(name[i])
+	}
+	return sb.String()
+}
+
 func (p *Parser) findPropertyEnd(path string, start int) int {
 	for i := start; i < len(path); i++ {
 		ch := path[i]
 		if ch == '.' || ch == '[' || ch == '~' || ch == '^' {
 			return i
 		}
+		if ch == '@' && typeSelectorLength(path[i:]) > 0 {
+			return i
+		}
 	}
 	return len(path)
 }
 
+// typeSelectorLength returns the length of the type selector, such as @string(), that
+// path starts with, or 0 if it does not start with one
+func typeSelectorLength(path string) int {
+	end := strings.Index(path, "()")
+	if !strings.HasPrefix(path, "@") || end < 0 || !filters.IsTypeSelector(path[1:end]) {
+		return 0
+	}
+	return end + 2
+}
+
 func (p *Parser) findMatchingBracket(path string, start int) int {
 	if start >= len(path) || path[start] != '[' {
 		return -1
@@ -392,11 +467,12 @@ func (p *Parser) findMatchingBracket(path string, start int) int {
 					return i
 				}
 			}
-		} else {
-			if ch == quoteChar && (i == 0 || path[i-1] != '\\') {
-				inQuotes = false
-				quoteChar = 0
-			}
+		} else if ch == '\\' {
+			// Skip the escaped character, which may be the quote or a backslash
+			i++
+		} else if ch == quoteChar {
+			inQuotes = false
+			quoteChar = 0
 		}
 	}
 
@@ -432,7 +508,10 @@ func (p *Parser) splitUnion(content string) []string {
 			}
 		} else {
 			current.WriteByte(ch)
-			if ch == quoteChar && (i == 0 || content[i-1] != '\\') {
+			if ch == '\\' && i+1 < len(content) {
+				i++
+				current.WriteByte(content[i])
+			} else if ch == quoteChar {
 				inQuotes = false
 				quoteChar = 0
 			}
@@ -0,0 +1,229 @@
+// Package rfc9535 parses JSONPath queries following the RFC 9535 grammar and
+// implements the parts of its semantics that do not depend on walking a document:
+// comparisons, the standard function extensions and normalized paths
+package rfc9535
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Query is a parsed query: the root identifier ($) or, inside filters, the current
+// node identifier (@), followed by segments
+type Query struct {
+	Relative bool // Starts at the current node (@) rather than the root ($)
+	Segments []*Segment
+}
+
+// Segment applies its selectors to the input nodes, or with Descendant set to the
+// input nodes and all of their descendants
+type Segment struct {
+	Descendant bool
+	Selectors  []Selector
+}
+
+// Selector is one of NameSelector, WildcardSelector, IndexSelector, SliceSelector
+// and FilterSelector
+type Selector interface {
+	String() string
+}
+
+// NameSelector selects the member with the given name of an object
+type NameSelector struct {
+	Name string
+}
+
+// WildcardSelector selects every member value of an object or element of an array
+type WildcardSelector struct{}
+
+// IndexSelector selects one element of an array; negative indices count from the end
+type IndexSelector struct {
+	Index int
+}
+
+// SliceSelector selects elements of an array from Start up to End in steps of Step;
+// nil bounds take their defaults
+type SliceSelector struct {
+	Start *int
+	End   *int
+	Step  *int
+}
+
+// FilterSelector selects the children of a node for which Expr holds
+type FilterSelector struct {
+	Expr Expr
+}
+
+// Expr is a node of a filter expression
+type Expr interface {
+	String() string
+}
+
+// LiteralExpr is a string, number (float64), true, false or null literal
+type LiteralExpr struct {
+	Value interface{}
+}
+
+// QueryExpr is an embedded query, used for existence tests and as a comparable
+type QueryExpr struct {
+	Query *Query
+}
+
+// FunctionExpr calls a function extension
+type FunctionExpr struct {
+	Func *Function
+	Args []Expr
+}
+
+// NotExpr negates a logical expression
+type NotExpr struct {
+	Operand Expr
+}
+
+// AndExpr is a logical conjunction
+type AndExpr struct {
+	Left  Expr
+	Right Expr
+}
+
+// OrExpr is a logical disjunction
+type OrExpr struct {
+	Left  Expr
+	Right Expr
+}
+
+// ComparisonExpr compares two comparables with ==, !=, <, <=, > or >=
+type ComparisonExpr struct {
+	Op    string
+	Left  Expr
+	Right Expr
+}
+
+// Singular reports whether the query selects at most one node, which is the case
+// when it only has child segments with a single name or index selector
+func (q *Query) Singular() bool {
+	for _, segment := range q.Segments {
+		if segment.Descendant || len(segment.Selectors) != 1 {
+			return false
+		}
+		switch segment.Selectors[0].(type) {
+		case *NameSelector, *IndexSelector:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// HasFilters reports whether any segment of the query has a filter selector
+func (q *Query) HasFilters() bool {
+	for _, segment := range q.Segments {
+		for _, selector := range segment.Selectors {
+			if _, ok := selector.(*FilterSelector); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (q *Query) String() string {
+	var sb strings.Builder
+	if q.Relative {
+		sb.WriteString("@")
+	} else {
+		sb.WriteString("$")
+	}
+	for _, segment := range q.Segments {
+		sb.WriteString(segment.String())
+	}
+	return sb.String()
+}
+
+func (s *Segment) String() string {
+	parts := make([]string, len(s.Selectors))
+	for i, selector := range s.Selectors {
+		parts[i] = selector.String()
+	}
+	selection := "[" + strings.Join(parts, ", ") + "]"
+	if s.Descendant {
+		return ".." + selection
+	}
+	return selection
+}
+
+func (s *NameSelector) String() string {
+	return quoteName(s.Name)
+}
+
+func (s *WildcardSelector) String() string {
+	return "*"
+}
+
+func (s *IndexSelector) String() string {
+	return strconv.Itoa(s.Index)
+}
+
+func (s *SliceSelector) String() string {
+	bound := func(b *int) string {
+		if b == nil {
+			return ""
+		}
+		return strconv.Itoa(*b)
+	}
+	str := bound(s.Start) + ":" + bound(s.End)
+	if s.Step != nil {
+		str += ":" + bound(s.Step)
+	}
+	return str
+}
+
+func (s *FilterSelector) String() string {
+	return "?" + s.Expr.String()
+}
+
+func (e *LiteralExpr) String() string {
+	switch v := e.Value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return quoteName(v)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return "?"
+	}
+}
+
+func (e *QueryExpr) String() string {
+	return e.Query.String()
+}
+
+func (e *FunctionExpr) String() string {
+	parts := make([]string, len(e.Args))
+	for i, arg := range e.Args {
+		parts[i] = arg.String()
+	}
+	return e.Func.Name + "(" + strings.Join(parts, ", ") + ")"
+}
+
+func (e *NotExpr) String() string {
+	if _, ok := e.Operand.(*ComparisonExpr); ok {
+		return "!(" + e.Operand.String() + ")"
+	}
+	return "!" + e.Operand.String()
+}
+
+func (e *AndExpr) String() string {
+	return "(" + e.Left.String() + " && " + e.Right.String() + ")"
+}
+
+func (e *OrExpr) String() string {
+	return "(" + e.Left.String() + " || " + e.Right.String() + ")"
+}
+
+func (e *ComparisonExpr) String() string {
+	return e.Left.String() + " " + e.Op + " " + e.Right.String()
+}
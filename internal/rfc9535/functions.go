@@ -0,0 +1,252 @@
+package rfc9535
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Type is the declared type of a function parameter or result (RFC 9535 section 2.4.1)
+type Type int
+
+const (
+	// ValueType is a JSON value or Nothing
+	ValueType Type = iota
+	// LogicalType is true or false
+	LogicalType
+	// NodesType is a list of nodes
+	NodesType
+)
+
+func (t Type) String() string {
+	switch t {
+	case ValueType:
+		return "ValueType"
+	case LogicalType:
+		return "LogicalType"
+	case NodesType:
+		return "NodesType"
+	default:
+		return fmt.Sprintf("Type(%d)", int(t))
+	}
+}
+
+// Function is a function extension. Call receives a value or Nothing for each
+// ValueType parameter, a bool for each LogicalType parameter and Nodes for each
+// NodesType parameter, and must return a value of its declared result type.
+type Function struct {
+	Name   string
+	Params []Type
+	Result Type
+	Call   func(args []interface{}) interface{}
+}
+
+// Builtins are the function extensions defined by RFC 9535 section 2.4
+var Builtins = map[string]*Function{
+	"length": {Name: "length", Params: []Type{ValueType}, Result: ValueType, Call: length},
+	"count":  {Name: "count", Params: []Type{NodesType}, Result: ValueType, Call: count},
+	"match":  {Name: "match", Params: []Type{ValueType, ValueType}, Result: LogicalType, Call: match},
+	"search": {Name: "search", Params: []Type{ValueType, ValueType}, Result: LogicalType, Call: search},
+	"value":  {Name: "value", Params: []Type{NodesType}, Result: ValueType, Call: value},
+}
+
+// Registry is a set of function extensions that can grow while queries are parsed
+// concurrently. A nil *Registry holds only the Builtins.
+type Registry struct {
+	mu        sync.RWMutex
+	functions map[string]*Function
+}
+
+// NewRegistry creates a registry holding the Builtins
+func NewRegistry() *Registry {
+	functions := make(map[string]*Function, len(Builtins))
+	for name, fn := range Builtins {
+		functions[name] = fn
+	}
+	return &Registry{functions: functions}
+}
+
+// Lookup returns the function registered under name
+func (r *Registry) Lookup(name string) (*Function, bool) {
+	if r == nil {
+		fn, ok := Builtins[name]
+		return fn, ok
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	fn, ok := r.functions[name]
+	return fn, ok
+}
+
+// Register adds fn under fn.Name. Names cannot be registered twice.
+func (r *Registry) Register(fn *Function) error {
+	for _, param := range fn.Params {
+		if param < ValueType || param > NodesType {
+			return fmt.Errorf("function %s: invalid parameter type %v", fn.Name, param)
+		}
+	}
+	if fn.Result < ValueType || fn.Result > NodesType {
+		return fmt.Errorf("function %s: invalid result type %v", fn.Name, fn.Result)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.functions[fn.Name]; exists {
+		return fmt.Errorf("function %s is already registered", fn.Name)
+	}
+	r.functions[fn.Name] = fn
+	return nil
+}
+
+// length returns the number of characters of a string, elements of an array or
+// members of an object, and Nothing for anything else
+func length(args []interface{}) interface{} {
+	switch v := args[0].(type) {
+	case string:
+		return float64(utf8.RuneCountInString(v))
+	case []interface{}:
+		return float64(len(v))
+	}
+	if members, ok := ObjectMembers(args[0]); ok {
+		return float64(len(members))
+	}
+	return Nothing
+}
+
+// count returns the number of nodes in a node list
+func count(args []interface{}) interface{} {
+	return float64(len(args[0].(Nodes)))
+}
+
+// value returns the value of a single-node list, and Nothing otherwise
+func value(args []interface{}) interface{} {
+	nodes := args[0].(Nodes)
+	if len(nodes) != 1 {
+		return Nothing
+	}
+	return nodes[0]
+}
+
+// match tests whether the whole string matches an I-Regexp
+func match(args []interface{}) interface{} {
+	return regexTest(args, true)
+}
+
+// search tests whether a substring matches an I-Regexp
+func search(args []interface{}) interface{} {
+	return regexTest(args, false)
+}
+
+func regexTest(args []interface{}, full bool) bool {
+	s, ok := args[0].(string)
+	if !ok {
+		return false
+	}
+	pattern, ok := args[1].(string)
+	if !ok {
+		return false
+	}
+	re, err := CompileIRegexp(pattern, full)
+	if err != nil {
+		// A pattern that is not a valid I-Regexp matches nothing
+		return false
+	}
+	return re.MatchString(s)
+}
+
+// regexpCache holds compiled I-Regexps keyed by pattern and anchoring, since
+// patterns usually repeat for every node a filter visits. Patterns can also be read
+// from the document, so only the most recently used ones are kept.
+var regexpCache = NewRegexpCache(regexpCacheSize)
+
+// regexpCacheSize bounds the number of I-Regexps in regexpCache
+const regexpCacheSize = 256
+
+type regexpKey struct {
+	pattern string
+	full    bool
+}
+
+// CompileIRegexp compiles an I-Regexp (RFC 9485) into a Go regexp. With full set
+// the expression must match the whole input, as the match() function requires.
+func CompileIRegexp(pattern string, full bool) (*regexp.Regexp, error) {
+	return regexpCache.Compile(regexpKey{pattern, full}, func() (*regexp.Regexp, error) {
+		translated, err := translateIRegexp(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if full {
+			translated = `\A(?:` + translated + `)\z`
+		}
+		re, err := regexp.Compile(translated)
+		if err != nil {
+			return nil, fmt.Errorf("invalid I-Regexp %q: %v", pattern, err)
+		}
+		return re, nil
+	})
+}
+
+// translateIRegexp rewrites the parts of an I-Regexp whose meaning differs in Go
+// syntax and rejects constructs I-Regexp does not have. Outside character classes
+// '.' matches any character but CR and LF, and '^' and '$' are ordinary characters.
+func translateIRegexp(pattern string) (string, error) {
+	var sb strings.Builder
+	inClass := false
+
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '\\':
+			if i+1 >= len(pattern) {
+				return "", fmt.Errorf("invalid I-Regexp %q: trailing backslash", pattern)
+			}
+			next := pattern[i+1]
+			switch next {
+			case 'p', 'P':
+				end := strings.IndexByte(pattern[i:], '}')
+				if i+2 >= len(pattern) || pattern[i+2] != '{' || end == -1 {
+					return "", fmt.Errorf("invalid I-Regexp %q: malformed category escape", pattern)
+				}
+				sb.WriteString(pattern[i : i+end+1])
+				i += end
+			case '(', ')', '*', '+', '-', '.', '?', '[', '\\', ']', '^', '{', '|', '}', 'n', 'r', 't':
+				sb.WriteString(pattern[i : i+2])
+				i++
+			default:
+				return "", fmt.Errorf("invalid I-Regexp %q: unsupported escape \\%c", pattern, next)
+			}
+		case inClass:
+			if c == ']' {
+				inClass = false
+			}
+			sb.WriteByte(c)
+		case c == '[':
+			inClass = true
+			sb.WriteByte(c)
+			// A ']' right after '[' or '[^' is a literal rather than the end of the class
+			if strings.HasPrefix(pattern[i+1:], "^") {
+				sb.WriteByte('^')
+				i++
+			}
+			if strings.HasPrefix(pattern[i+1:], "]") {
+				sb.WriteByte(']')
+				i++
+			}
+		case c == '.':
+			sb.WriteString(`[^\n\r]`)
+		case c == '^' || c == '$':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		case c == '(' && strings.HasPrefix(pattern[i+1:], "?"):
+			return "", fmt.Errorf("invalid I-Regexp %q: groups cannot start with '?'", pattern)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	if inClass {
+		return "", fmt.Errorf("invalid I-Regexp %q: unterminated character class", pattern)
+	}
+	return sb.String(), nil
+}
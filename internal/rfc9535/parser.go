@@ -0,0 +1,660 @@
+package rfc9535
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// maxSafeInteger bounds indices, slice parameters and integers in I-JSON (RFC 7493)
+const maxSafeInteger = 1<<53 - 1
+
+// maxNesting bounds how deeply filter expressions and queries may nest, so that a
+// hostile query cannot exhaust the stack of the recursive descent parser
+const maxNesting = 256
+
+// SyntaxError reports a query that is not well-formed or not well-typed
+type SyntaxError struct {
+	Query string
+	Pos   int
+	Msg   string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Msg, e.Pos)
+}
+
+// Parse parses a query following the grammar of RFC 9535, including the
+// well-typedness rules for function extensions
+func Parse(query string) (*Query, error) {
+	return ParseWithFunctions(query, nil)
+}
+
+// ParseWithFunctions is like Parse but resolves function extensions in functions,
+// which may be nil for the Builtins alone
+func ParseWithFunctions(query string, functions *Registry) (*Query, error) {
+	p := &parser{query: query, functions: functions}
+	return p.parse()
+}
+
+type parser struct {
+	query     string
+	pos       int
+	depth     int
+	functions *Registry
+}
+
+// bailout carries a SyntaxError out of the recursive descent
+type bailout struct {
+	err *SyntaxError
+}
+
+func (p *parser) parse() (q *Query, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if b, ok := r.(bailout); ok {
+				q, err = nil, b.err
+				return
+			}
+			panic(r)
+		}
+	}()
+
+	if !p.consume('$') {
+		p.fail("query must start with '$'")
+	}
+	q = &Query{Segments: p.parseSegments()}
+	if p.pos < len(p.query) {
+		p.fail(fmt.Sprintf("unexpected character %s", p.describe()))
+	}
+	return q, nil
+}
+
+func (p *parser) fail(msg string) {
+	panic(bailout{&SyntaxError{Query: p.query, Pos: p.pos, Msg: msg}})
+}
+
+func (p *parser) failAt(pos int, msg string) {
+	p.pos = pos
+	p.fail(msg)
+}
+
+// nest records one more level of nesting and fails once maxNesting is exceeded
+func (p *parser) nest() {
+	p.depth++
+	if p.depth > maxNesting {
+		p.fail(fmt.Sprintf("query nests more than %d levels deep", maxNesting))
+	}
+}
+
+func (p *parser) unnest() {
+	p.depth--
+}
+
+// describe names the character at the current position for error messages
+func (p *parser) describe() string {
+	if p.pos >= len(p.query) {
+		return "end of query"
+	}
+	r, _ := utf8.DecodeRuneInString(p.query[p.pos:])
+	return strconv.QuoteRune(r)
+}
+
+func (p *parser) peek() byte {
+	if p.pos >= len(p.query) {
+		return 0
+	}
+	return p.query[p.pos]
+}
+
+func (p *parser) consume(c byte) bool {
+	if p.pos < len(p.query) && p.query[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) consumeString(s string) bool {
+	if strings.HasPrefix(p.query[p.pos:], s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(c byte) {
+	if !p.consume(c) {
+		p.fail(fmt.Sprintf("expected '%c', found %s", c, p.describe()))
+	}
+}
+
+// skipSpace skips the blank characters the grammar calls S
+func (p *parser) skipSpace() {
+	for p.pos < len(p.query) {
+		switch p.query[p.pos] {
+		case ' ', '\t', '\n', '\r':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+// parseSegments reads segments until the next character cannot start one.
+// Blanks are allowed before each segment but are left in place when no segment follows.
+func (p *parser) parseSegments() []*Segment {
+	var segments []*Segment
+	for {
+		start := p.pos
+		p.skipSpace()
+		switch {
+		case strings.HasPrefix(p.query[p.pos:], ".."):
+			p.pos += 2
+			segments = append(segments, p.parseDescendantSegment())
+		case p.peek() == '.':
+			p.pos++
+			segments = append(segments, &Segment{Selectors: []Selector{p.parseShorthand()}})
+		case p.peek() == '[':
+			segments = append(segments, &Segment{Selectors: p.parseBracketedSelection()})
+		default:
+			p.pos = start
+			return segments
+		}
+	}
+}
+
+func (p *parser) parseDescendantSegment() *Segment {
+	if p.peek() == '[' {
+		return &Segment{Descendant: true, Selectors: p.parseBracketedSelection()}
+	}
+	return &Segment{Descendant: true, Selectors: []Selector{p.parseShorthand()}}
+}
+
+// parseShorthand reads the wildcard or member name that follows '.' or '..'
+func (p *parser) parseShorthand() Selector {
+	if p.consume('*') {
+		return &WildcardSelector{}
+	}
+	start := p.pos
+	for p.pos < len(p.query) {
+		r, size := utf8.DecodeRuneInString(p.query[p.pos:])
+		if !isNameChar(r) || (p.pos == start && isDigit(r)) {
+			break
+		}
+		p.pos += size
+	}
+	if p.pos == start {
+		p.fail(fmt.Sprintf("expected a member name or '*', found %s", p.describe()))
+	}
+	return &NameSelector{Name: p.query[start:p.pos]}
+}
+
+func isNameChar(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '_' || isDigit(r) ||
+		r >= 0x80 && r != utf8.RuneError && !utf16.IsSurrogate(r)
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func (p *parser) parseBracketedSelection() []Selector {
+	p.expect('[')
+	var selectors []Selector
+	for {
+		p.skipSpace()
+		selectors = append(selectors, p.parseSelector())
+		p.skipSpace()
+		if p.consume(']') {
+			return selectors
+		}
+		if !p.consume(',') {
+			p.fail(fmt.Sprintf("expected ',' or ']', found %s", p.describe()))
+		}
+	}
+}
+
+func (p *parser) parseSelector() Selector {
+	switch c := p.peek(); {
+	case c == '\'' || c == '"':
+		return &NameSelector{Name: p.parseStringLiteral()}
+	case c == '*':
+		p.pos++
+		return &WildcardSelector{}
+	case c == '?':
+		p.pos++
+		p.skipSpace()
+		p.nest()
+		defer p.unnest()
+		return &FilterSelector{Expr: p.parseLogicalExpr()}
+	case c == ':' || c == '-' || isDigit(rune(c)):
+		return p.parseIndexOrSlice()
+	default:
+		p.fail(fmt.Sprintf("expected a selector, found %s", p.describe()))
+		return nil
+	}
+}
+
+func (p *parser) parseIndexOrSlice() Selector {
+	var start *int
+	if p.peek() != ':' {
+		index := p.parseInt()
+		start = &index
+		p.skipSpace()
+		if p.peek() != ':' {
+			return &IndexSelector{Index: index}
+		}
+	}
+
+	slice := &SliceSelector{Start: start}
+	p.expect(':')
+	p.skipSpace()
+	if c := p.peek(); c == '-' || isDigit(rune(c)) {
+		end := p.parseInt()
+		slice.End = &end
+		p.skipSpace()
+	}
+	if p.consume(':') {
+		p.skipSpace()
+		if c := p.peek(); c == '-' || isDigit(rune(c)) {
+			step := p.parseInt()
+			slice.Step = &step
+		}
+	}
+	return slice
+}
+
+// parseInt reads an integer without leading zeros or a negative zero, within the
+// range of integers I-JSON can represent exactly
+func (p *parser) parseInt() int {
+	start := p.pos
+	p.consume('-')
+	digits := p.pos
+	for p.pos < len(p.query) && isDigit(rune(p.query[p.pos])) {
+		p.pos++
+	}
+	text := p.query[start:p.pos]
+	switch {
+	case p.pos == digits:
+		p.failAt(start, "expected an integer")
+	case p.query[digits] == '0' && (p.pos > digits+1 || digits > start):
+		p.failAt(start, fmt.Sprintf("invalid integer %q", text))
+	}
+	n, err := strconv.ParseInt(text, 10, 64)
+	if err != nil || n > maxSafeInteger || n < -maxSafeInteger {
+		p.failAt(start, fmt.Sprintf("integer %s is out of range", text))
+	}
+	return int(n)
+}
+
+// parseStringLiteral reads a single- or double-quoted string and unescapes it
+func (p *parser) parseStringLiteral() string {
+	quote := p.query[p.pos]
+	p.pos++
+	var sb strings.Builder
+	for {
+		if p.pos >= len(p.query) {
+			p.fail("unterminated string literal")
+		}
+		c := p.query[p.pos]
+		switch {
+		case c == quote:
+			p.pos++
+			return sb.String()
+		case c < 0x20:
+			p.fail(fmt.Sprintf("control character %s must be escaped", p.describe()))
+		case c == '\\':
+			p.parseEscape(quote, &sb)
+		default:
+			r, size := utf8.DecodeRuneInString(p.query[p.pos:])
+			if r == utf8.RuneError && size == 1 {
+				p.fail("invalid UTF-8 in string literal")
+			}
+			sb.WriteString(p.query[p.pos : p.pos+size])
+			p.pos += size
+		}
+	}
+}
+
+// parseEscape reads one escape sequence; of the two quote characters only the one
+// delimiting the literal may be escaped
+func (p *parser) parseEscape(quote byte, sb *strings.Builder) {
+	start := p.pos
+	p.pos++
+	switch p.peek() {
+	case 'b':
+		sb.WriteByte('\b')
+	case 'f':
+		sb.WriteByte('\f')
+	case 'n':
+		sb.WriteByte('\n')
+	case 'r':
+		sb.WriteByte('\r')
+	case 't':
+		sb.WriteByte('\t')
+	case '/', '\\':
+		sb.WriteByte(p.peek())
+	case quote:
+		sb.WriteByte(quote)
+	case 'u':
+		p.pos++
+		r := p.parseHex4()
+		switch {
+		case utf16.IsSurrogate(r) && r < 0xDC00:
+			if !p.consumeString(`\u`) {
+				p.failAt(start, "high surrogate must be followed by a low surrogate")
+			}
+			low := p.parseHex4()
+			if low < 0xDC00 || low > 0xDFFF {
+				p.failAt(start, "high surrogate must be followed by a low surrogate")
+			}
+			sb.WriteRune(utf16.DecodeRune(r, low))
+		case utf16.IsSurrogate(r):
+			p.failAt(start, "unpaired low surrogate")
+		default:
+			sb.WriteRune(r)
+		}
+		return
+	default:
+		p.failAt(start, fmt.Sprintf("invalid escape sequence \\%s", strings.Trim(p.describe(), "'")))
+	}
+	p.pos++
+}
+
+func (p *parser) parseHex4() rune {
+	if p.pos+4 > len(p.query) {
+		p.fail("expected four hexadecimal digits")
+	}
+	code, err := strconv.ParseUint(p.query[p.pos:p.pos+4], 16, 32)
+	if err != nil || strings.ContainsAny(p.query[p.pos:p.pos+4], "+-") {
+		p.fail("expected four hexadecimal digits")
+	}
+	p.pos += 4
+	return rune(code)
+}
+
+// parseLogicalExpr reads a logical-or-expr
+func (p *parser) parseLogicalExpr() Expr {
+	left := p.parseLogicalAnd()
+	for {
+		start := p.pos
+		p.skipSpace()
+		if !p.consumeString("||") {
+			p.pos = start
+			return left
+		}
+		p.skipSpace()
+		left = &OrExpr{Left: left, Right: p.parseLogicalAnd()}
+	}
+}
+
+func (p *parser) parseLogicalAnd() Expr {
+	left := p.parseBasicExpr()
+	for {
+		start := p.pos
+		p.skipSpace()
+		if !p.consumeString("&&") {
+			p.pos = start
+			return left
+		}
+		p.skipSpace()
+		left = &AndExpr{Left: left, Right: p.parseBasicExpr()}
+	}
+}
+
+// parseBasicExpr reads a parenthesized expression, a comparison or a test expression
+func (p *parser) parseBasicExpr() Expr {
+	p.nest()
+	defer p.unnest()
+
+	if p.consume('!') {
+		p.skipSpace()
+		if p.peek() == '(' {
+			return &NotExpr{Operand: p.parseParenExpr()}
+		}
+		start := p.pos
+		operand := p.parseOperand()
+		p.checkTest(operand, start)
+		return &NotExpr{Operand: operand}
+	}
+	if p.peek() == '(' {
+		return p.parseParenExpr()
+	}
+
+	start := p.pos
+	left := p.parseOperand()
+	end := p.pos
+	p.skipSpace()
+	if op := p.parseComparisonOp(); op != "" {
+		p.checkComparable(left, start)
+		p.skipSpace()
+		rightStart := p.pos
+		right := p.parseOperand()
+		p.checkComparable(right, rightStart)
+		return &ComparisonExpr{Op: op, Left: left, Right: right}
+	}
+	p.pos = end
+	p.checkTest(left, start)
+	return left
+}
+
+func (p *parser) parseParenExpr() Expr {
+	p.expect('(')
+	p.skipSpace()
+	expr := p.parseLogicalExpr()
+	p.skipSpace()
+	p.expect(')')
+	return expr
+}
+
+func (p *parser) parseComparisonOp() string {
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.consumeString(op) {
+			return op
+		}
+	}
+	return ""
+}
+
+// parseOperand reads a literal, an embedded query or a function call
+func (p *parser) parseOperand() Expr {
+	switch c := p.peek(); {
+	case c == '$' || c == '@':
+		p.pos++
+		return &QueryExpr{Query: &Query{Relative: c == '@', Segments: p.parseSegments()}}
+	case c == '\'' || c == '"':
+		return &LiteralExpr{Value: p.parseStringLiteral()}
+	case c == '-' || isDigit(rune(c)):
+		return &LiteralExpr{Value: p.parseNumber()}
+	case c >= 'a' && c <= 'z':
+		for _, keyword := range []struct {
+			text  string
+			value interface{}
+		}{{"true", true}, {"false", false}, {"null", nil}} {
+			if p.consumeString(keyword.text) {
+				if c := p.peek(); c != '(' && !isNameChar(rune(c)) {
+					return &LiteralExpr{Value: keyword.value}
+				}
+				p.pos -= len(keyword.text)
+			}
+		}
+		return p.parseFunctionExpr()
+	default:
+		p.fail(fmt.Sprintf("expected a query, literal or function call, found %s", p.describe()))
+		return nil
+	}
+}
+
+// parseNumber reads a number literal, which unlike an integer may be -0
+// and may have a fraction and an exponent
+func (p *parser) parseNumber() float64 {
+	start := p.pos
+	p.consume('-')
+	digits := p.pos
+	for p.pos < len(p.query) && isDigit(rune(p.query[p.pos])) {
+		p.pos++
+	}
+	if p.pos == digits || (p.query[digits] == '0' && p.pos > digits+1) {
+		p.failAt(start, "invalid number")
+	}
+	if p.consume('.') {
+		fraction := p.pos
+		for p.pos < len(p.query) && isDigit(rune(p.query[p.pos])) {
+			p.pos++
+		}
+		if p.pos == fraction {
+			p.failAt(start, "invalid number")
+		}
+	}
+	if p.consume('e') || p.consume('E') {
+		if !p.consume('+') {
+			p.consume('-')
+		}
+		exponent := p.pos
+		for p.pos < len(p.query) && isDigit(rune(p.query[p.pos])) {
+			p.pos++
+		}
+		if p.pos == exponent {
+			p.failAt(start, "invalid number")
+		}
+	}
+	f, err := strconv.ParseFloat(p.query[start:p.pos], 64)
+	if err != nil {
+		p.failAt(start, "number is out of range")
+	}
+	return f
+}
+
+// parseFunctionExpr reads a function call and checks its arguments against the
+// declared parameter types
+func (p *parser) parseFunctionExpr() Expr {
+	start := p.pos
+	for p.pos < len(p.query) {
+		c := p.query[p.pos]
+		if !(c >= 'a' && c <= 'z' || c == '_' || isDigit(rune(c))) || (p.pos == start && c == '_') {
+			break
+		}
+		p.pos++
+	}
+	name := p.query[start:p.pos]
+	if name == "" || p.peek() != '(' {
+		p.failAt(start, fmt.Sprintf("expected a query, literal or function call, found %s", p.describe()))
+	}
+	fn, ok := p.functions.Lookup(name)
+	if !ok {
+		p.failAt(start, fmt.Sprintf("unknown function %s()", name))
+	}
+
+	p.expect('(')
+	p.skipSpace()
+	var args []Expr
+	var positions []int
+	if !p.consume(')') {
+		for {
+			positions = append(positions, p.pos)
+			args = append(args, p.parseArgument())
+			p.skipSpace()
+			if p.consume(')') {
+				break
+			}
+			if !p.consume(',') {
+				p.fail(fmt.Sprintf("expected ',' or ')', found %s", p.describe()))
+			}
+			p.skipSpace()
+		}
+	}
+
+	if len(args) != len(fn.Params) {
+		p.failAt(start, fmt.Sprintf("%s() takes %d argument(s), got %d", name, len(fn.Params), len(args)))
+	}
+	for i, arg := range args {
+		if !argumentFits(arg, fn.Params[i]) {
+			p.failAt(positions[i], fmt.Sprintf("argument %d of %s() must be of %s", i+1, name, fn.Params[i]))
+		}
+	}
+	return &FunctionExpr{Func: fn, Args: args}
+}
+
+// parseArgument reads a function argument: a literal, query or function call on its
+// own, or else a logical expression
+func (p *parser) parseArgument() Expr {
+	p.nest()
+	defer p.unnest()
+
+	start := p.pos
+	if c := p.peek(); c != '!' && c != '(' {
+		operand := p.parseOperand()
+		end := p.pos
+		p.skipSpace()
+		if c := p.peek(); c == ',' || c == ')' {
+			p.pos = end
+			return operand
+		}
+		p.pos = start
+	}
+	return p.parseLogicalExpr()
+}
+
+// argumentFits applies the well-typedness rules of RFC 9535 section 2.4.3
+func argumentFits(arg Expr, param Type) bool {
+	switch param {
+	case ValueType:
+		switch a := arg.(type) {
+		case *LiteralExpr:
+			return true
+		case *QueryExpr:
+			return a.Query.Singular()
+		case *FunctionExpr:
+			return a.Func.Result == ValueType
+		}
+		return false
+	case LogicalType:
+		switch a := arg.(type) {
+		case *LiteralExpr:
+			return false
+		case *FunctionExpr:
+			return a.Func.Result != ValueType
+		}
+		return true
+	case NodesType:
+		switch a := arg.(type) {
+		case *QueryExpr:
+			return true
+		case *FunctionExpr:
+			return a.Func.Result == NodesType
+		}
+		return false
+	}
+	return false
+}
+
+// checkComparable rejects operands that cannot be compared: queries that may select
+// more than one node and functions that do not return a value
+func (p *parser) checkComparable(expr Expr, pos int) {
+	switch e := expr.(type) {
+	case *QueryExpr:
+		if !e.Query.Singular() {
+			p.failAt(pos, "only singular queries can be compared")
+		}
+	case *FunctionExpr:
+		if e.Func.Result != ValueType {
+			p.failAt(pos, fmt.Sprintf("%s() does not return a value and cannot be compared", e.Func.Name))
+		}
+	}
+}
+
+// checkTest rejects operands that cannot stand alone as a test: literals and
+// functions that return a value
+func (p *parser) checkTest(expr Expr, pos int) {
+	switch e := expr.(type) {
+	case *LiteralExpr:
+		p.failAt(pos, "a literal must be compared")
+	case *FunctionExpr:
+		if e.Func.Result == ValueType {
+			p.failAt(pos, fmt.Sprintf("the result of %s() must be compared", e.Func.Name))
+		}
+	}
+}
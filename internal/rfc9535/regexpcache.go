@@ -0,0 +1,37 @@
+package rfc9535
+
+import (
+	"regexp"
+
+	"github.com/reclaimprotocol/jsonpathplus-go/internal/lru"
+)
+
+// RegexpCache is a concurrency-safe LRU cache of compiled regular expressions. Patterns
+// can come from the documents a filter reads, so it holds a bounded number of them.
+type RegexpCache struct {
+	cache *lru.Cache
+}
+
+// NewRegexpCache creates a cache holding up to capacity compiled regexps
+func NewRegexpCache(capacity int) *RegexpCache {
+	return &RegexpCache{cache: lru.New(capacity)}
+}
+
+// Compile returns the regexp cached under key or, on a miss, the one compile returns,
+// which is cached unless compile fails
+func (c *RegexpCache) Compile(key interface{}, compile func() (*regexp.Regexp, error)) (*regexp.Regexp, error) {
+	if cached, ok := c.cache.Get(key); ok {
+		return cached.(*regexp.Regexp), nil
+	}
+	re, err := compile()
+	if err != nil {
+		return nil, err
+	}
+	c.cache.Add(key, re)
+	return re, nil
+}
+
+// Len returns the number of cached regexps
+func (c *RegexpCache) Len() int {
+	return c.cache.Len()
+}
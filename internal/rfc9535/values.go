@@ -0,0 +1,225 @@
+package rfc9535
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/reclaimprotocol/jsonpathplus-go/pkg/utils"
+)
+
+// nothing is the type of Nothing
+type nothing struct{}
+
+// Nothing is the special result of a singular query that selects no node, or of a
+// function whose arguments do not make sense; it is distinct from JSON null
+var Nothing interface{} = nothing{}
+
+// Nodes is a list of node values, the NodesType argument of a function extension
+type Nodes []interface{}
+
+// Member is one member of an object
+type Member struct {
+	Key   string
+	Value interface{}
+}
+
+// ObjectMembers returns the members of an object in document order, or in key order
+// for a Go map, and whether v is an object at all
+func ObjectMembers(v interface{}) ([]Member, bool) {
+	switch obj := v.(type) {
+	case *utils.OrderedMap:
+		members := make([]Member, 0, obj.Len())
+		obj.Range(func(key string, value interface{}) bool {
+			members = append(members, Member{Key: key, Value: value})
+			return true
+		})
+		return members, true
+	case map[string]interface{}:
+		keys := make([]string, 0, len(obj))
+		for key := range obj {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		members := make([]Member, len(keys))
+		for i, key := range keys {
+			members[i] = Member{Key: key, Value: obj[key]}
+		}
+		return members, true
+	}
+	return nil, false
+}
+
+// ObjectMember returns the value of the named member of an object
+func ObjectMember(v interface{}, name string) (interface{}, bool) {
+	switch obj := v.(type) {
+	case *utils.OrderedMap:
+		return obj.Get(name)
+	case map[string]interface{}:
+		value, ok := obj[name]
+		return value, ok
+	}
+	return nil, false
+}
+
+// ToNumber converts any Go numeric type to float64
+func ToNumber(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// Compare applies a comparison operator with the semantics of RFC 9535 section 2.3.5.2.2
+func Compare(op string, left, right interface{}) bool {
+	switch op {
+	case "==":
+		return equal(left, right)
+	case "!=":
+		return !equal(left, right)
+	case "<":
+		return less(left, right)
+	case "<=":
+		return less(left, right) || equal(left, right)
+	case ">":
+		return less(right, left)
+	case ">=":
+		return less(right, left) || equal(left, right)
+	default:
+		return false
+	}
+}
+
+// equal implements == : Nothing equals only Nothing, numbers compare numerically and
+// arrays and objects compare deeply
+func equal(left, right interface{}) bool {
+	if left == Nothing || right == Nothing {
+		return left == Nothing && right == Nothing
+	}
+	if l, ok := ToNumber(left); ok {
+		r, ok := ToNumber(right)
+		return ok && l == r
+	}
+
+	switch l := left.(type) {
+	case nil:
+		return right == nil
+	case string:
+		r, ok := right.(string)
+		return ok && l == r
+	case bool:
+		r, ok := right.(bool)
+		return ok && l == r
+	case []interface{}:
+		r, ok := right.([]interface{})
+		if !ok || len(l) != len(r) {
+			return false
+		}
+		for i := range l {
+			if !equal(l[i], r[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	lm, ok := ObjectMembers(left)
+	if !ok {
+		return false
+	}
+	rm, ok := ObjectMembers(right)
+	if !ok || len(lm) != len(rm) {
+		return false
+	}
+	for _, member := range lm {
+		value, ok := ObjectMember(right, member.Key)
+		if !ok || !equal(member.Value, value) {
+			return false
+		}
+	}
+	return true
+}
+
+// less implements < , which only holds between two numbers or two strings
+func less(left, right interface{}) bool {
+	if l, ok := ToNumber(left); ok {
+		r, ok := ToNumber(right)
+		return ok && l < r
+	}
+	if l, ok := left.(string); ok {
+		// Byte order of UTF-8 is the order of Unicode scalar values
+		r, ok := right.(string)
+		return ok && l < r
+	}
+	return false
+}
+
+// NormalizedName appends a member name to a normalized path
+func NormalizedName(path, name string) string {
+	return path + "[" + quoteName(name) + "]"
+}
+
+// NormalizedIndex appends a non-negative array index to a normalized path
+func NormalizedIndex(path string, index int) string {
+	return path + "[" + strconv.Itoa(index) + "]"
+}
+
+// quoteName renders a name in single quotes with the escapes of RFC 9535 section 2.7
+func quoteName(name string) string {
+	var sb strings.Builder
+	sb.WriteByte('\'')
+	for _, r := range name {
+		switch r {
+		case '\b':
+			sb.WriteString(`\b`)
+		case '\f':
+			sb.WriteString(`\f`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\'':
+			sb.WriteString(`\'`)
+		case '\\':
+			sb.WriteString(`\\`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(&sb, `\u%04x`, r)
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	sb.WriteByte('\'')
+	return sb.String()
+}
@@ -1,12 +1,15 @@
 package jsonpathplus
 
 import (
-	"fmt"
-	"strconv"
+	"context"
+	"errors"
 	"strings"
+	"time"
 
 	"github.com/reclaimprotocol/jsonpathplus-go/internal/evaluator"
+	"github.com/reclaimprotocol/jsonpathplus-go/internal/filters"
 	"github.com/reclaimprotocol/jsonpathplus-go/internal/parser"
+	"github.com/reclaimprotocol/jsonpathplus-go/internal/rfc9535"
 	"github.com/reclaimprotocol/jsonpathplus-go/pkg/types"
 	"github.com/reclaimprotocol/jsonpathplus-go/pkg/utils"
 )
@@ -14,6 +17,29 @@ import (
 // Result represents a JSONPath query result (alias for types.Result for backward compatibility)
 type Result = types.Result
 
+// Span is a range in the original JSON string (alias for types.Span)
+type Span = types.Span
+
+// PositionUnit selects how offsets into a JSON string are counted (alias for types.PositionUnit)
+type PositionUnit = types.PositionUnit
+
+// Position units for Options.PositionUnit
+const (
+	PositionUnitBytes = types.PositionUnitBytes
+	PositionUnitRunes = types.PositionUnitRunes
+	PositionUnitUTF16 = types.PositionUnitUTF16
+)
+
+// EvalMode selects whether and how filter and script expressions are evaluated (alias for types.EvalMode)
+type EvalMode = types.EvalMode
+
+// Evaluation modes for Options.EvalMode
+const (
+	EvalModeSafe     = types.EvalModeSafe
+	EvalModeDisabled = types.EvalModeDisabled
+	EvalModeSandbox  = types.EvalModeSandbox
+)
+
 // Options represents JSONPath options (alias for types.Options for backward compatibility)
 type Options = types.Options
 
@@ -21,68 +47,432 @@ type Options = types.Options
 type JSONPathEngine struct {
 	parser    *parser.Parser
 	evaluator *evaluator.Evaluator
+	functions *rfc9535.Registry  // Function extensions callable from filters
+	config    *Config            // nil means no limits are enforced
+	security  *SecurityValidator // Validates compiled paths; nil when config has no Security
+	metrics   *MetricsCollector  // Records every query; nil unless config enables metrics
+	logger    Logger             // Receives structured events; nil disables logging
+	cache     *PathCache         // Compiled paths; nil when the cache is disabled
 }
 
-// NewJSONPathEngine creates a new JSONPath engine
+// NewJSONPathEngine creates a new JSONPath engine that caches up to DefaultCacheSize
+// compiled paths
 func NewJSONPathEngine() *JSONPathEngine {
+	functions := rfc9535.NewRegistry()
+	return &JSONPathEngine{
+		parser:    parser.NewParserWithFunctions(functions),
+		evaluator: evaluator.NewEvaluatorWithFunctions(evaluator.Limits{}, functions),
+		functions: functions,
+		cache:     newPathCache(DefaultCacheSize),
+	}
+}
+
+// NewJSONPathEngineWithConfig creates a JSONPath engine that enforces the limits in config
+// on every query it compiles and executes. A nil config uses DefaultConfig().
+func NewJSONPathEngineWithConfig(config *Config) (*JSONPathEngine, error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	config = config.Clone()
+
+	limits := evaluator.Limits{
+		MaxRecursionDepth: config.MaxRecursionDepth,
+		MaxResultCount:    config.MaxResultCount,
+		Timeout:           config.Timeout,
+		MaxMemoryUsage:    config.MaxMemoryUsage,
+	}
+	functions := rfc9535.NewRegistry()
+	var security *SecurityValidator
+	if config.Security != nil {
+		limits.MaxExecutionTime = config.Security.MaxExecutionTime
+		security = newSecurityValidator(config.Security, functions)
+	}
+	var metrics *MetricsCollector
+	if config.EnableMetrics {
+		metrics = NewMetricsCollector(true)
+	}
+	var logger Logger
+	if config.EnableLogging {
+		logger = NewDefaultLogger(LogLevelDebug)
+	}
+	var cache *PathCache
+	if config.CacheSize > 0 {
+		cache = newPathCache(config.CacheSize)
+	}
+
 	return &JSONPathEngine{
-		parser:    parser.NewParser(),
-		evaluator: evaluator.NewEvaluator(),
+		parser:    parser.NewParserWithFunctions(functions),
+		evaluator: evaluator.NewEvaluatorWithFunctions(limits, functions),
+		functions: functions,
+		config:    config,
+		security:  security,
+		metrics:   metrics,
+		logger:    logger,
+		cache:     cache,
+	}, nil
+}
+
+// Metrics returns the collector recording the engine's queries, or nil unless the
+// engine was configured with EnableMetrics. The collector is an http.Handler serving
+// the metrics in the Prometheus text format.
+func (engine *JSONPathEngine) Metrics() *MetricsCollector {
+	return engine.metrics
+}
+
+// GetMetrics returns a snapshot of the engine metrics; it is empty unless the engine
+// was configured with EnableMetrics
+func (engine *JSONPathEngine) GetMetrics() Metrics {
+	if engine.metrics == nil {
+		return NewMetricsCollector(false).GetMetrics()
 	}
+	return engine.metrics.GetMetrics()
+}
+
+// observe runs query and, when metrics or logging are enabled, records its latency,
+// result count, node evaluations and error
+func (engine *JSONPathEngine) observe(ctx context.Context, path string, query func(context.Context) ([]Result, error)) ([]Result, error) {
+	if engine.metrics == nil && engine.logger == nil {
+		return query(ctx)
+	}
+
+	if engine.logger != nil {
+		engine.logger.Debug("evaluation started", String("path", path))
+	}
+	stats := &evaluator.Stats{}
+	start := time.Now()
+	results, err := query(evaluator.WithStats(ctx, stats))
+	duration := time.Since(start)
+
+	if engine.metrics != nil {
+		engine.metrics.RecordQuery(duration, err)
+		if err == nil {
+			engine.metrics.RecordResultCount(len(results))
+		}
+		engine.metrics.RecordNodeEvaluations(stats.NodeEvaluations)
+	}
+	if engine.logger != nil {
+		engine.logEvaluation(path, duration, results, stats, err)
+	}
+	return results, err
+}
+
+// logEvaluation logs the end of an evaluation: a recovered panic as an error, a
+// breached limit as a warning and any other failure or success at lower levels
+func (engine *JSONPathEngine) logEvaluation(path string, duration time.Duration, results []Result, stats *evaluator.Stats, err error) {
+	fields := []Field{String("path", path), Duration("duration", duration)}
+
+	var panicErr *evaluator.PanicError
+	switch {
+	case err == nil:
+		fields = append(fields, Int("result_count", len(results)), Any("node_evaluations", stats.NodeEvaluations))
+		engine.logger.Debug("evaluation finished", fields...)
+	case errors.As(err, &panicErr):
+		fields = append(fields, Any("panic", panicErr.Value), String("stack", string(panicErr.Stack)))
+		engine.logger.Error("recovered panic", fields...)
+	case isLimitError(err):
+		fields = append(fields, String("limit", classifyError(err).String()), Error("error", err))
+		engine.logger.Warn("limit exceeded", fields...)
+	default:
+		fields = append(fields, Error("error", err))
+		engine.logger.Info("query failed", fields...)
+	}
+}
+
+// isLimitError reports whether err reports a breach of one of the engine's limits
+func isLimitError(err error) bool {
+	var lengthErr *PathLengthError
+	if errors.As(err, &lengthErr) {
+		return true
+	}
+	switch classifyError(err) {
+	case ErrRecursionLimit, ErrResultLimit, ErrTimeout, ErrMemoryLimit, ErrExecutionBudget:
+		return true
+	}
+	return false
+}
+
+// Cache returns the engine's cache of compiled paths, or nil when it is disabled
+func (engine *JSONPathEngine) Cache() *PathCache {
+	return engine.cache
+}
+
+// cached returns the compiled path cached under key, logging the hit or miss
+func (engine *JSONPathEngine) cached(key cacheKey) (*JSONPath, bool) {
+	if engine.cache == nil {
+		return nil, false
+	}
+	jp, ok := engine.cache.get(key)
+	if engine.logger != nil {
+		fields := []Field{String("path", key.path), Bool("standard", key.standard)}
+		if ok {
+			engine.logger.Debug("cache hit", fields...)
+		} else {
+			engine.logger.Debug("cache miss", fields...)
+		}
+	}
+	return jp, ok
+}
+
+// SetLogger sets the logger that receives the engine's structured events: compiled
+// paths, cache hits and misses, evaluation start and end, limit breaches and
+// recovered panics. A nil logger turns logging off. It must not be called concurrently with queries.
+func (engine *JSONPathEngine) SetLogger(logger Logger) {
+	engine.logger = logger
+}
+
+// Config returns a copy of the engine configuration, or nil for an unlimited engine
+func (engine *JSONPathEngine) Config() *Config {
+	if engine.config == nil {
+		return nil
+	}
+	return engine.config.Clone()
 }
 
 // JSONPath represents a compiled JSONPath expression using the new architecture
 type JSONPath struct {
-	path   string
-	ast    *types.AstNode
-	engine *JSONPathEngine
+	path     string
+	ast      *types.AstNode
+	standard *rfc9535.Query           // Set instead of ast when compiled by CompileStandard
+	nodes    *evaluator.StandardNodes // Nodes the spans of standard are reported for
+	engine   *JSONPathEngine
 }
 
 // New creates a new JSONPath instance
 func New(path string) (*JSONPath, error) {
-	engine := NewJSONPathEngine()
+	return defaultEngine.Compile(path)
+}
+
+// Compile parses a JSONPath expression into a JSONPath bound to this engine,
+// so that executing it enforces the engine's limits. Compiled paths are cached.
+func (engine *JSONPathEngine) Compile(path string) (jp *JSONPath, err error) {
+	key := cacheKey{path: path}
+	if jp, ok := engine.cached(key); ok {
+		return jp, nil
+	}
+	if engine.logger != nil {
+		defer engine.logCompile(path, false, time.Now(), &err)
+	}
+	if err := engine.checkPath(path); err != nil {
+		return nil, err
+	}
 
 	ast, err := engine.parser.Parse(path)
 	if err != nil {
-		return nil, err
+		return nil, convertParseError(err, path)
+	}
+	if engine.security != nil {
+		if err := engine.security.validate(ast, path); err != nil {
+			return nil, err
+		}
 	}
 
-	return &JSONPath{
+	jp = &JSONPath{
 		path:   path,
 		ast:    ast,
 		engine: engine,
-	}, nil
+	}
+	if engine.cache != nil {
+		engine.cache.add(key, jp)
+	}
+	return jp, nil
 }
 
-// Execute executes the JSONPath against the given data
-func (jp *JSONPath) Execute(data interface{}) (results []Result, err error) {
-	options := &types.Options{}
-
-	// Catch panics from JavaScript compatibility errors (like null.length)
-	defer func() {
-		if r := recover(); r != nil {
-			if str, ok := r.(string); ok && strings.Contains(str, "Cannot read properties of null") {
-				// JavaScript compatibility: propagate the error instead of returning empty results
-				results = []Result{}
-				err = fmt.Errorf("jsonPath: %s", str)
-				return
-			}
-			// Re-panic for other errors
-			panic(r)
+// CompileStandard parses a query that must follow the RFC 9535 grammar. Executing it
+// follows RFC 9535 semantics and reports results with normalized paths such as
+// $['store']['book'][0].
+func (engine *JSONPathEngine) CompileStandard(path string) (jp *JSONPath, err error) {
+	key := cacheKey{path: path, standard: true}
+	if jp, ok := engine.cached(key); ok {
+		return jp, nil
+	}
+	if engine.logger != nil {
+		defer engine.logCompile(path, true, time.Now(), &err)
+	}
+	if err := engine.checkPath(path); err != nil {
+		return nil, err
+	}
+	query, err := parseStandard(path, engine.functions)
+	if err != nil {
+		return nil, err
+	}
+	if engine.security != nil {
+		if err := engine.security.validateStandard(query, path); err != nil {
+			return nil, err
+		}
+	}
+
+	jp = &JSONPath{
+		path:     path,
+		standard: query,
+		nodes:    evaluator.NewStandardNodes(query),
+		engine:   engine,
+	}
+	if engine.cache != nil {
+		engine.cache.add(key, jp)
+	}
+	return jp, nil
+}
+
+// logCompile logs the outcome of a compilation that started at start; it is deferred
+// with a pointer to the error the compilation returns
+func (engine *JSONPathEngine) logCompile(path string, standard bool, start time.Time, err *error) {
+	fields := []Field{String("path", path), Bool("standard", standard), Duration("duration", time.Since(start))}
+	if *err != nil {
+		engine.logger.Debug("path rejected", append(fields, Error("error", *err))...)
+		return
+	}
+	engine.logger.Debug("path compiled", fields...)
+}
+
+// checkPath enforces the path length limit of the engine
+func (engine *JSONPathEngine) checkPath(path string) error {
+	if engine.config != nil && len(path) > engine.config.MaxPathLength {
+		return &PathLengthError{Length: len(path), Limit: engine.config.MaxPathLength}
+	}
+	return nil
+}
+
+// compileFor compiles path with the grammar options selects
+func (engine *JSONPathEngine) compileFor(path string, options *Options) (*JSONPath, error) {
+	if options != nil && options.Standard {
+		return engine.CompileStandard(path)
+	}
+	return engine.Compile(path)
+}
+
+// parseStandard parses an RFC 9535 query, reporting syntax errors as ErrInvalidPath
+func parseStandard(path string, functions *rfc9535.Registry) (*rfc9535.Query, error) {
+	query, err := rfc9535.ParseWithFunctions(path, functions)
+	if err != nil {
+		var syntaxErr *rfc9535.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return nil, WrapError(ErrInvalidPath, err, path, syntaxErr.Pos)
 		}
-	}()
+		return nil, WrapError(ErrInvalidPath, err, path, -1)
+	}
+	return query, nil
+}
+
+// Execute executes the JSONPath against the given data
+func (jp *JSONPath) Execute(data interface{}) ([]Result, error) {
+	return jp.ExecuteContext(context.Background(), data)
+}
 
-	results = jp.engine.evaluator.Evaluate(jp.ast, data, options)
-	err = nil
-	return
+// ExecuteContext executes the JSONPath against the given data, stopping early when ctx is done
+func (jp *JSONPath) ExecuteContext(ctx context.Context, data interface{}) ([]Result, error) {
+	return jp.execute(ctx, data, &types.Options{})
 }
 
-// ExecuteWithOptions executes the JSONPath with custom options
+// ExecuteWithOptions executes the JSONPath with custom options. Each Result carries
+// every shape of its match; Evaluate returns the shape options.ResultType selects.
 func (jp *JSONPath) ExecuteWithOptions(data interface{}, options *Options) ([]Result, error) {
 	if options == nil {
 		options = &Options{}
 	}
-	return jp.engine.evaluator.Evaluate(jp.ast, data, options), nil
+	if err := validateResultType(options.ResultType, jp.path); err != nil {
+		return nil, err
+	}
+	return jp.execute(context.Background(), data, options)
+}
+
+// execute runs the compiled query, recording it in the engine metrics
+func (jp *JSONPath) execute(ctx context.Context, data interface{}, options *types.Options) ([]Result, error) {
+	return jp.engine.observe(ctx, jp.path, func(ctx context.Context) ([]Result, error) {
+		return jp.run(ctx, data, options)
+	})
+}
+
+// run evaluates the compiled query under the engine's limits. A path compiled by
+// Compile is parsed again with the RFC 9535 grammar when options.Standard is set.
+func (jp *JSONPath) run(ctx context.Context, data interface{}, options *types.Options) ([]Result, error) {
+	if err := validateEvalMode(options.EvalMode, jp.engine.security, jp.path); err != nil {
+		return nil, err
+	}
+
+	var results []Result
+	var err error
+	if query := jp.standard; query != nil || options.Standard {
+		if query == nil {
+			if query, err = parseStandard(jp.path, jp.engine.functions); err != nil {
+				return nil, err
+			}
+		}
+		if options.EvalMode == EvalModeDisabled && query.HasFilters() {
+			return nil, expressionsDisabled(jp.path)
+		}
+		nodes := jp.nodes
+		if query != jp.standard {
+			nodes = nil
+		}
+		results, err = jp.engine.evaluator.RunStandard(ctx, query, nodes, data, options)
+	} else {
+		if options.EvalMode == EvalModeDisabled && hasExpressions(jp.ast) {
+			return nil, expressionsDisabled(jp.path)
+		}
+		results, err = jp.engine.evaluator.RunContext(ctx, jp.ast, data, options)
+	}
+	if err != nil {
+		return nil, convertEvaluationError(err, jp.path)
+	}
+	return results, nil
+}
+
+// convertEvaluationError maps internal evaluator errors onto the public error types
+func convertEvaluationError(err error, path string) error {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return WrapError(ErrCanceled, err, path, -1)
+	case errors.Is(err, context.DeadlineExceeded):
+		return WrapError(ErrTimeout, err, path, -1)
+	}
+
+	// Filters that fail at runtime (such as reading a property of null) are evaluation
+	// errors, as in JavaScript; filters that do not parse are invalid expressions
+	var syntaxErr *filters.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return convertParseError(err, path)
+	}
+
+	var limitErr *evaluator.LimitError
+	if !errors.As(err, &limitErr) {
+		return WrapError(ErrEvaluationError, err, path, -1)
+	}
+
+	switch limitErr.Kind {
+	case evaluator.LimitRecursionDepth:
+		return WrapError(ErrRecursionLimit, &RecursionLimitError{
+			Depth: int(limitErr.Actual),
+			Limit: int(limitErr.Limit),
+		}, path, -1)
+	case evaluator.LimitResultCount:
+		return WrapError(ErrResultLimit, limitErr, path, -1)
+	case evaluator.LimitTimeout:
+		return WrapError(ErrTimeout, limitErr, path, -1)
+	case evaluator.LimitMemoryUsage:
+		return WrapError(ErrMemoryLimit, limitErr, path, -1)
+	case evaluator.LimitExecutionTime:
+		return WrapError(ErrExecutionBudget, limitErr, path, -1)
+	default:
+		return WrapError(ErrEvaluationError, limitErr, path, -1)
+	}
+}
+
+// convertParseError maps filter syntax errors onto ErrInvalidExpression with their
+// position in the path; other parse errors are returned unchanged
+func convertParseError(err error, path string) error {
+	var syntaxErr *filters.SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		return err
+	}
+
+	position := -1
+	if idx := strings.Index(path, syntaxErr.Expr); idx != -1 {
+		position = idx + syntaxErr.Pos
+	}
+	return WrapError(ErrInvalidExpression, err, path, position)
 }
 
 // Path returns the JSONPath expression
@@ -90,9 +480,26 @@ func (jp *JSONPath) Path() string {
 	return jp.path
 }
 
-// AST returns the parsed AST
+// AST returns a copy of the parsed AST, or nil for a path compiled by CompileStandard.
+// Compiled paths are cached and shared, so changing the copy never affects them.
 func (jp *JSONPath) AST() *types.AstNode {
-	return jp.ast
+	return cloneAST(jp.ast)
+}
+
+// cloneAST copies the nodes of an AST. Compiled filter expressions are never modified
+// after parsing and are shared with the copy.
+func cloneAST(node *types.AstNode) *types.AstNode {
+	if node == nil {
+		return nil
+	}
+	clone := *node
+	if node.Children != nil {
+		clone.Children = make([]*types.AstNode, len(node.Children))
+		for i, child := range node.Children {
+			clone.Children[i] = cloneAST(child)
+		}
+	}
+	return &clone
 }
 
 // Convenience functions for the refactored API
@@ -102,301 +509,263 @@ func JSONParse(jsonStr string) (interface{}, error) {
 	return utils.ParseOrderedJSON([]byte(jsonStr))
 }
 
+// defaultEngine backs the package-level functions, so that the paths they compile are
+// cached across calls. It is never configured, so sharing it shares nothing but the cache.
+var defaultEngine = NewJSONPathEngine()
+
 // Query executes a JSONPath query against JSON string or data
 func Query(path string, input interface{}) ([]Result, error) {
-	var data interface{}
-	var jsonStr string
-	var isStringInput bool
-
-	// Check if input is a string (JSON) or already parsed data
-	if str, ok := input.(string); ok {
-		jsonStr = str
-		isStringInput = true
-		var err error
-		data, err = utils.ParseOrderedJSON([]byte(jsonStr))
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		data = input
-		isStringInput = false
-	}
-
-	jp, err := New(path)
-	if err != nil {
-		return nil, err
-	}
-
-	results, err := jp.Execute(data)
-	if err != nil {
-		return nil, err
-	}
+	return defaultEngine.Query(path, input)
+}
 
-	// If input was a JSON string, calculate string indices for each result
-	if isStringInput {
-		for i := range results {
-			stringPos := findStringPositionForResult(results[i], jsonStr)
-			results[i].Start = stringPos.Start
-			results[i].End = stringPos.End
-			results[i].Length = stringPos.Length
-			// For backward compatibility, also set OriginalIndex to the start position
-			results[i].OriginalIndex = stringPos.Start
-		}
-	}
+// QueryContext executes a JSONPath query against JSON string or data, stopping early when ctx is done
+func QueryContext(ctx context.Context, path string, input interface{}) ([]Result, error) {
+	return defaultEngine.QueryContext(ctx, path, input)
+}
 
-	return results, nil
+// QueryWithOptions executes a JSONPath query against JSON string or data with custom options
+func QueryWithOptions(path string, input interface{}, options *Options) ([]Result, error) {
+	return defaultEngine.QueryWithOptions(path, input, options)
 }
 
 // Parse parses a JSONPath expression and returns the AST
 func Parse(path string) (*types.AstNode, error) {
 	p := parser.NewParser()
-	return p.Parse(path)
+	ast, err := p.Parse(path)
+	if err != nil {
+		return nil, convertParseError(err, path)
+	}
+	return ast, nil
 }
 
-// Validate validates a JSONPath expression
+// Validate validates a JSONPath expression, including the syntax of its filters
 func Validate(path string) error {
 	p := parser.NewParser()
-	return p.ValidatePath(path)
+	if err := p.ValidatePath(path); err != nil {
+		return convertParseError(err, path)
+	}
+	return nil
 }
 
 // Additional JSONPathEngine methods for backward compatibility
 
-// Query executes a JSONPath query using the engine
+// Query executes a JSONPath query against JSON string or data using the engine
 func (engine *JSONPathEngine) Query(path string, input interface{}) ([]Result, error) {
-	return Query(path, input)
+	return engine.QueryContext(context.Background(), path, input)
 }
 
-// Close closes the engine (no-op for compatibility)
-func (engine *JSONPathEngine) Close() error {
-	return nil
+// QueryContext executes a JSONPath query using the engine, stopping early when ctx is done
+func (engine *JSONPathEngine) QueryContext(ctx context.Context, path string, input interface{}) ([]Result, error) {
+	return engine.QueryContextWithOptions(ctx, path, input, nil)
 }
 
-// NewEngine creates a new JSONPath engine (backward compatibility)
-func NewEngine() *JSONPathEngine {
-	return NewJSONPathEngine()
+// QueryWithOptions executes a JSONPath query using the engine with custom options
+func (engine *JSONPathEngine) QueryWithOptions(path string, input interface{}, options *Options) ([]Result, error) {
+	return engine.QueryContextWithOptions(context.Background(), path, input, options)
 }
 
-// String position tracking functionality
-
-// StringPosition represents a position in the original JSON string
-type StringPosition struct {
-	Start  int // Starting character position
-	End    int // Ending character position
-	Length int // Length of the element
+// QueryContextWithOptions executes a JSONPath query using the engine with custom options,
+// stopping early when ctx is done. When input is a JSON string, result positions are
+// counted in options.PositionUnit. Each Result carries every shape of its match;
+// Evaluate returns the shape options.ResultType selects.
+func (engine *JSONPathEngine) QueryContextWithOptions(ctx context.Context, path string, input interface{}, options *Options) ([]Result, error) {
+	if options == nil {
+		options = &Options{}
+	}
+	if err := validateResultType(options.ResultType, path); err != nil {
+		return nil, err
+	}
+	return engine.observe(ctx, path, func(ctx context.Context) ([]Result, error) {
+		return engine.query(ctx, path, input, options)
+	})
 }
 
-// findStringPositionForResult finds the character position of a result in the JSON string
-func findStringPositionForResult(result Result, jsonStr string) StringPosition {
-	// Handle different types of JSONPath results
-	path := result.Path
-
-	// Root element
-	if path == "$" {
-		return StringPosition{Start: 0, End: len(jsonStr), Length: len(jsonStr)}
+// query compiles path and runs it against input, parsing JSON strings first
+func (engine *JSONPathEngine) query(ctx context.Context, path string, input interface{}, options *Options) ([]Result, error) {
+	if err := validatePositionUnit(options.PositionUnit, path); err != nil {
+		return nil, err
 	}
 
-	// Handle property access in objects
-	if result.ParentProperty != "" && result.Parent != nil {
-		// Check if parent is an array (array element access)
-		if _, isArray := result.Parent.([]interface{}); isArray {
-			if idx, err := strconv.Atoi(result.ParentProperty); err == nil {
-				return findArrayElementPosition(idx, jsonStr)
-			}
-		}
-
-		// Property access in objects
-		if _, isObject := result.Parent.(map[string]interface{}); isObject {
-			return findPropertyValuePosition(result.ParentProperty, jsonStr, path)
+	str, ok := input.(string)
+	if !ok {
+		jp, err := engine.compileFor(path, options)
+		if err != nil {
+			return nil, err
 		}
+		return jp.run(ctx, input, options)
+	}
 
-		// Property access in OrderedMap objects
-		if _, isOrderedMap := result.Parent.(*utils.OrderedMap); isOrderedMap {
-			return findPropertyValuePosition(result.ParentProperty, jsonStr, path)
-		}
+	doc, err := parseDocument(str)
+	if err != nil {
+		return nil, err
 	}
+	return engine.queryDocument(ctx, path, doc, options)
+}
 
-	// Fallback: try to find by parsing the path
-	return findPositionByPath(path, jsonStr)
+// document is a JSON string parsed together with the positions of its keys and values
+type document struct {
+	source    string
+	data      interface{}
+	positions *utils.PositionNode
 }
 
-// findArrayElementPosition finds the position of an array element by index
-func findArrayElementPosition(index int, jsonStr string) StringPosition {
-	// Find the first array in the JSON
-	arrayStart := strings.Index(jsonStr, "[")
-	if arrayStart == -1 {
-		return StringPosition{}
+// parseDocument parses a JSON string, recording the byte span of every key and value
+func parseDocument(jsonStr string) (*document, error) {
+	data, positions, err := utils.ParseJSONWithPositions([]byte(jsonStr))
+	if err != nil {
+		return nil, err
 	}
+	return &document{source: jsonStr, data: data, positions: positions}, nil
+}
 
-	pos := arrayStart + 1
-	currentIndex := 0
-
-	// Skip whitespace
-	for pos < len(jsonStr) && isWhitespace(jsonStr[pos]) {
-		pos++
+// queryDocument runs path against a parsed document and fills in the string position
+// of each result, counted in options.PositionUnit
+func (engine *JSONPathEngine) queryDocument(ctx context.Context, path string, doc *document, options *Options) ([]Result, error) {
+	jp, err := engine.compileFor(path, options)
+	if err != nil {
+		return nil, err
 	}
 
-	// If we want index 0, we're at the first element
-	if index == 0 {
-		elementEnd := skipJSONElement(jsonStr, pos)
-		return StringPosition{Start: pos, End: elementEnd, Length: elementEnd - pos}
+	results, err := jp.run(ctx, doc.data, options)
+	if err != nil {
+		return nil, err
 	}
 
-	// Skip elements until we reach the target index
-	for currentIndex < index && pos < len(jsonStr) {
-		pos = skipJSONElement(jsonStr, pos)
-
-		// Skip whitespace and comma
-		for pos < len(jsonStr) && (isWhitespace(jsonStr[pos]) || jsonStr[pos] == ',') {
-			pos++
-		}
-		currentIndex++
+	converter := utils.NewOffsetConverter([]byte(doc.source), options.PositionUnit)
+	for i := range results {
+		keySpan, valueSpan := findSpansForResult(results[i], doc.positions)
+		results[i].KeySpan = convertSpan(converter, keySpan)
+		results[i].ValueSpan = convertSpan(converter, valueSpan)
+		stringPos := stringPosition(results[i].KeySpan, results[i].ValueSpan)
+		results[i].Start = stringPos.Start
+		results[i].End = stringPos.End
+		results[i].Length = stringPos.Length
+		// For backward compatibility, also set OriginalIndex to the start position
+		results[i].OriginalIndex = stringPos.Start
 	}
 
-	if pos < len(jsonStr) {
-		elementEnd := skipJSONElement(jsonStr, pos)
-		return StringPosition{Start: pos, End: elementEnd, Length: elementEnd - pos}
-	}
+	return results, nil
+}
 
-	return StringPosition{}
+// Close closes the engine (no-op for compatibility)
+func (engine *JSONPathEngine) Close() error {
+	return nil
 }
 
-// findPropertyValuePosition finds the position of a property key (not value)
-func findPropertyValuePosition(propName, jsonStr, path string) StringPosition {
-	// Extract the array index from the path if present
-	arrayIndex := -1
-	if strings.Contains(path, "[") && strings.Contains(path, "]") {
-		// Extract array index from path like $[0]['id'] or $.arr[1].prop
-		// Look for numeric array indices first (e.g., [0], [1], [2])
-		start := strings.Index(path, "[")
-		if start != -1 {
-			end := strings.Index(path[start:], "]")
-			if end != -1 {
-				indexStr := path[start+1 : start+end]
-				if idx, err := strconv.Atoi(indexStr); err == nil {
-					arrayIndex = idx
-				}
-			}
-		}
-	}
+// NewEngine creates a new JSONPath engine (backward compatibility)
+func NewEngine() *JSONPathEngine {
+	return NewJSONPathEngine()
+}
 
-	// Search for the property key
-	searchStr := fmt.Sprintf(`"%s"`, propName)
+// String position tracking functionality
 
-	pos := 0
-	occurrenceCount := 0
-	for {
-		idx := strings.Index(jsonStr[pos:], searchStr)
-		if idx == -1 {
-			break
-		}
+// StringPosition represents a position in the original JSON string
+type StringPosition struct {
+	Start  int // Starting character position
+	End    int // Ending character position
+	Length int // Length of the element
+}
 
-		absolutePos := pos + idx
+// findSpansForResult looks up the spans of a result in the positions recorded while
+// parsing the JSON string, following the JSON Pointer the evaluator recorded for it.
+// Object members get both a key and a value span, array elements and the root only a
+// value span. Property-name results (~) have the key they name as their value.
+func findSpansForResult(result Result, root *utils.PositionNode) (keySpan, valueSpan *types.Span) {
+	segments, err := pointerSegments(result.Pointer)
+	if err != nil {
+		return nil, nil
+	}
 
-		// Check if this is a property key (followed by colon)
-		afterKey := absolutePos + len(searchStr)
-		for afterKey < len(jsonStr) && isWhitespace(jsonStr[afterKey]) {
-			afterKey++
+	node := root
+	for _, segment := range segments {
+		if node.Members != nil {
+			node = node.Member(segment)
+		} else if idx, ok := pointerIndex(segment); ok {
+			node = node.Element(idx)
+		} else {
+			node = nil
 		}
-
-		if afterKey < len(jsonStr) && jsonStr[afterKey] == ':' {
-			// If we have an array index, return the occurrence that matches
-			if arrayIndex == -1 || occurrenceCount == arrayIndex {
-				return StringPosition{Start: absolutePos, End: absolutePos + len(searchStr), Length: len(searchStr)}
-			}
-			occurrenceCount++
+		if node == nil {
+			return nil, nil
 		}
-
-		pos = absolutePos + 1
-	}
-
-	return StringPosition{}
-}
-
-// findPositionByPath attempts to find position by parsing the JSONPath
-func findPositionByPath(path, jsonStr string) StringPosition {
-	// This is a simplified implementation
-	// For complex paths, we'd need more sophisticated parsing
-
-	// Handle simple property access like $.property
-	if strings.HasPrefix(path, "$.") && !strings.Contains(path[2:], ".") && !strings.Contains(path, "[") {
-		propName := path[2:]
-		return findPropertyValuePosition(propName, jsonStr, path)
 	}
 
-	// Handle array access like $[0], $[1], etc.
-	if strings.HasPrefix(path, "$[") && strings.HasSuffix(path, "]") {
-		indexStr := path[2 : len(path)-1]
-		if idx, err := strconv.Atoi(indexStr); err == nil {
-			return findArrayElementPosition(idx, jsonStr)
+	if result.PropertyName {
+		if node.Key == nil {
+			return nil, nil
 		}
+		key := *node.Key
+		return nil, &key
 	}
-
-	// For complex paths, return empty position
-	return StringPosition{}
+	value := node.Value
+	if node.Key != nil {
+		key := *node.Key
+		return &key, &value
+	}
+	return nil, &value
 }
 
-// Helper functions for JSON parsing
+// convertSpan converts a byte span into the converter's unit, keeping nil spans nil
+func convertSpan(converter *utils.OffsetConverter, span *types.Span) *types.Span {
+	if span == nil {
+		return nil
+	}
+	converted := converter.Span(*span)
+	return &converted
+}
 
-// skipJSONElement skips over a complete JSON element and returns the position after it
-func skipJSONElement(jsonStr string, start int) int {
-	if start >= len(jsonStr) {
-		return start
+// validatePositionUnit rejects position units the converter does not know
+func validatePositionUnit(unit PositionUnit, path string) error {
+	switch unit {
+	case "", PositionUnitBytes, PositionUnitRunes, PositionUnitUTF16:
+		return nil
+	default:
+		return NewError(ErrTypeError, "unknown position unit: "+string(unit), path, -1)
 	}
+}
 
-	char := jsonStr[start]
-	switch char {
-	case '"':
-		// Skip string
-		pos := start + 1
-		for pos < len(jsonStr) {
-			if jsonStr[pos] == '"' && (pos == start+1 || jsonStr[pos-1] != '\\') {
-				return pos + 1
-			}
-			pos++
+// validateEvalMode rejects evaluation modes the evaluator does not know, and the
+// sandbox mode when the engine's security config does not enable it
+func validateEvalMode(mode EvalMode, security *SecurityValidator, path string) error {
+	switch mode {
+	case "", EvalModeSafe, EvalModeDisabled:
+		return nil
+	case EvalModeSandbox:
+		if security != nil && !security.config.EnableSandbox {
+			return NewError(ErrInvalidExpression, "sandbox evaluation is not enabled", path, -1)
 		}
-		return pos
-	case '{':
-		// Skip object
-		return skipBracedElement(jsonStr, start, '{', '}')
-	case '[':
-		// Skip array
-		return skipBracedElement(jsonStr, start, '[', ']')
+		return nil
 	default:
-		// Skip primitive (number, boolean, null)
-		pos := start
-		for pos < len(jsonStr) && !isWhitespace(jsonStr[pos]) &&
-			jsonStr[pos] != ',' && jsonStr[pos] != '}' && jsonStr[pos] != ']' {
-			pos++
-		}
-		return pos
+		return NewError(ErrTypeError, "unknown eval mode: "+string(mode), path, -1)
 	}
 }
 
-// skipBracedElement skips over a complete braced element (object or array)
-func skipBracedElement(jsonStr string, start int, openBrace, closeBrace byte) int {
-	pos := start + 1
-	depth := 1
-	inString := false
-
-	for pos < len(jsonStr) && depth > 0 {
-		char := jsonStr[pos]
-
-		if char == '"' && (pos == 0 || jsonStr[pos-1] != '\\') {
-			inString = !inString
-		} else if !inString {
-			if char == openBrace {
-				depth++
-			} else if char == closeBrace {
-				depth--
-			}
+// hasExpressions reports whether an AST contains a filter or script expression
+func hasExpressions(node *types.AstNode) bool {
+	if node.Type == "filter" || node.Type == "script" {
+		return true
+	}
+	for _, child := range node.Children {
+		if hasExpressions(child) {
+			return true
 		}
-		pos++
 	}
+	return false
+}
 
-	return pos
+func expressionsDisabled(path string) error {
+	return NewError(ErrInvalidExpression, "filter and script expressions are disabled", path, -1)
 }
 
-// isWhitespace checks if a character is whitespace
-func isWhitespace(c byte) bool {
-	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+// stringPosition returns the legacy position of a result: the key span for object
+// members and the value span otherwise
+func stringPosition(keySpan, valueSpan *types.Span) StringPosition {
+	span := keySpan
+	if span == nil {
+		span = valueSpan
+	}
+	if span == nil {
+		return StringPosition{}
+	}
+	return StringPosition{Start: span.Start, End: span.End, Length: span.Len()}
 }
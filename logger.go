@@ -99,61 +99,6 @@ func (l *NoOpLogger) Warn(_ string, _ ...Field) {}
 // Error does nothing (no-op implementation).
 func (l *NoOpLogger) Error(_ string, _ ...Field) {}
 
-// Metrics collects performance metrics.
-type Metrics struct {
-	QueriesExecuted      int64
-	TotalExecutionTime   time.Duration
-	AverageExecutionTime time.Duration
-	ErrorCount           int64
-	MemoryUsage          int64
-}
-
-// MetricsCollector collects and tracks metrics.
-type MetricsCollector struct {
-	metrics Metrics
-	enabled bool
-}
-
-// NewMetricsCollector creates a new metrics collector.
-func NewMetricsCollector(enabled bool) *MetricsCollector {
-	return &MetricsCollector{
-		enabled: enabled,
-	}
-}
-
-// RecordQuery records a query execution.
-func (m *MetricsCollector) RecordQuery(duration time.Duration, err error) {
-	if !m.enabled {
-		return
-	}
-
-	m.metrics.QueriesExecuted++
-	m.metrics.TotalExecutionTime += duration
-	m.metrics.AverageExecutionTime = time.Duration(int64(m.metrics.TotalExecutionTime) / m.metrics.QueriesExecuted)
-
-	if err != nil {
-		m.metrics.ErrorCount++
-	}
-}
-
-// UpdateMemoryUsage updates the current memory usage.
-func (m *MetricsCollector) UpdateMemoryUsage(usage int64) {
-	if !m.enabled {
-		return
-	}
-	m.metrics.MemoryUsage = usage
-}
-
-// GetMetrics returns a copy of the current metrics.
-func (m *MetricsCollector) GetMetrics() Metrics {
-	return m.metrics
-}
-
-// Reset resets all metrics.
-func (m *MetricsCollector) Reset() {
-	m.metrics = Metrics{}
-}
-
 // String creates a field with a string value.
 func String(key, value string) Field {
 	return Field{Key: key, Value: value}
@@ -169,6 +114,16 @@ func Int64(key string, value int64) Field {
 	return Field{Key: key, Value: value}
 }
 
+// Bool creates a field with a bool value.
+func Bool(key string, value bool) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Any creates a field with a value of any type.
+func Any(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
 // Duration creates a field with a time.Duration value.
 func Duration(key string, value time.Duration) Field {
 	return Field{Key: key, Value: value}
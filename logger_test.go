@@ -0,0 +1,121 @@
+package jsonpathplus
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordingLogger keeps every event it receives, prefixed with its level.
+type recordingLogger struct {
+	mu     sync.Mutex
+	events []string
+	fields []map[string]interface{}
+}
+
+func (l *recordingLogger) record(level, msg string, fields []Field) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	values := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		values[field.Key] = field.Value
+	}
+	l.events = append(l.events, level+" "+msg)
+	l.fields = append(l.fields, values)
+}
+
+func (l *recordingLogger) Debug(msg string, fields ...Field) { l.record("DEBUG", msg, fields) }
+func (l *recordingLogger) Info(msg string, fields ...Field)  { l.record("INFO", msg, fields) }
+func (l *recordingLogger) Warn(msg string, fields ...Field)  { l.record("WARN", msg, fields) }
+func (l *recordingLogger) Error(msg string, fields ...Field) { l.record("ERROR", msg, fields) }
+
+func TestEngineLogging(t *testing.T) {
+	t.Run("Events", func(t *testing.T) {
+		logger := &recordingLogger{}
+		engine := NewJSONPathEngine()
+		engine.SetLogger(logger)
+
+		if _, err := engine.Query("$.users[?(@.age > 40)]", `{"users":[{"age":30}]}`); err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		engine.Query("$.users[", `{}`)
+
+		expected := []string{
+			"DEBUG evaluation started",
+			"DEBUG cache miss",
+			"DEBUG path compiled",
+			"DEBUG evaluation finished",
+			"DEBUG evaluation started",
+			"DEBUG cache miss",
+			"DEBUG path rejected",
+			"INFO query failed",
+		}
+		if strings.Join(logger.events, "\n") != strings.Join(expected, "\n") {
+			t.Fatalf("Expected events %v, got %v", expected, logger.events)
+		}
+		finished := logger.fields[3]
+		if finished["path"] != "$.users[?(@.age > 40)]" || finished["result_count"] != 0 {
+			t.Errorf("Unexpected fields %v", finished)
+		}
+		if evaluations, _ := finished["node_evaluations"].(map[string]int64); evaluations["filter"] != 1 {
+			t.Errorf("Expected node evaluations to include the filter, got %v", finished["node_evaluations"])
+		}
+	})
+
+	t.Run("LimitExceeded", func(t *testing.T) {
+		config := DefaultConfig()
+		config.MaxResultCount = 1
+		engine, err := NewJSONPathEngineWithConfig(config)
+		if err != nil {
+			t.Fatalf("NewJSONPathEngineWithConfig failed: %v", err)
+		}
+		logger := &recordingLogger{}
+		engine.SetLogger(logger)
+
+		engine.Query("$[*]", `[1,2]`)
+		last := len(logger.events) - 1
+		if logger.events[last] != "WARN limit exceeded" || logger.fields[last]["limit"] != "result_limit" {
+			t.Errorf("Expected a result_limit warning, got %s %v", logger.events[last], logger.fields[last])
+		}
+	})
+
+	t.Run("RecoveredPanic", func(t *testing.T) {
+		engine := NewJSONPathEngine()
+		logger := &recordingLogger{}
+		engine.SetLogger(logger)
+		err := engine.RegisterFunction("explode", func(args []interface{}) interface{} {
+			panic("boom")
+		}, FunctionSignature{Params: []FunctionType{ValueType}, Result: LogicalType})
+		if err != nil {
+			t.Fatalf("RegisterFunction failed: %v", err)
+		}
+
+		_, err = engine.Query("$[?(explode(@))]", `[1]`)
+		if !errors.Is(err, &JSONPathError{Type: ErrEvaluationError}) {
+			t.Fatalf("Expected ErrEvaluationError, got %v", err)
+		}
+		last := len(logger.events) - 1
+		if logger.events[last] != "ERROR recovered panic" || logger.fields[last]["panic"] != "boom" {
+			t.Errorf("Expected a recovered panic error, got %s %v", logger.events[last], logger.fields[last])
+		}
+	})
+
+	t.Run("Slog", func(t *testing.T) {
+		var buf bytes.Buffer
+		engine := NewJSONPathEngine()
+		engine.SetLogger(NewSlogLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))))
+
+		if _, err := engine.Query("$.a", `{"a":1}`); err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		output := buf.String()
+		for _, part := range []string{`level=DEBUG msg="path compiled" path=$.a`, `msg="evaluation finished"`, "result_count=1"} {
+			if !strings.Contains(output, part) {
+				t.Errorf("Expected %q in slog output:\n%s", part, output)
+			}
+		}
+	})
+}
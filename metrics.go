@@ -0,0 +1,259 @@
+package jsonpathplus
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/reclaimprotocol/jsonpathplus-go/pkg/utils"
+)
+
+// DefaultLatencyBuckets are the upper bounds, in seconds, of the query latency histogram.
+var DefaultLatencyBuckets = []float64{0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// DefaultResultCountBuckets are the upper bounds of the result count histogram.
+var DefaultResultCountBuckets = []float64{0, 1, 2, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// Histogram counts observations in buckets with fixed upper bounds.
+type Histogram struct {
+	// Bounds are the upper bounds of the buckets, in increasing order
+	Bounds []float64
+
+	// Counts holds one count per bucket plus a last count for observations above
+	// every bound; Counts[i] counts observations in (Bounds[i-1], Bounds[i]]
+	Counts []int64
+
+	// Count is the number of observations and Sum their total
+	Count int64
+	Sum   float64
+}
+
+func newHistogram(bounds []float64) Histogram {
+	return Histogram{Bounds: bounds, Counts: make([]int64, len(bounds)+1)}
+}
+
+func (h *Histogram) observe(value float64) {
+	h.Counts[sort.SearchFloat64s(h.Bounds, value)]++
+	h.Count++
+	h.Sum += value
+}
+
+func (h Histogram) clone() Histogram {
+	h.Counts = append([]int64(nil), h.Counts...)
+	return h
+}
+
+// Metrics is a snapshot of the metrics a MetricsCollector has recorded.
+type Metrics struct {
+	QueriesExecuted      int64
+	TotalExecutionTime   time.Duration
+	AverageExecutionTime time.Duration
+	ErrorCount           int64
+	MemoryUsage          int64
+
+	// Latency is the histogram of query latencies in seconds
+	Latency Histogram
+
+	// ResultCounts is the histogram of the number of results of successful queries
+	ResultCounts Histogram
+
+	// ErrorsByType counts failed queries by the type of their error
+	ErrorsByType map[ErrorType]int64
+
+	// NodeEvaluations counts evaluations of each kind of path node, such as "property"
+	// or "filter", against one input value
+	NodeEvaluations map[string]int64
+}
+
+// MetricsCollector collects and tracks metrics. It is safe for concurrent use.
+type MetricsCollector struct {
+	mu      sync.Mutex
+	metrics Metrics
+	enabled bool
+}
+
+// NewMetricsCollector creates a new metrics collector.
+func NewMetricsCollector(enabled bool) *MetricsCollector {
+	m := &MetricsCollector{enabled: enabled}
+	m.reset()
+	return m
+}
+
+// RecordQuery records the latency of a query execution and, when err is not nil,
+// its failure.
+func (m *MetricsCollector) RecordQuery(duration time.Duration, err error) {
+	if !m.enabled {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.metrics.QueriesExecuted++
+	m.metrics.TotalExecutionTime += duration
+	m.metrics.AverageExecutionTime = time.Duration(int64(m.metrics.TotalExecutionTime) / m.metrics.QueriesExecuted)
+	m.metrics.Latency.observe(duration.Seconds())
+
+	if err != nil {
+		m.metrics.ErrorCount++
+		m.metrics.ErrorsByType[classifyError(err)]++
+	}
+}
+
+// RecordResultCount records the number of results of a successful query.
+func (m *MetricsCollector) RecordResultCount(count int) {
+	if !m.enabled {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.metrics.ResultCounts.observe(float64(count))
+}
+
+// RecordNodeEvaluations adds counts of node evaluations, keyed by node type.
+func (m *MetricsCollector) RecordNodeEvaluations(counts map[string]int64) {
+	if !m.enabled || len(counts) == 0 {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for nodeType, count := range counts {
+		m.metrics.NodeEvaluations[nodeType] += count
+	}
+}
+
+// UpdateMemoryUsage updates the current memory usage.
+func (m *MetricsCollector) UpdateMemoryUsage(usage int64) {
+	if !m.enabled {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.metrics.MemoryUsage = usage
+}
+
+// GetMetrics returns a copy of the current metrics.
+func (m *MetricsCollector) GetMetrics() Metrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := m.metrics
+	snapshot.Latency = m.metrics.Latency.clone()
+	snapshot.ResultCounts = m.metrics.ResultCounts.clone()
+	snapshot.ErrorsByType = make(map[ErrorType]int64, len(m.metrics.ErrorsByType))
+	for errType, count := range m.metrics.ErrorsByType {
+		snapshot.ErrorsByType[errType] = count
+	}
+	snapshot.NodeEvaluations = make(map[string]int64, len(m.metrics.NodeEvaluations))
+	for nodeType, count := range m.metrics.NodeEvaluations {
+		snapshot.NodeEvaluations[nodeType] = count
+	}
+	return snapshot
+}
+
+// Reset resets all metrics.
+func (m *MetricsCollector) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reset()
+}
+
+func (m *MetricsCollector) reset() {
+	m.metrics = Metrics{
+		Latency:         newHistogram(DefaultLatencyBuckets),
+		ResultCounts:    newHistogram(DefaultResultCountBuckets),
+		ErrorsByType:    make(map[ErrorType]int64),
+		NodeEvaluations: make(map[string]int64),
+	}
+}
+
+// classifyError returns the error type metrics count err under. Errors that are not
+// a JSONPathError come from path length checks, JSON parsing or the path parser.
+func classifyError(err error) ErrorType {
+	var pathErr *JSONPathError
+	var lengthErr *PathLengthError
+	var syntaxErr *utils.JSONSyntaxError
+	switch {
+	case errors.As(err, &pathErr):
+		return pathErr.Type
+	case errors.As(err, &lengthErr):
+		return ErrInvalidPath
+	case errors.As(err, &syntaxErr):
+		return ErrInvalidJSON
+	default:
+		return ErrParseError
+	}
+}
+
+// WritePrometheus writes the metrics in the Prometheus text exposition format.
+func (m *MetricsCollector) WritePrometheus(w io.Writer) error {
+	metrics := m.GetMetrics()
+	bw := bufio.NewWriter(w)
+
+	writeHeader(bw, "jsonpath_queries_total", "counter", "Queries executed.")
+	fmt.Fprintf(bw, "jsonpath_queries_total %d\n", metrics.QueriesExecuted)
+
+	writeHeader(bw, "jsonpath_query_errors_total", "counter", "Failed queries by error type.")
+	errTypes := make([]ErrorType, 0, len(metrics.ErrorsByType))
+	for errType := range metrics.ErrorsByType {
+		errTypes = append(errTypes, errType)
+	}
+	sort.Slice(errTypes, func(i, j int) bool { return errTypes[i] < errTypes[j] })
+	for _, errType := range errTypes {
+		fmt.Fprintf(bw, "jsonpath_query_errors_total{type=%q} %d\n", errType.String(), metrics.ErrorsByType[errType])
+	}
+
+	writeHeader(bw, "jsonpath_query_duration_seconds", "histogram", "Query latency in seconds.")
+	writeHistogram(bw, "jsonpath_query_duration_seconds", metrics.Latency)
+
+	writeHeader(bw, "jsonpath_query_results", "histogram", "Results returned by successful queries.")
+	writeHistogram(bw, "jsonpath_query_results", metrics.ResultCounts)
+
+	writeHeader(bw, "jsonpath_node_evaluations_total", "counter", "Path node evaluations by node type.")
+	nodeTypes := make([]string, 0, len(metrics.NodeEvaluations))
+	for nodeType := range metrics.NodeEvaluations {
+		nodeTypes = append(nodeTypes, nodeType)
+	}
+	sort.Strings(nodeTypes)
+	for _, nodeType := range nodeTypes {
+		fmt.Fprintf(bw, "jsonpath_node_evaluations_total{node=%q} %d\n", nodeType, metrics.NodeEvaluations[nodeType])
+	}
+
+	writeHeader(bw, "jsonpath_memory_usage_bytes", "gauge", "Last reported memory usage in bytes.")
+	fmt.Fprintf(bw, "jsonpath_memory_usage_bytes %d\n", metrics.MemoryUsage)
+
+	return bw.Flush()
+}
+
+// ServeHTTP serves the metrics in the Prometheus text exposition format, so a
+// MetricsCollector can be registered directly as a scrape endpoint.
+func (m *MetricsCollector) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := m.WritePrometheus(w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func writeHeader(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func writeHistogram(w io.Writer, name string, h Histogram) {
+	var cumulative int64
+	for i, bound := range h.Bounds {
+		cumulative += h.Counts[i]
+		fmt.Fprintf(w, "%s_bucket{le=%q} %d\n", name, strconv.FormatFloat(bound, 'g', -1, 64), cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", name, h.Count)
+	fmt.Fprintf(w, "%s_sum %s\n", name, strconv.FormatFloat(h.Sum, 'g', -1, 64))
+	fmt.Fprintf(w, "%s_count %d\n", name, h.Count)
+}
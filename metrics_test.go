@@ -0,0 +1,101 @@
+package jsonpathplus
+
+import (
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMetricsCollector(t *testing.T) {
+	config := DefaultConfig()
+	config.EnableMetrics = true
+	engine, err := NewJSONPathEngineWithConfig(config)
+	if err != nil {
+		t.Fatalf("NewJSONPathEngineWithConfig failed: %v", err)
+	}
+
+	data := `{"users":[{"name":"a","age":30},{"name":"b","age":20}]}`
+
+	t.Run("Concurrent", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := engine.Query("$.users[?(@.age > 25)].name", data); err != nil {
+					t.Errorf("Query failed: %v", err)
+				}
+				engine.Query("$.users[", data)
+			}()
+		}
+		wg.Wait()
+
+		metrics := engine.GetMetrics()
+		if metrics.QueriesExecuted != 40 {
+			t.Errorf("Expected 40 queries, got %d", metrics.QueriesExecuted)
+		}
+		if metrics.ErrorCount != 20 || metrics.ErrorsByType[ErrParseError] != 20 {
+			t.Errorf("Expected 20 parse errors, got %d (%v)", metrics.ErrorCount, metrics.ErrorsByType)
+		}
+		if metrics.Latency.Count != 40 {
+			t.Errorf("Expected 40 latency observations, got %d", metrics.Latency.Count)
+		}
+		if metrics.ResultCounts.Count != 20 || metrics.ResultCounts.Counts[1] != 20 {
+			t.Errorf("Expected 20 queries with one result, got %+v", metrics.ResultCounts)
+		}
+		expected := map[string]int64{"root": 20, "property": 40, "filter": 20}
+		for nodeType, count := range expected {
+			if metrics.NodeEvaluations[nodeType] != count {
+				t.Errorf("Expected %d %s evaluations, got %d", count, nodeType, metrics.NodeEvaluations[nodeType])
+			}
+		}
+	})
+
+	t.Run("Prometheus", func(t *testing.T) {
+		jp, err := engine.CompileStandard("$.users[?@.age > 25]")
+		if err != nil {
+			t.Fatalf("CompileStandard failed: %v", err)
+		}
+		if _, err := jp.ExecuteWithOptions([]interface{}{}, nil); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+
+		recorder := httptest.NewRecorder()
+		engine.Metrics().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+		body := recorder.Body.String()
+
+		if contentType := recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain; version=0.0.4") {
+			t.Errorf("Unexpected content type %q", contentType)
+		}
+		lines := []string{
+			"# TYPE jsonpath_queries_total counter",
+			"jsonpath_queries_total 41",
+			`jsonpath_query_errors_total{type="parse_error"} 20`,
+			"# TYPE jsonpath_query_duration_seconds histogram",
+			`jsonpath_query_duration_seconds_bucket{le="+Inf"} 41`,
+			"jsonpath_query_duration_seconds_count 41",
+			`jsonpath_query_results_bucket{le="0"} 1`,
+			`jsonpath_query_results_bucket{le="1"} 21`,
+			`jsonpath_node_evaluations_total{node="property"} 41`,
+		}
+		for _, line := range lines {
+			if !strings.Contains(body, line+"\n") {
+				t.Errorf("Expected line %q in:\n%s", line, body)
+			}
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		engine := NewJSONPathEngine()
+		if _, err := engine.Query("$.a", `{"a":1}`); err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		if engine.Metrics() != nil {
+			t.Error("Expected no collector without EnableMetrics")
+		}
+		if metrics := engine.GetMetrics(); metrics.QueriesExecuted != 0 {
+			t.Errorf("Expected empty metrics, got %d queries", metrics.QueriesExecuted)
+		}
+	})
+}
@@ -4,20 +4,42 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Result represents a single result from a JSONPath query
 type Result struct {
 	Value          interface{} // The actual value found
 	Path           string      // JSONPath to this value
+	Pointer        string      // RFC 6901 JSON Pointer to this value, e.g. /store/book/0
 	Parent         interface{} // Parent object/array containing this value
 	ParentProperty string      // Property name or array index in parent
+	PropertyName   bool        // Whether Value is the name of the member at Pointer, as selected by ~
 	Index          int         // Index in the result set
 	OriginalIndex  int         // Original index in parent array (for array elements)
 	// String index fields for character position tracking
 	Start  int // Starting character position in original JSON string
 	End    int // Ending character position in original JSON string
 	Length int // Length of the element in the original JSON string
+	// Exact spans, set only when the query ran against a JSON string
+	KeySpan   *Span // The quoted member key; nil for array elements and the root
+	ValueSpan *Span // The raw value, including quotes for strings and braces for containers
+}
+
+// Span is a half-open range [Start, End) in the original JSON string, counted in
+// the unit selected by Options.PositionUnit
+type Span struct {
+	Start     int
+	End       int
+	Line      int // 1-based line of Start
+	Column    int // 1-based column of Start, in the same unit as Start
+	EndLine   int // 1-based line of End
+	EndColumn int // 1-based column of End, in the same unit as End
+}
+
+// Len returns the number of units covered by the span
+func (s Span) Len() int {
+	return s.End - s.Start
 }
 
 // String returns a string representation of the result
@@ -25,15 +47,71 @@ func (r Result) String() string {
 	return fmt.Sprintf("Result{Value: %v, Path: %s}", r.Value, r.Path)
 }
 
+// ResultType selects how each match is shaped, mirroring JSONPath-Plus resultType
+type ResultType string
+
+const (
+	ResultTypeValue          ResultType = "value"          // The matched value
+	ResultTypePath           ResultType = "path"           // The bracket-notation path, e.g. $['store']['book'][0]
+	ResultTypePointer        ResultType = "pointer"        // The RFC 6901 JSON Pointer, e.g. /store/book/0
+	ResultTypeParent         ResultType = "parent"         // The object or array containing the match
+	ResultTypeParentProperty ResultType = "parentProperty" // The key (string) or index (int) in the parent
+	ResultTypeAll            ResultType = "all"            // A record holding all of the above
+)
+
+// PositionUnit selects how offsets into a JSON string are counted
+type PositionUnit string
+
+const (
+	PositionUnitBytes PositionUnit = "bytes" // Bytes of the UTF-8 encoding
+	PositionUnitRunes PositionUnit = "runes" // Unicode code points
+	PositionUnitUTF16 PositionUnit = "utf16" // UTF-16 code units, as JavaScript string indices count
+)
+
+// EvalMode selects whether and how filter and script expressions are evaluated,
+// mirroring the JSONPath-Plus eval and sandbox options
+type EvalMode string
+
+const (
+	EvalModeSafe     EvalMode = "safe"     // The restricted built-in evaluator
+	EvalModeDisabled EvalMode = "disabled" // Paths containing filter or script expressions are rejected
+	EvalModeSandbox  EvalMode = "sandbox"  // The built-in evaluator, with the names in Options.Sandbox defined
+)
+
 // Options configures JSONPath query execution
 type Options struct {
-	Root interface{} // Root object for $ references in filters
+	Root         interface{}            // Root object for $ references in filters
+	ResultType   ResultType             // Shape of the matches Evaluate returns; empty means ResultTypeValue. A Result carries every shape
+	PositionUnit PositionUnit           // Unit of result positions in a JSON string; empty means PositionUnitBytes
+	Standard     bool                   // Accept only RFC 9535 syntax, follow its semantics and report normalized paths
+	EvalMode     EvalMode               // How filter and script expressions are evaluated; empty means EvalModeSafe
+	Sandbox      map[string]interface{} // Values and functions expressions may use by name under EvalModeSandbox
+	Tracer       Tracer                 // Notified around the evaluation of each AST node; nil disables tracing
+}
+
+// Tracer receives a span for each evaluation of an AST node against a set of input
+// values. Spans nest: the spans of the steps applied to a node's values start and end
+// while the node's own span is open, and EndSpan always closes the most recently
+// started span. RFC 9535 queries are reported with a node per selector and a
+// "recursive" node per descendant segment.
+type Tracer interface {
+	// StartSpan is called before node is evaluated against inputs input values
+	StartSpan(node *AstNode, inputs int)
+	// EndSpan is called once the evaluation of node and the steps after it ended;
+	// outputs counts the values node itself selected and elapsed includes those steps
+	EndSpan(node *AstNode, outputs int, elapsed time.Duration)
+}
+
+// Expression is a filter expression compiled by the parser
+type Expression interface {
+	String() string
 }
 
 // AstNode represents a node in the Abstract Syntax Tree for JSONPath expressions
 type AstNode struct {
 	Type     string     // Node type: "root", "property", "wildcard", "index", "slice", "filter", "recursive", "union"
 	Value    string     // Node value (property name, index, filter expression, etc.)
+	Expr     Expression // Compiled expression of a filter node, nil for other nodes
 	Children []*AstNode // Child nodes
 }
 
@@ -47,14 +125,15 @@ func (n *AstNode) String() string {
 
 // Context holds evaluation context for advanced JSONPath features
 type Context struct {
-	Root                   interface{} // Root object
-	Current                interface{} // Current object being evaluated
-	Parent                 interface{} // Parent of current object
-	ParentProperty         string      // Property name or index in parent
-	Path                   string      // Current JSONPath
-	Index                  int         // Current index (for arrays)
-	ParentOfParentProperty string      // Property that led to the parent (for @parentProperty)
-	ActualParentArray      interface{} // For array elements, the actual array (for @property type detection)
+	Root                   interface{}            // Root object
+	Current                interface{}            // Current object being evaluated
+	Parent                 interface{}            // Parent of current object
+	ParentProperty         string                 // Property name or index in parent
+	Path                   string                 // Current JSONPath
+	Index                  int                    // Current index (for arrays)
+	ParentOfParentProperty string                 // Property that led to the parent (for @parentProperty)
+	ActualParentArray      interface{}            // For array elements, the actual array (for @property type detection)
+	Sandbox                map[string]interface{} // Names defined for expressions, from Options.Sandbox
 }
 
 // NewContext creates a new evaluation context
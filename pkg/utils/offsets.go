@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"sort"
+	"unicode/utf8"
+
+	"github.com/reclaimprotocol/jsonpathplus-go/pkg/types"
+)
+
+// checkpointInterval is the number of bytes between recorded unit offsets, trading
+// memory for the length of the scan needed to convert one offset
+const checkpointInterval = 256
+
+// OffsetConverter converts byte offsets in a JSON document into offsets counted in
+// another unit, plus 1-based line and column numbers. Each byte of an invalid UTF-8
+// sequence counts as one unit, as it decodes to a U+FFFD of its own.
+type OffsetConverter struct {
+	data        []byte
+	unit        types.PositionUnit
+	lineStarts  []int        // Byte offset of the first byte of each line
+	checkpoints []checkpoint // The first character boundary at or after every checkpointInterval bytes
+}
+
+// checkpoint records the unit offset of a character boundary
+type checkpoint struct {
+	byteOffset int
+	units      int
+}
+
+// NewOffsetConverter prepares conversions for data. An empty unit counts bytes.
+func NewOffsetConverter(data []byte, unit types.PositionUnit) *OffsetConverter {
+	c := &OffsetConverter{data: data, unit: unit, lineStarts: []int{0}}
+	for i, b := range data {
+		if b == '\n' {
+			c.lineStarts = append(c.lineStarts, i+1)
+		}
+	}
+
+	if !c.countsBytes() {
+		c.checkpoints = make([]checkpoint, 0, len(data)/checkpointInterval+1)
+		count := 0
+		for i := 0; i < len(data); {
+			if i >= len(c.checkpoints)*checkpointInterval {
+				c.checkpoints = append(c.checkpoints, checkpoint{byteOffset: i, units: count})
+			}
+			size, units := c.decode(i)
+			i += size
+			count += units
+		}
+	}
+	return c
+}
+
+func (c *OffsetConverter) countsBytes() bool {
+	return c.unit == "" || c.unit == types.PositionUnitBytes
+}
+
+// decode returns the length in bytes of the character starting at data[i] and the
+// units it adds: one code point, or two UTF-16 units for characters outside the BMP.
+// A byte that does not start a valid sequence is a character of its own.
+func (c *OffsetConverter) decode(i int) (size, units int) {
+	// Four-byte sequences encode the characters outside the BMP
+	_, size = utf8.DecodeRune(c.data[i:])
+	if c.unit == types.PositionUnitUTF16 && size == 4 {
+		return size, 2
+	}
+	return size, 1
+}
+
+// Offset converts a byte offset into the converter's unit. An offset within a
+// character counts that character.
+func (c *OffsetConverter) Offset(byteOffset int) int {
+	if c.countsBytes() {
+		return byteOffset
+	}
+	if byteOffset > len(c.data) {
+		byteOffset = len(c.data)
+	}
+	if byteOffset <= 0 {
+		return 0
+	}
+
+	idx := (byteOffset - 1) / checkpointInterval
+	if idx >= len(c.checkpoints) {
+		idx = len(c.checkpoints) - 1
+	}
+	if c.checkpoints[idx].byteOffset >= byteOffset {
+		idx--
+	}
+	count := c.checkpoints[idx].units
+	for i := c.checkpoints[idx].byteOffset; i < byteOffset; {
+		size, units := c.decode(i)
+		i += size
+		count += units
+	}
+	return count
+}
+
+// LineColumn returns the 1-based line and column of a byte offset, with the
+// column counted in the converter's unit
+func (c *OffsetConverter) LineColumn(byteOffset int) (line, column int) {
+	// Index of the last line starting at or before byteOffset
+	idx := sort.Search(len(c.lineStarts), func(i int) bool {
+		return c.lineStarts[i] > byteOffset
+	}) - 1
+	return idx + 1, c.Offset(byteOffset) - c.Offset(c.lineStarts[idx]) + 1
+}
+
+// Span converts a byte span into the converter's unit and fills in its line and column numbers
+func (c *OffsetConverter) Span(span types.Span) types.Span {
+	converted := types.Span{Start: c.Offset(span.Start), End: c.Offset(span.End)}
+	converted.Line, converted.Column = c.LineColumn(span.Start)
+	converted.EndLine, converted.EndColumn = c.LineColumn(span.End)
+	return converted
+}
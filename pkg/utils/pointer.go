@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+)
+
+// MemberPointer appends an object member to an RFC 6901 JSON Pointer, escaping ~ and /
+func MemberPointer(pointer, key string) string {
+	key = strings.ReplaceAll(key, "~", "~0")
+	key = strings.ReplaceAll(key, "/", "~1")
+	return pointer + "/" + key
+}
+
+// ElementPointer appends an array index to an RFC 6901 JSON Pointer
+func ElementPointer(pointer string, idx int) string {
+	return pointer + "/" + strconv.Itoa(idx)
+}
+
+// ParentPointer removes the last reference token of a JSON Pointer; the parent of the
+// root pointer "" is the root itself
+func ParentPointer(pointer string) string {
+	if i := strings.LastIndexByte(pointer, '/'); i >= 0 {
+		return pointer[:i]
+	}
+	return ""
+}
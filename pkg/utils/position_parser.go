@@ -0,0 +1,383 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/reclaimprotocol/jsonpathplus-go/pkg/types"
+)
+
+// maxJSONDepth bounds how deeply arrays and objects may nest, matching encoding/json
+const maxJSONDepth = 10000
+
+// PositionNode records where a parsed JSON value appears in the source document
+type PositionNode struct {
+	Value    types.Span               // The raw value, including quotes for strings and brackets for containers
+	Key      *types.Span              // The quoted member key, nil for array elements and the root
+	Keys     []string                 // Member keys of an object in OrderedMap order
+	Members  map[string]*PositionNode // Member positions of an object; the last duplicate key wins
+	Elements []*PositionNode          // Element positions of an array
+}
+
+// Member returns the position of the member with the given key, or nil
+func (n *PositionNode) Member(key string) *PositionNode {
+	if n == nil || n.Members == nil {
+		return nil
+	}
+	return n.Members[key]
+}
+
+// Element returns the position of the array element at index, or nil
+func (n *PositionNode) Element(index int) *PositionNode {
+	if n == nil || index < 0 || index >= len(n.Elements) {
+		return nil
+	}
+	return n.Elements[index]
+}
+
+// JSONSyntaxError reports malformed JSON together with the byte offset of the problem
+type JSONSyntaxError struct {
+	Offset int
+	Msg    string
+}
+
+func (e *JSONSyntaxError) Error() string {
+	return fmt.Sprintf("%s at offset %d", e.Msg, e.Offset)
+}
+
+// ParseJSONWithPositions parses JSON into the same OrderedMap/slice tree as
+// ParseOrderedJSON and also records the byte span of every key and value
+func ParseJSONWithPositions(data []byte) (interface{}, *PositionNode, error) {
+	p := &positionParser{data: data}
+	p.skipSpace()
+	value, node, err := p.parseValue(0)
+	if err != nil {
+		return nil, nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.data) {
+		return nil, nil, p.errorf("invalid character %s after top-level value", quoteChar(p.data[p.pos]))
+	}
+	return value, node, nil
+}
+
+// positionParser is a recursive-descent JSON parser that tracks byte offsets
+type positionParser struct {
+	data []byte
+	pos  int
+}
+
+func (p *positionParser) errorf(format string, args ...interface{}) error {
+	return &JSONSyntaxError{Offset: p.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *positionParser) unexpected(context string) error {
+	if p.pos >= len(p.data) {
+		return p.errorf("unexpected end of JSON input")
+	}
+	return p.errorf("invalid character %s %s", quoteChar(p.data[p.pos]), context)
+}
+
+func (p *positionParser) skipSpace() {
+	for p.pos < len(p.data) && isWhitespace(p.data[p.pos]) {
+		p.pos++
+	}
+}
+
+// parseValue parses the value starting at the current position, which must not be whitespace
+func (p *positionParser) parseValue(depth int) (interface{}, *PositionNode, error) {
+	if p.pos >= len(p.data) {
+		return nil, nil, p.errorf("unexpected end of JSON input")
+	}
+
+	start := p.pos
+	switch ch := p.data[p.pos]; {
+	case ch == '{':
+		return p.parseObject(depth + 1)
+	case ch == '[':
+		return p.parseArray(depth + 1)
+	case ch == '"':
+		s, err := p.parseString()
+		if err != nil {
+			return nil, nil, err
+		}
+		return s, &PositionNode{Value: types.Span{Start: start, End: p.pos}}, nil
+	case ch == '-' || (ch >= '0' && ch <= '9'):
+		num, err := p.parseNumber()
+		if err != nil {
+			return nil, nil, err
+		}
+		return num, &PositionNode{Value: types.Span{Start: start, End: p.pos}}, nil
+	case ch == 't':
+		if err := p.parseLiteral("true"); err != nil {
+			return nil, nil, err
+		}
+		return true, &PositionNode{Value: types.Span{Start: start, End: p.pos}}, nil
+	case ch == 'f':
+		if err := p.parseLiteral("false"); err != nil {
+			return nil, nil, err
+		}
+		return false, &PositionNode{Value: types.Span{Start: start, End: p.pos}}, nil
+	case ch == 'n':
+		if err := p.parseLiteral("null"); err != nil {
+			return nil, nil, err
+		}
+		return nil, &PositionNode{Value: types.Span{Start: start, End: p.pos}}, nil
+	default:
+		return nil, nil, p.unexpected("looking for beginning of value")
+	}
+}
+
+func (p *positionParser) parseObject(depth int) (interface{}, *PositionNode, error) {
+	if depth > maxJSONDepth {
+		return nil, nil, p.errorf("exceeded max depth")
+	}
+
+	start := p.pos
+	p.pos++ // opening brace
+	om := NewOrderedMap()
+	node := &PositionNode{Members: make(map[string]*PositionNode)}
+
+	p.skipSpace()
+	if p.pos < len(p.data) && p.data[p.pos] == '}' {
+		p.pos++
+		node.Value = types.Span{Start: start, End: p.pos}
+		return om, node, nil
+	}
+
+	for {
+		if p.pos >= len(p.data) || p.data[p.pos] != '"' {
+			return nil, nil, p.unexpected("looking for beginning of object key string")
+		}
+		keyStart := p.pos
+		key, err := p.parseString()
+		if err != nil {
+			return nil, nil, err
+		}
+		keySpan := types.Span{Start: keyStart, End: p.pos}
+
+		p.skipSpace()
+		if p.pos >= len(p.data) || p.data[p.pos] != ':' {
+			return nil, nil, p.unexpected("after object key")
+		}
+		p.pos++
+		p.skipSpace()
+
+		value, child, err := p.parseValue(depth)
+		if err != nil {
+			return nil, nil, err
+		}
+		child.Key = &keySpan
+		om.Set(key, value)
+		node.Members[key] = child
+
+		p.skipSpace()
+		if p.pos >= len(p.data) {
+			return nil, nil, p.errorf("unexpected end of JSON input")
+		}
+		switch p.data[p.pos] {
+		case ',':
+			p.pos++
+			p.skipSpace()
+		case '}':
+			p.pos++
+			node.Value = types.Span{Start: start, End: p.pos}
+			node.Keys = om.Keys()
+			return om, node, nil
+		default:
+			return nil, nil, p.unexpected("after object key:value pair")
+		}
+	}
+}
+
+func (p *positionParser) parseArray(depth int) (interface{}, *PositionNode, error) {
+	if depth > maxJSONDepth {
+		return nil, nil, p.errorf("exceeded max depth")
+	}
+
+	start := p.pos
+	p.pos++ // opening bracket
+	// Empty arrays stay nil, as ParseOrderedJSON returns them
+	var arr []interface{}
+	node := &PositionNode{}
+
+	p.skipSpace()
+	if p.pos < len(p.data) && p.data[p.pos] == ']' {
+		p.pos++
+		node.Value = types.Span{Start: start, End: p.pos}
+		return arr, node, nil
+	}
+
+	for {
+		value, child, err := p.parseValue(depth)
+		if err != nil {
+			return nil, nil, err
+		}
+		arr = append(arr, value)
+		node.Elements = append(node.Elements, child)
+
+		p.skipSpace()
+		if p.pos >= len(p.data) {
+			return nil, nil, p.errorf("unexpected end of JSON input")
+		}
+		switch p.data[p.pos] {
+		case ',':
+			p.pos++
+			p.skipSpace()
+		case ']':
+			p.pos++
+			node.Value = types.Span{Start: start, End: p.pos}
+			return arr, node, nil
+		default:
+			return nil, nil, p.unexpected("after array element")
+		}
+	}
+}
+
+// parseString validates the string literal at the current position and returns its
+// unescaped value. Escapes and invalid UTF-8 are decoded by encoding/json so the
+// result is identical to what ParseOrderedJSON produces.
+func (p *positionParser) parseString() (string, error) {
+	start := p.pos
+	p.pos++ // opening quote
+	simple := true
+
+	for p.pos < len(p.data) {
+		ch := p.data[p.pos]
+		switch {
+		case ch == '"':
+			p.pos++
+			raw := p.data[start:p.pos]
+			if simple {
+				return string(raw[1 : len(raw)-1]), nil
+			}
+			var s string
+			if err := json.Unmarshal(raw, &s); err != nil {
+				return "", &JSONSyntaxError{Offset: start, Msg: err.Error()}
+			}
+			return s, nil
+		case ch == '\\':
+			simple = false
+			p.pos++
+			if p.pos >= len(p.data) {
+				return "", p.errorf("unexpected end of JSON input")
+			}
+			switch p.data[p.pos] {
+			case '"', '\\', '/', 'b', 'f', 'n', 'r', 't':
+				p.pos++
+			case 'u':
+				p.pos++
+				for i := 0; i < 4; i++ {
+					if p.pos >= len(p.data) {
+						return "", p.errorf("unexpected end of JSON input")
+					}
+					if !isHexDigit(p.data[p.pos]) {
+						return "", p.unexpected("in \\u hexadecimal character escape")
+					}
+					p.pos++
+				}
+			default:
+				return "", p.unexpected("in string escape code")
+			}
+		case ch < 0x20:
+			return "", p.unexpected("in string literal")
+		default:
+			if ch >= utf8.RuneSelf {
+				simple = false
+			}
+			p.pos++
+		}
+	}
+	return "", p.errorf("unexpected end of JSON input")
+}
+
+// parseNumber parses a number following the JSON grammar
+func (p *positionParser) parseNumber() (float64, error) {
+	start := p.pos
+	if p.data[p.pos] == '-' {
+		p.pos++
+	}
+
+	if p.pos >= len(p.data) {
+		return 0, p.errorf("unexpected end of JSON input")
+	}
+	switch ch := p.data[p.pos]; {
+	case ch == '0':
+		p.pos++
+	case ch >= '1' && ch <= '9':
+		p.skipDigits()
+	default:
+		return 0, p.unexpected("in numeric literal")
+	}
+
+	if p.pos < len(p.data) && p.data[p.pos] == '.' {
+		p.pos++
+		if p.pos >= len(p.data) || !isDigitByte(p.data[p.pos]) {
+			return 0, p.unexpected("after decimal point in numeric literal")
+		}
+		p.skipDigits()
+	}
+
+	if p.pos < len(p.data) && (p.data[p.pos] == 'e' || p.data[p.pos] == 'E') {
+		p.pos++
+		if p.pos < len(p.data) && (p.data[p.pos] == '+' || p.data[p.pos] == '-') {
+			p.pos++
+		}
+		if p.pos >= len(p.data) || !isDigitByte(p.data[p.pos]) {
+			return 0, p.unexpected("in exponent of numeric literal")
+		}
+		p.skipDigits()
+	}
+
+	text := string(p.data[start:p.pos])
+	num, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0, &JSONSyntaxError{Offset: start, Msg: fmt.Sprintf("number %s out of range", text)}
+	}
+	return num, nil
+}
+
+func (p *positionParser) skipDigits() {
+	for p.pos < len(p.data) && isDigitByte(p.data[p.pos]) {
+		p.pos++
+	}
+}
+
+func (p *positionParser) parseLiteral(literal string) error {
+	for i := 0; i < len(literal); i++ {
+		if p.pos >= len(p.data) {
+			return p.errorf("unexpected end of JSON input")
+		}
+		if p.data[p.pos] != literal[i] {
+			return p.unexpected(fmt.Sprintf("in literal %s (expecting %s)", literal, quoteChar(literal[i])))
+		}
+		p.pos++
+	}
+	return nil
+}
+
+func isWhitespace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isDigitByte(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isHexDigit(c byte) bool {
+	return isDigitByte(c) || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// quoteChar formats a byte the way encoding/json does in its error messages
+func quoteChar(c byte) string {
+	if c == '\'' {
+		return `'\''`
+	}
+	if c == '"' {
+		return `'"'`
+	}
+	s := strconv.Quote(string(c))
+	return "'" + s[1:len(s)-1] + "'"
+}
@@ -0,0 +1,276 @@
+package jsonpathplus
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/reclaimprotocol/jsonpathplus-go/pkg/utils"
+)
+
+// PathToPointer converts a singular JSONPath such as $['store']['book'][0] or
+// $.store.book[0] into an RFC 6901 JSON Pointer such as /store/book/0
+func PathToPointer(path string) (string, error) {
+	var segments []string
+	if err := scanPath(path, func(segment string) {
+		segments = append(segments, segment)
+	}); err != nil {
+		return "", err
+	}
+	return toPointer(segments), nil
+}
+
+// PointerToPath converts an RFC 6901 JSON Pointer into a JSONPath in bracket notation.
+// Segments made of digits become array indices, as JSONPath-Plus renders them.
+func PointerToPath(pointer string) (string, error) {
+	segments, err := pointerSegments(pointer)
+	if err != nil {
+		return "", err
+	}
+	return toPathString(segments), nil
+}
+
+// ResolvePointer returns the value an RFC 6901 JSON Pointer refers to in data
+func ResolvePointer(data interface{}, pointer string) (interface{}, error) {
+	segments, err := pointerSegments(pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	current := data
+	for i, segment := range segments {
+		at := toPointer(segments[:i+1])
+		switch v := current.(type) {
+		case *utils.OrderedMap:
+			value, ok := v.Get(segment)
+			if !ok {
+				return nil, NewError(ErrEvaluationError, "no member "+strconv.Quote(segment)+" at "+at, pointer, -1)
+			}
+			current = value
+		case map[string]interface{}:
+			value, ok := v[segment]
+			if !ok {
+				return nil, NewError(ErrEvaluationError, "no member "+strconv.Quote(segment)+" at "+at, pointer, -1)
+			}
+			current = value
+		case []interface{}:
+			idx, ok := pointerIndex(segment)
+			if !ok {
+				return nil, NewError(ErrTypeError, "invalid array index "+strconv.Quote(segment)+" at "+at, pointer, -1)
+			}
+			if idx >= len(v) {
+				return nil, NewError(ErrOutOfBounds, fmt.Sprintf("index %d out of range at %s", idx, at), pointer, -1)
+			}
+			current = v[idx]
+		default:
+			return nil, NewError(ErrTypeError, "cannot index a non-container value at "+at, pointer, -1)
+		}
+	}
+	return current, nil
+}
+
+// pointerSegments splits and unescapes an RFC 6901 JSON Pointer
+func pointerSegments(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if pointer[0] != '/' {
+		return nil, NewError(ErrInvalidPath, "JSON Pointer must be empty or start with '/'", pointer, 0)
+	}
+
+	parts := strings.Split(pointer[1:], "/")
+	offset := 1
+	for i, part := range parts {
+		for j := 0; j < len(part); j++ {
+			if part[j] == '~' && (j+1 >= len(part) || (part[j+1] != '0' && part[j+1] != '1')) {
+				return nil, NewError(ErrInvalidPath, "'~' must be followed by '0' or '1'", pointer, offset+j)
+			}
+		}
+		offset += len(part) + 1
+		// ~1 is unescaped before ~0 so that ~01 stays the literal ~1
+		parts[i] = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+	}
+	return parts, nil
+}
+
+// pointerIndex parses an array index segment, which RFC 6901 writes without leading zeros
+func pointerIndex(segment string) (int, bool) {
+	if segment == "" || (len(segment) > 1 && segment[0] == '0') {
+		return 0, false
+	}
+	for _, c := range segment {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+	}
+	idx, err := strconv.Atoi(segment)
+	return idx, err == nil
+}
+
+// scanPath calls visit for each segment of path, unescaping quoted names. Only member
+// names and non-negative indices are accepted; anything else is an error.
+func scanPath(path string, visit func(segment string)) error {
+	original := path
+	if !strings.HasPrefix(path, "$") {
+		return NewError(ErrInvalidPath, "path must start with '$'", original, 0)
+	}
+	path = path[1:]
+
+	pos := 0
+	fail := func(msg string) error {
+		return NewError(ErrInvalidPath, msg, original, pos+1)
+	}
+
+	for pos < len(path) {
+		switch path[pos] {
+		case '~', '^':
+			return fail(fmt.Sprintf("'%c' cannot be converted to a JSON Pointer", path[pos]))
+		case '.':
+			end := pos + 1
+			for end < len(path) && !strings.ContainsRune(".[~^", rune(path[end])) {
+				end++
+			}
+			if end == pos+1 {
+				return fail("expected a member name after '.'")
+			}
+			visit(path[pos+1 : end])
+			pos = end
+		case '[':
+			if pos+1 < len(path) && (path[pos+1] == '\'' || path[pos+1] == '"') {
+				name, end, ok := unquotePathName(path, pos+1)
+				if !ok || end >= len(path) || path[end] != ']' {
+					return fail("unterminated quoted name")
+				}
+				visit(name)
+				pos = end + 1
+				break
+			}
+			end := strings.IndexByte(path[pos:], ']')
+			if end == -1 {
+				return fail("unterminated '['")
+			}
+			content := path[pos+1 : pos+end]
+			if _, ok := pointerIndex(content); !ok {
+				return fail("only names and non-negative indices can be converted to a JSON Pointer")
+			}
+			visit(content)
+			pos += end + 1
+		default:
+			return fail(fmt.Sprintf("unexpected character %q", path[pos]))
+		}
+	}
+	return nil
+}
+
+// unquotePathName reads the quoted name starting at path[start] and returns it
+// unescaped, along with the position just after the closing quote
+func unquotePathName(path string, start int) (string, int, bool) {
+	quote := path[start]
+	var sb strings.Builder
+	for i := start + 1; i < len(path); i++ {
+		c := path[i]
+		if c == quote {
+			return sb.String(), i + 1, true
+		}
+		if c != '\\' {
+			sb.WriteByte(c)
+			continue
+		}
+
+		i++
+		if i >= len(path) {
+			return "", 0, false
+		}
+		switch path[i] {
+		case 'b':
+			sb.WriteByte('\b')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 't':
+			sb.WriteByte('\t')
+		case '\'', '"', '\\', '/':
+			sb.WriteByte(path[i])
+		case 'u':
+			r, next, ok := unquoteUnicode(path, i+1)
+			if !ok {
+				return "", 0, false
+			}
+			sb.WriteRune(r)
+			i = next - 1
+		default:
+			return "", 0, false
+		}
+	}
+	return "", 0, false
+}
+
+// unquoteUnicode reads the hex digits of a \u escape starting at path[start],
+// joining a following \u escape when the two form a surrogate pair
+func unquoteUnicode(path string, start int) (rune, int, bool) {
+	if start+4 > len(path) {
+		return 0, 0, false
+	}
+	code, err := strconv.ParseUint(path[start:start+4], 16, 32)
+	if err != nil {
+		return 0, 0, false
+	}
+	r := rune(code)
+	next := start + 4
+	if utf16.IsSurrogate(r) && strings.HasPrefix(path[next:], `\u`) && next+6 <= len(path) {
+		if low, err := strconv.ParseUint(path[next+2:next+6], 16, 32); err == nil {
+			if pair := utf16.DecodeRune(r, rune(low)); pair != utf8.RuneError {
+				return pair, next + 6, true
+			}
+		}
+	}
+	return r, next, true
+}
+
+// toPathString renders segments in JSONPath-Plus bracket notation
+func toPathString(segments []string) string {
+	var sb strings.Builder
+	sb.WriteString("$")
+	for _, segment := range segments {
+		if isNumericSegment(segment) {
+			sb.WriteString("[" + segment + "]")
+		} else {
+			sb.WriteString("['" + escapePathName(segment) + "']")
+		}
+	}
+	return sb.String()
+}
+
+// escapePathName escapes a member name for use between single quotes
+func escapePathName(name string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(name, `\`, `\\`), `'`, `\'`)
+}
+
+// toPointer renders segments as an RFC 6901 JSON Pointer
+func toPointer(segments []string) string {
+	var sb strings.Builder
+	for _, segment := range segments {
+		segment = strings.ReplaceAll(segment, "~", "~0")
+		segment = strings.ReplaceAll(segment, "/", "~1")
+		sb.WriteString("/" + segment)
+	}
+	return sb.String()
+}
+
+// isNumericSegment reports whether JSONPath-Plus would render the segment without quotes
+func isNumericSegment(segment string) bool {
+	if segment == "" {
+		return false
+	}
+	for _, c := range segment {
+		if (c < '0' || c > '9') && c != '*' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,168 @@
+package jsonpathplus
+
+import (
+	"testing"
+)
+
+func TestJSONPointer(t *testing.T) {
+	jsonStr := `{"store":{"book":[{"title":"A"},{"title":"B"}]},"a/b":{"m~n":1},"it's":{"c\\d":2}}`
+
+	t.Run("ResultPointer", func(t *testing.T) {
+		tests := []struct {
+			path     string
+			expected []string
+		}{
+			{"$.store.book[*].title", []string{"/store/book/0/title", "/store/book/1/title"}},
+			{"$['a/b']['m~n']", []string{"/a~1b/m~0n"}},
+			{"$..*", []string{"/store", "/a~1b", "/it's", "/store/book", "/store/book/0", "/store/book/1",
+				"/store/book/0/title", "/store/book/1/title", "/a~1b/m~0n", "/it's/c\\d"}},
+			{"$", []string{""}},
+		}
+
+		for _, test := range tests {
+			results, err := Query(test.path, jsonStr)
+			if err != nil {
+				t.Fatalf("Query %s failed: %v", test.path, err)
+			}
+			if len(results) != len(test.expected) {
+				t.Fatalf("Query %s: expected %d results, got %d", test.path, len(test.expected), len(results))
+			}
+			for i, result := range results {
+				if result.Pointer != test.expected[i] {
+					t.Errorf("Query %s result %d (%s): expected pointer %q, got %q",
+						test.path, i, result.Path, test.expected[i], result.Pointer)
+				}
+			}
+		}
+	})
+
+	t.Run("NumericLikeKeys", func(t *testing.T) {
+		doc := `{"01":{"1":"a"},"1":"b","x":[{"+1":true}]}`
+		tests := []struct {
+			path    string
+			pointer string
+		}{
+			{"$['01']['1']", "/01/1"},
+			{"$['1']", "/1"},
+			{"$.x[0]['+1']", "/x/0/+1"},
+			{"$['01']^", ""},
+		}
+		for _, test := range tests {
+			results, err := QueryWithOptions(test.path, doc, &Options{})
+			if err != nil || len(results) != 1 {
+				t.Fatalf("Query %s: expected 1 result, got %v, %v", test.path, results, err)
+			}
+			if results[0].Pointer != test.pointer {
+				t.Errorf("Query %s: expected pointer %q, got %q", test.path, test.pointer, results[0].Pointer)
+			}
+			shaped, err := ShapeResults(results, ResultTypePointer)
+			if err != nil || shaped[0] != test.pointer {
+				t.Errorf("Query %s: expected shaped pointer %q, got %v, %v", test.path, test.pointer, shaped, err)
+			}
+		}
+	})
+
+	t.Run("QuotedKeyPath", func(t *testing.T) {
+		results, err := Query("$..*", jsonStr)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		if path := results[len(results)-1].Path; path != `$['it\'s']['c\\d']` {
+			t.Errorf("Expected escaped path $['it\\'s']['c\\\\d'], got %s", path)
+		}
+	})
+
+	t.Run("PathRoundTrip", func(t *testing.T) {
+		// Every result path, escapes included, selects its own result again
+		doc := `{"it's":{"c\\d":2},"x]y":{"q\"":3,"e\\'":4}}`
+		results, err := Query("$..*", doc)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		for _, result := range results {
+			again, err := Query(result.Path, doc)
+			if err != nil || len(again) != 1 || again[0].Path != result.Path {
+				t.Errorf("Query %s: expected its own result, got %v, %v", result.Path, again, err)
+			}
+		}
+	})
+
+	t.Run("Conversions", func(t *testing.T) {
+		tests := []struct {
+			path    string
+			pointer string
+		}{
+			{"$", ""},
+			{"$['store']['book'][0]", "/store/book/0"},
+			{"$['a/b']['m~n']", "/a~1b/m~0n"},
+			{`$['it\'s']['c\\d']`, `/it's/c\d`},
+			{"$['']", "/"},
+		}
+
+		for _, test := range tests {
+			pointer, err := PathToPointer(test.path)
+			if err != nil {
+				t.Errorf("PathToPointer(%s) failed: %v", test.path, err)
+			} else if pointer != test.pointer {
+				t.Errorf("PathToPointer(%s) = %q, expected %q", test.path, pointer, test.pointer)
+			}
+
+			path, err := PointerToPath(test.pointer)
+			if err != nil {
+				t.Errorf("PointerToPath(%q) failed: %v", test.pointer, err)
+			} else if path != test.path {
+				t.Errorf("PointerToPath(%q) = %s, expected %s", test.pointer, path, test.path)
+			}
+		}
+
+		// Dot notation and double quotes are accepted on input
+		if pointer, err := PathToPointer(`$.store["book"][1].title`); err != nil || pointer != "/store/book/1/title" {
+			t.Errorf("Expected /store/book/1/title, got %q, %v", pointer, err)
+		}
+
+		for _, path := range []string{"store", "$.store.book[*]", "$..title", "$.store.book[-1]", "$['a", "$.a~"} {
+			if _, err := PathToPointer(path); err == nil {
+				t.Errorf("PathToPointer(%s): expected error", path)
+			}
+		}
+		for _, pointer := range []string{"store", "/a~2", "/a~"} {
+			if _, err := PointerToPath(pointer); err == nil {
+				t.Errorf("PointerToPath(%q): expected error", pointer)
+			}
+		}
+	})
+
+	t.Run("ResolvePointer", func(t *testing.T) {
+		data, err := JSONParse(jsonStr)
+		if err != nil {
+			t.Fatalf("JSONParse failed: %v", err)
+		}
+
+		tests := []struct {
+			pointer  string
+			expected interface{}
+		}{
+			{"/store/book/1/title", "B"},
+			{"/a~1b/m~0n", float64(1)},
+			{`/it's/c\d`, float64(2)},
+		}
+		for _, test := range tests {
+			value, err := ResolvePointer(data, test.pointer)
+			if err != nil {
+				t.Errorf("ResolvePointer(%q) failed: %v", test.pointer, err)
+			} else if value != test.expected {
+				t.Errorf("ResolvePointer(%q) = %v, expected %v", test.pointer, value, test.expected)
+			}
+		}
+
+		if value, err := ResolvePointer(data, ""); err != nil || value != data {
+			t.Errorf("Expected the empty pointer to resolve to the document, got %v, %v", value, err)
+		}
+
+		for _, pointer := range []string{"/missing", "/store/book/2", "/store/book/01", "/store/book/-", "/a~1b/m~0n/x"} {
+			if _, err := ResolvePointer(data, pointer); err == nil {
+				t.Errorf("ResolvePointer(%q): expected error", pointer)
+			}
+		}
+	})
+}
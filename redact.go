@@ -0,0 +1,104 @@
+package jsonpathplus
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"unicode/utf8"
+)
+
+// DefaultMaskChar replaces hidden bytes when RedactOptions.MaskChar is zero
+const DefaultMaskChar = '*'
+
+// Range is a half-open byte range [Start, End) of a JSON string
+type Range struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Len returns the number of bytes covered by the range
+func (r Range) Len() int {
+	return r.End - r.Start
+}
+
+// RedactOptions configures Redact
+type RedactOptions struct {
+	MaskChar   byte // ASCII byte that replaces every hidden byte; zero means DefaultMaskChar
+	RevealKeys bool // Also reveal the quoted key of matched object members
+}
+
+// Redact returns jsonStr with every byte outside the value spans matched by paths
+// replaced by the mask character, so the result has the same length as the input.
+// It also returns the revealed ranges, sorted and merged.
+func Redact(jsonStr string, paths []string, opts RedactOptions) (string, []Range, error) {
+	return defaultEngine.Redact(jsonStr, paths, opts)
+}
+
+// Redact masks jsonStr outside the value spans matched by paths using the engine
+func (engine *JSONPathEngine) Redact(jsonStr string, paths []string, opts RedactOptions) (string, []Range, error) {
+	mask := opts.MaskChar
+	if mask == 0 {
+		mask = DefaultMaskChar
+	}
+	if mask >= utf8.RuneSelf {
+		return "", nil, NewError(ErrTypeError, fmt.Sprintf("mask character %q is not ASCII", mask), "", -1)
+	}
+
+	doc, err := parseDocument(jsonStr)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var ranges []Range
+	for _, path := range paths {
+		results, err := engine.queryDocument(context.Background(), path, doc, &Options{})
+		if err != nil {
+			return "", nil, err
+		}
+		for _, result := range results {
+			if result.ValueSpan == nil {
+				return "", nil, NewError(ErrEvaluationError, "no position recorded for result "+result.Path, path, -1)
+			}
+			ranges = append(ranges, Range{Start: result.ValueSpan.Start, End: result.ValueSpan.End})
+			if opts.RevealKeys && result.KeySpan != nil {
+				ranges = append(ranges, Range{Start: result.KeySpan.Start, End: result.KeySpan.End})
+			}
+		}
+	}
+	ranges = mergeRanges(ranges)
+
+	redacted := make([]byte, len(jsonStr))
+	for i := range redacted {
+		redacted[i] = mask
+	}
+	for _, r := range ranges {
+		copy(redacted[r.Start:r.End], jsonStr[r.Start:r.End])
+	}
+	return string(redacted), ranges, nil
+}
+
+// mergeRanges sorts ranges and joins those that overlap or touch
+func mergeRanges(ranges []Range) []Range {
+	if len(ranges) == 0 {
+		return nil
+	}
+	sort.Slice(ranges, func(i, j int) bool {
+		if ranges[i].Start != ranges[j].Start {
+			return ranges[i].Start < ranges[j].Start
+		}
+		return ranges[i].End < ranges[j].End
+	})
+
+	merged := []Range{ranges[0]}
+	for _, r := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if r.Start <= last.End {
+			if r.End > last.End {
+				last.End = r.End
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
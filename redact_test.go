@@ -0,0 +1,87 @@
+package jsonpathplus
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	jsonStr := `{"user":{"name":"José","token":"s3cr3t"},"items":[1,2,3]}`
+
+	tests := []struct {
+		name     string
+		paths    []string
+		opts     RedactOptions
+		expected string
+		ranges   []Range
+	}{
+		{
+			name:     "SingleValue",
+			paths:    []string{"$.user.name"},
+			expected: `****************"José"***********************************`,
+			ranges:   []Range{{Start: 16, End: 23}},
+		},
+		{
+			name:     "OverlappingRangesMerge",
+			paths:    []string{"$.items[1]", "$.items[0]", "$.items"},
+			expected: `**************************************************[1,2,3]*`,
+			ranges:   []Range{{Start: 50, End: 57}},
+		},
+		{
+			name:     "RevealKeys",
+			paths:    []string{"$.user.name"},
+			opts:     RedactOptions{RevealKeys: true, MaskChar: '#'},
+			expected: `#########"name"#"José"###################################`,
+			ranges:   []Range{{Start: 9, End: 15}, {Start: 16, End: 23}},
+		},
+		{
+			name:     "NoMatches",
+			paths:    []string{"$.missing"},
+			expected: `**********************************************************`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			redacted, ranges, err := Redact(jsonStr, test.paths, test.opts)
+			if err != nil {
+				t.Fatalf("Redact failed: %v", err)
+			}
+			if len(redacted) != len(jsonStr) {
+				t.Errorf("Expected length %d, got %d", len(jsonStr), len(redacted))
+			}
+			if redacted != test.expected {
+				t.Errorf("Expected %s, got %s", test.expected, redacted)
+			}
+			if !reflect.DeepEqual(ranges, test.ranges) {
+				t.Errorf("Expected ranges %v, got %v", test.ranges, ranges)
+			}
+		})
+	}
+
+	t.Run("NumericLikeKeys", func(t *testing.T) {
+		// "01" and "1" read as the same number; only the selected member may show
+		redacted, ranges, err := Redact(`{"01":"a","1":"b"}`, []string{"$['01']"}, RedactOptions{})
+		if err != nil {
+			t.Fatalf("Redact failed: %v", err)
+		}
+		if expected := `******"a"*********`; redacted != expected {
+			t.Errorf("Expected %s, got %s", expected, redacted)
+		}
+		if expected := []Range{{Start: 6, End: 9}}; !reflect.DeepEqual(ranges, expected) {
+			t.Errorf("Expected ranges %v, got %v", expected, ranges)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		if _, _, err := Redact(jsonStr, []string{"$.user[?(@.name ==)]"}, RedactOptions{}); err == nil {
+			t.Error("Expected error for invalid path")
+		}
+		if _, _, err := Redact(`{"a":`, []string{"$.a"}, RedactOptions{}); err == nil {
+			t.Error("Expected error for invalid JSON")
+		}
+		if _, _, err := Redact(jsonStr, []string{"$.user"}, RedactOptions{MaskChar: 0xE9}); err == nil {
+			t.Error("Expected error for non-ASCII mask character")
+		}
+	})
+}
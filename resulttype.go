@@ -0,0 +1,146 @@
+package jsonpathplus
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/reclaimprotocol/jsonpathplus-go/pkg/types"
+)
+
+// ResultType selects how each match is shaped (alias for types.ResultType)
+type ResultType = types.ResultType
+
+// Result types, matching the JSONPath-Plus resultType option
+const (
+	ResultTypeValue          = types.ResultTypeValue
+	ResultTypePath           = types.ResultTypePath
+	ResultTypePointer        = types.ResultTypePointer
+	ResultTypeParent         = types.ResultTypeParent
+	ResultTypeParentProperty = types.ResultTypeParentProperty
+	ResultTypeAll            = types.ResultTypeAll
+)
+
+// ResultRecord is the shape returned for ResultTypeAll, mirroring the JSONPath-Plus record
+type ResultRecord struct {
+	Path           string      `json:"path"`
+	Value          interface{} `json:"value"`
+	Parent         interface{} `json:"parent"`
+	ParentProperty interface{} `json:"parentProperty"`
+	Pointer        string      `json:"pointer"`
+}
+
+// Evaluate executes the JSONPath and shapes every match according to options.ResultType.
+// It is the counterpart of ExecuteWithOptions that returns what JSONPath-Plus returns
+// for the same path, json and resultType.
+func (jp *JSONPath) Evaluate(data interface{}, options *Options) ([]interface{}, error) {
+	if options == nil {
+		options = &Options{}
+	}
+	if err := validateResultType(options.ResultType, jp.path); err != nil {
+		return nil, err
+	}
+
+	results, err := jp.execute(context.Background(), data, options)
+	if err != nil {
+		return nil, err
+	}
+	return ShapeResults(results, options.ResultType)
+}
+
+// Evaluate executes a JSONPath query against JSON string or data and shapes the matches
+// according to options.ResultType
+func Evaluate(path string, input interface{}, options *Options) ([]interface{}, error) {
+	return defaultEngine.Evaluate(path, input, options)
+}
+
+// Evaluate executes a JSONPath query using the engine and shapes the matches
+// according to options.ResultType
+func (engine *JSONPathEngine) Evaluate(path string, input interface{}, options *Options) ([]interface{}, error) {
+	data := input
+	if jsonStr, ok := input.(string); ok {
+		parsed, err := JSONParse(jsonStr)
+		if err != nil {
+			return nil, err
+		}
+		data = parsed
+	}
+
+	jp, err := engine.compileFor(path, options)
+	if err != nil {
+		return nil, err
+	}
+	return jp.Evaluate(data, options)
+}
+
+// ShapeResults converts raw results into the shape selected by resultType.
+// An empty resultType behaves like ResultTypeValue.
+func ShapeResults(results []Result, resultType ResultType) ([]interface{}, error) {
+	if err := validateResultType(resultType, ""); err != nil {
+		return nil, err
+	}
+
+	shaped := make([]interface{}, len(results))
+	for i, result := range results {
+		shaped[i] = shapeResult(result, resultType)
+	}
+	return shaped, nil
+}
+
+// validateResultType rejects result types JSONPath-Plus does not know
+func validateResultType(resultType ResultType, path string) error {
+	switch resultType {
+	case "", ResultTypeValue, ResultTypePath, ResultTypePointer,
+		ResultTypeParent, ResultTypeParentProperty, ResultTypeAll:
+		return nil
+	default:
+		return NewError(ErrTypeError, "unknown result type: "+string(resultType), path, -1)
+	}
+}
+
+func shapeResult(result Result, resultType ResultType) interface{} {
+	switch resultType {
+	case ResultTypePath:
+		return resultPath(result)
+	case ResultTypePointer:
+		return result.Pointer
+	case ResultTypeParent:
+		return result.Parent
+	case ResultTypeParentProperty:
+		return parentPropertyValue(result)
+	case ResultTypeAll:
+		return ResultRecord{
+			Path:           resultPath(result),
+			Value:          result.Value,
+			Parent:         result.Parent,
+			ParentProperty: parentPropertyValue(result),
+			Pointer:        result.Pointer,
+		}
+	default:
+		return result.Value
+	}
+}
+
+// resultPath renders the bracket path of a match from the member names and indices
+// recorded in its pointer. As in JSONPath-Plus, property name results are reported
+// at the member whose name they are.
+func resultPath(result Result) string {
+	segments, err := pointerSegments(result.Pointer)
+	if err != nil {
+		return result.Path
+	}
+	return toPathString(segments)
+}
+
+// parentPropertyValue returns the parent property as JSONPath-Plus reports it:
+// an int for array indices, a string for object keys and nil for the root
+func parentPropertyValue(result Result) interface{} {
+	if result.Parent == nil && result.ParentProperty == "" {
+		return nil
+	}
+	if _, isArray := result.Parent.([]interface{}); isArray {
+		if idx, err := strconv.Atoi(result.ParentProperty); err == nil {
+			return idx
+		}
+	}
+	return result.ParentProperty
+}
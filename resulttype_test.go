@@ -0,0 +1,111 @@
+package jsonpathplus
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// TestEvaluateResultType tests that each result type matches JSONPath-Plus output
+func TestEvaluateResultType(t *testing.T) {
+	jsonStr := `{"store":{"book":[{"title":"A","price":8},{"title":"B","price":12}],"a/b":{"c~d":1,"it's":2}}}`
+
+	tests := []struct {
+		name       string
+		path       string
+		resultType ResultType
+		expected   []interface{}
+	}{
+		{"PathWildcard", "$.store.book[*].title", ResultTypePath,
+			[]interface{}{"$['store']['book'][0]['title']", "$['store']['book'][1]['title']"}},
+		{"PathFilter", "$..book[?(@.price > 10)]", ResultTypePath,
+			[]interface{}{"$['store']['book'][1]"}},
+		{"PathRoot", "$", ResultTypePath, []interface{}{"$"}},
+		{"PathEscaped", "$.store['a/b'].*", ResultTypePath,
+			[]interface{}{"$['store']['a/b']['c~d']", `$['store']['a/b']['it\'s']`}},
+		{"PathPropertyName", "$.store['a/b'].*~", ResultTypePath,
+			[]interface{}{"$['store']['a/b']['c~d']", `$['store']['a/b']['it\'s']`}},
+		{"PointerWildcard", "$.store.book[*].title", ResultTypePointer,
+			[]interface{}{"/store/book/0/title", "/store/book/1/title"}},
+		{"PointerEscaped", "$.store['a/b']['c~d']", ResultTypePointer,
+			[]interface{}{"/store/a~1b/c~0d"}},
+		{"PointerRoot", "$", ResultTypePointer, []interface{}{""}},
+		{"ParentPropertyKey", "$..price", ResultTypeParentProperty,
+			[]interface{}{"price", "price"}},
+		{"ParentPropertyIndex", "$..book[?(@.price > 10)]", ResultTypeParentProperty,
+			[]interface{}{1}},
+		{"ParentPropertyRoot", "$", ResultTypeParentProperty, []interface{}{nil}},
+		{"Value", "$.store.book[1].price", ResultTypeValue, []interface{}{float64(12)}},
+		{"Default", "$.store.book[0].title", "", []interface{}{"A"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Evaluate(tt.path, jsonStr, &Options{ResultType: tt.resultType})
+			if err != nil {
+				t.Fatalf("Evaluate failed: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %#v, got %#v", tt.expected, got)
+			}
+		})
+	}
+
+	t.Run("Parent", func(t *testing.T) {
+		got, err := Evaluate("$..book[?(@.price > 10)]", jsonStr, &Options{ResultType: ResultTypeParent})
+		if err != nil {
+			t.Fatalf("Evaluate failed: %v", err)
+		}
+		if len(got) != 1 {
+			t.Fatalf("Expected 1 result, got %d", len(got))
+		}
+		if books, ok := got[0].([]interface{}); !ok || len(books) != 2 {
+			t.Errorf("Expected the book array as parent, got %#v", got[0])
+		}
+	})
+
+	t.Run("All", func(t *testing.T) {
+		got, err := Evaluate("$.store.book[*]", jsonStr, &Options{ResultType: ResultTypeAll})
+		if err != nil {
+			t.Fatalf("Evaluate failed: %v", err)
+		}
+		if len(got) != 2 {
+			t.Fatalf("Expected 2 results, got %d", len(got))
+		}
+		record, ok := got[1].(ResultRecord)
+		if !ok {
+			t.Fatalf("Expected ResultRecord, got %T", got[1])
+		}
+		if record.Path != "$['store']['book'][1]" || record.Pointer != "/store/book/1" || record.ParentProperty != 1 {
+			t.Errorf("Unexpected record: %+v", record)
+		}
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		_, err := Evaluate("$", jsonStr, &Options{ResultType: "values"})
+		if err == nil {
+			t.Fatal("Expected error for unknown result type")
+		}
+	})
+
+	t.Run("Execute", func(t *testing.T) {
+		// Results carry every shape, so a result type is accepted but only Evaluate applies it
+		options := &Options{ResultType: ResultTypePath}
+		results, err := QueryWithOptions("$.store.book[0]", jsonStr, options)
+		if err != nil || len(results) != 1 || results[0].Pointer != "/store/book/0" {
+			t.Errorf("Expected QueryWithOptions to return the full result, got %+v, %v", results, err)
+		}
+		jp, err := New("$.store.book[0]")
+		if err != nil {
+			t.Fatalf("New failed: %v", err)
+		}
+		data, _ := JSONParse(jsonStr)
+		if _, err := jp.ExecuteWithOptions(data, &Options{ResultType: "values"}); !errors.Is(err, &JSONPathError{Type: ErrTypeError}) {
+			t.Errorf("Expected ExecuteWithOptions to reject an unknown result type, got %v", err)
+		}
+		shaped, err := jp.Evaluate(data, options)
+		if err != nil || !reflect.DeepEqual(shaped, []interface{}{"$['store']['book'][0]"}) {
+			t.Errorf("Expected Evaluate to shape paths, got %v, %v", shaped, err)
+		}
+	})
+}
@@ -1,38 +1,79 @@
 package jsonpathplus
 
 import (
+	"errors"
 	"fmt"
 	"regexp"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/reclaimprotocol/jsonpathplus-go/internal/filters"
+	"github.com/reclaimprotocol/jsonpathplus-go/internal/parser"
+	"github.com/reclaimprotocol/jsonpathplus-go/internal/rfc9535"
+	"github.com/reclaimprotocol/jsonpathplus-go/pkg/types"
 )
 
 // Security constants
 const (
 	DefaultMaxPathComplexity = 50
 	DefaultMaxExecutionTime  = 5 * time.Second
-	ComplexityPerChar        = 10
-	ComplexityRecursive      = 3
-	ComplexityFilter         = 4
-	MaxRecursionDepthLimit   = 10
+	// Deprecated: complexity is scored per parsed node, see SecurityConfig.NodeCosts.
+	ComplexityPerChar      = 10
+	ComplexityRecursive    = 3
+	ComplexityFilter       = 4
+	MaxRecursionDepthLimit = 10
 )
 
+// DefaultNodeCosts returns the complexity cost of each kind of node. Path nodes are
+// keyed by their AST type, such as "property" or "recursive". Every node of a filter
+// or script expression costs "expression", plus "call" for function and method calls,
+// "filter" for nested filters, "wildcard" for wildcards and "recursive" for descendants.
+func DefaultNodeCosts() map[string]int {
+	return map[string]int{
+		"root":           0,
+		"chain":          0,
+		"property":       1,
+		"index":          1,
+		"union":          1,
+		"property_names": 1,
+		"parent":         1,
+		"type_selector":  1,
+		"slice":          2,
+		"wildcard":       2,
+		"index_wildcard": 2,
+		"recursive":      ComplexityRecursive,
+		"filter":         ComplexityFilter,
+		"script":         ComplexityFilter,
+		"expression":     1,
+		"call":           2,
+	}
+}
+
+var defaultNodeCosts = DefaultNodeCosts()
+
 // SecurityConfig defines security-related configuration.
 type SecurityConfig struct {
 	// MaxPathComplexity limits the complexity of JSONPath expressions
 	MaxPathComplexity int
 
-	// AllowedFunctions lists allowed functions in filter expressions
+	// NodeCosts overrides the complexity cost of node kinds; kinds it does not list
+	// cost as in DefaultNodeCosts
+	NodeCosts map[string]int
+
+	// AllowedFunctions lists the functions and methods filter and script expressions may call
 	AllowedFunctions []string
 
-	// BlockedPatterns contains patterns that are not allowed
+	// BlockedPatterns are matched against each name, member name and call used in
+	// filter and script expressions, with a call of f matched as "f(". String and
+	// regex literals are data and are never matched.
 	BlockedPatterns []*regexp.Regexp
 
 	// MaxExecutionTime limits execution time per query
 	MaxExecutionTime time.Duration
 
-	// EnableSandbox enables sandboxed execution
+	// EnableSandbox allows queries to run with Options.EvalModeSandbox, making the
+	// values in Options.Sandbox reachable from expressions
 	EnableSandbox bool
 
 	// AllowNetworkAccess allows network access in expressions
@@ -44,25 +85,20 @@ type SecurityConfig struct {
 
 // DefaultSecurityConfig returns secure default configuration.
 func DefaultSecurityConfig() *SecurityConfig {
-	// Common dangerous patterns to block
+	// Calls and names that reach for code execution; URLs and file paths in
+	// literals are governed by AllowNetworkAccess and AllowFileAccess instead
 	blockedPatterns := []*regexp.Regexp{
 		regexp.MustCompile(`eval\s*\(`),
 		regexp.MustCompile(`exec\s*\(`),
 		regexp.MustCompile(`system\s*\(`),
 		regexp.MustCompile(`require\s*\(`),
-		regexp.MustCompile(`import\s+`),
-		regexp.MustCompile(`__.*__`),      // Python dunder methods
-		regexp.MustCompile(`\$\{.*\}`),    // Template injection
-		regexp.MustCompile(`<!--.*-->`),   // Comment injection
-		regexp.MustCompile(`javascript:`), // JavaScript URLs
-		regexp.MustCompile(`data:`),       // Data URLs
-		regexp.MustCompile(`file:`),       // File URLs
-		regexp.MustCompile(`ftp:`),        // FTP URLs
+		regexp.MustCompile(`import\s*\(`),
+		regexp.MustCompile(`__.*__`), // Python dunder methods and __proto__
 	}
 
 	return &SecurityConfig{
 		MaxPathComplexity:  DefaultMaxPathComplexity,
-		AllowedFunctions:   []string{"length", "size", "keys", "values", "type"},
+		AllowedFunctions:   []string{"length", "size", "keys", "values", "type", "count", "match", "search", "value"},
 		BlockedPatterns:    blockedPatterns,
 		MaxExecutionTime:   DefaultMaxExecutionTime,
 		EnableSandbox:      true,
@@ -73,121 +109,309 @@ func DefaultSecurityConfig() *SecurityConfig {
 
 // SecurityValidator validates JSONPath expressions for security issues.
 type SecurityValidator struct {
-	config *SecurityConfig
+	config    *SecurityConfig
+	functions *rfc9535.Registry // Function extensions paths are parsed with; nil for the builtins
 }
 
 // NewSecurityValidator creates a new security validator.
 func NewSecurityValidator(config *SecurityConfig) *SecurityValidator {
+	return newSecurityValidator(config, nil)
+}
+
+// newSecurityValidator creates a validator that parses paths with the function
+// extensions of an engine, so that it checks the same tree the engine evaluates.
+func newSecurityValidator(config *SecurityConfig, functions *rfc9535.Registry) *SecurityValidator {
 	if config == nil {
 		config = DefaultSecurityConfig()
 	}
 
 	return &SecurityValidator{
-		config: config,
+		config:    config,
+		functions: functions,
 	}
 }
 
-// ValidatePath validates a JSONPath expression for security issues.
+// ValidatePath validates a JSONPath expression for security issues. The path is parsed,
+// and its complexity, function calls and blocked constructs are checked on the syntax
+// tree, so quoted keys and string literals are never mistaken for operators.
 func (v *SecurityValidator) ValidatePath(path string) error {
+	ast, err := v.parse(path)
+	if err != nil {
+		return err
+	}
+	return v.validate(ast, path)
+}
+
+// validate checks the complexity and the expressions of a parsed path.
+func (v *SecurityValidator) validate(ast *types.AstNode, path string) error {
 	// Check path complexity
-	complexity := v.calculateComplexity(path)
+	complexity := v.score(ast)
 	if complexity > v.config.MaxPathComplexity {
 		return NewError(ErrInvalidPath,
 			fmt.Sprintf("path complexity %d exceeds limit %d", complexity, v.config.MaxPathComplexity),
 			path, -1)
 	}
 
-	// Check for blocked patterns
-	for _, pattern := range v.config.BlockedPatterns {
-		if pattern.MatchString(path) {
-			return NewError(ErrInvalidExpression,
-				fmt.Sprintf("path contains blocked pattern: %s", pattern.String()),
-				path, -1)
+	// Validate filter and script expressions
+	return v.validateExpressions(ast, path)
+}
+
+// Complexity parses path and returns its complexity score: the sum of the costs of its
+// path and expression nodes, as configured by SecurityConfig.NodeCosts.
+func (v *SecurityValidator) Complexity(path string) (int, error) {
+	ast, err := v.parse(path)
+	if err != nil {
+		return 0, err
+	}
+	return v.score(ast), nil
+}
+
+// parse parses path with the JSONPath-Plus grammar and the validator's function
+// extensions, reporting failures as a *JSONPathError.
+func (v *SecurityValidator) parse(path string) (*types.AstNode, error) {
+	ast, err := parser.NewParserWithFunctions(v.functions).Parse(path)
+	if err != nil {
+		var syntaxErr *filters.SyntaxError
+		if errors.As(err, &syntaxErr) {
+			return nil, convertParseError(err, path)
 		}
+		return nil, WrapError(ErrInvalidPath, err, path, -1)
 	}
+	return ast, nil
+}
 
-	// Validate filter expressions
-	if err := v.validateFilterExpressions(path); err != nil {
-		return err
+// score sums the costs of node, of the expression it holds and of its children.
+func (v *SecurityValidator) score(node *types.AstNode) int {
+	total := v.nodeCost(node)
+	for _, child := range node.Children {
+		total += v.score(child)
 	}
+	return total
+}
 
-	return nil
+// nodeCost returns the cost of node itself, including its filter or script expression.
+func (v *SecurityValidator) nodeCost(node *types.AstNode) int {
+	cost := v.cost(node.Type)
+	if expr, ok := node.Expr.(filters.Node); ok {
+		filters.Walk(expr, func(n filters.Node) {
+			cost += v.expressionCost(n)
+		})
+	}
+	return cost
+}
+
+// expressionCost returns the cost of one node of a filter or script expression.
+func (v *SecurityValidator) expressionCost(node filters.Node) int {
+	cost := v.cost("expression")
+	switch node.(type) {
+	case *filters.Call, *filters.FunctionCall:
+		cost += v.cost("call")
+	case *filters.FilterSelector:
+		cost += v.cost("filter")
+	case *filters.Wildcard:
+		cost += v.cost("wildcard")
+	case *filters.Descendants:
+		cost += v.cost("recursive")
+	}
+	return cost
 }
 
-// calculateComplexity calculates the complexity score of a JSONPath expression.
-func (v *SecurityValidator) calculateComplexity(path string) int {
-	complexity := 0
-
-	// Base complexity
-	complexity += len(path) / ComplexityPerChar
-
-	// Operators add complexity
-	complexity += strings.Count(path, "..") * ComplexityRecursive // Recursive descent
-	complexity += strings.Count(path, "*") * 2                    // Wildcards
-	complexity += strings.Count(path, "?") * ComplexityFilter     // Filters
-	complexity += strings.Count(path, "[") * 1                    // Brackets
-	complexity += strings.Count(path, ",") * 1                    // Union
-
-	// Nested expressions add complexity
-	depth := 0
-	maxDepth := 0
-	for _, char := range path {
-		switch char {
-		case '[', '(':
-			depth++
-			if depth > maxDepth {
-				maxDepth = depth
+// cost returns the configured cost of a node kind, falling back to DefaultNodeCosts.
+func (v *SecurityValidator) cost(kind string) int {
+	if cost, ok := v.config.NodeCosts[kind]; ok {
+		return cost
+	}
+	return defaultNodeCosts[kind]
+}
+
+// validateExpressions validates every filter and script expression in the tree.
+func (v *SecurityValidator) validateExpressions(node *types.AstNode, path string) error {
+	if expr, ok := node.Expr.(filters.Node); ok {
+		var err error
+		filters.Walk(expr, func(n filters.Node) {
+			if err == nil {
+				err = v.validateConstruct(n, path)
 			}
-		case ']', ')':
-			depth--
+		})
+		if err != nil {
+			return err
 		}
 	}
-	complexity += maxDepth * 2
+	for _, child := range node.Children {
+		if err := v.validateExpressions(child, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	return complexity
+// validateConstruct validates a single node of a filter or script expression.
+func (v *SecurityValidator) validateConstruct(node filters.Node, path string) error {
+	var literal string
+	switch n := node.(type) {
+	case *filters.Literal:
+		literal, _ = n.Value.(string)
+	case *filters.RegexLiteral:
+		literal = n.Pattern
+	}
+	return v.checkConstruct(calledName(node), constructText(node), literal, path)
 }
 
-// validateFilterExpressions validates filter expressions in the path.
-func (v *SecurityValidator) validateFilterExpressions(path string) error {
-	// Find all filter expressions
-	filterPattern := regexp.MustCompile(`\?\([^)]+\)`)
-	filters := filterPattern.FindAllString(path, -1)
+// checkConstruct applies the policy to one node of an expression: the name of the
+// function it calls, the construct BlockedPatterns are matched against and the text
+// of its string or regex literal, each empty when the node has none.
+func (v *SecurityValidator) checkConstruct(called, construct, literal, path string) error {
+	// Check for function and method calls
+	if called != "" && !v.isFunctionAllowed(called) {
+		return NewError(ErrInvalidExpression,
+			fmt.Sprintf("function '%s' is not allowed", called),
+			path, -1)
+	}
 
-	for _, filter := range filters {
-		if err := v.validateSingleFilter(filter, path); err != nil {
-			return err
+	// Check for blocked patterns
+	if construct != "" {
+		for _, pattern := range v.config.BlockedPatterns {
+			if pattern.MatchString(construct) {
+				return NewError(ErrInvalidExpression,
+					fmt.Sprintf("path contains blocked pattern: %s", pattern.String()),
+					path, -1)
+			}
 		}
 	}
 
+	// Check for network/file access attempts in literals
+	return v.validateLiteral(strings.ToLower(literal), path)
+}
+
+// validateStandard checks the complexity and the filter expressions of a query parsed
+// with the RFC 9535 grammar, scoring its selectors and expression nodes with the same
+// costs as the nodes of a JSONPath-Plus path.
+func (v *SecurityValidator) validateStandard(query *rfc9535.Query, path string) error {
+	complexity := v.scoreStandard(query)
+	if complexity > v.config.MaxPathComplexity {
+		return NewError(ErrInvalidPath,
+			fmt.Sprintf("path complexity %d exceeds limit %d", complexity, v.config.MaxPathComplexity),
+			path, -1)
+	}
+
+	for _, segment := range query.Segments {
+		for _, selector := range segment.Selectors {
+			filter, ok := selector.(*rfc9535.FilterSelector)
+			if !ok {
+				continue
+			}
+			var err error
+			walkStandardExpr(filter.Expr, func(node interface{}) {
+				if err == nil {
+					err = v.validateStandardConstruct(node, path)
+				}
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
-// validateSingleFilter validates a single filter expression.
-func (v *SecurityValidator) validateSingleFilter(filter, path string) error {
-	// Remove the wrapper
-	filter = strings.TrimPrefix(filter, "?(")
-	filter = strings.TrimSuffix(filter, ")")
+// scoreStandard sums the costs of the segments and selectors of an RFC 9535 query.
+func (v *SecurityValidator) scoreStandard(query *rfc9535.Query) int {
+	total := v.cost("root")
+	for _, segment := range query.Segments {
+		if segment.Descendant {
+			total += v.cost("recursive")
+		}
+		for _, selector := range segment.Selectors {
+			total += v.selectorCost(selector)
+		}
+	}
+	return total
+}
 
-	// Check for function calls
-	functionPattern := regexp.MustCompile(`(\w+)\s*\(`)
-	functions := functionPattern.FindAllStringSubmatch(filter, -1)
+// selectorCost returns the cost of an RFC 9535 selector, including its filter expression.
+func (v *SecurityValidator) selectorCost(selector rfc9535.Selector) int {
+	cost := v.cost(standardStepKind(selector))
+	if filter, ok := selector.(*rfc9535.FilterSelector); ok {
+		walkStandardExpr(filter.Expr, func(node interface{}) {
+			cost += v.standardExpressionCost(node)
+		})
+	}
+	return cost
+}
 
-	for _, match := range functions {
-		if len(match) > 1 {
-			funcName := match[1]
-			if !v.isFunctionAllowed(funcName) {
-				return NewError(ErrInvalidExpression,
-					fmt.Sprintf("function '%s' is not allowed", funcName),
-					path, -1)
+// standardExpressionCost returns the cost of one node of an RFC 9535 filter expression:
+// an expression, or a segment or selector of a query embedded in it.
+func (v *SecurityValidator) standardExpressionCost(node interface{}) int {
+	switch n := node.(type) {
+	case *rfc9535.Segment:
+		if n.Descendant {
+			return v.cost("expression") + v.cost("recursive")
+		}
+		return 0
+	case *rfc9535.WildcardSelector:
+		return v.cost("expression") + v.cost("wildcard")
+	case *rfc9535.FilterSelector:
+		return v.cost("expression") + v.cost("filter")
+	case *rfc9535.FunctionExpr:
+		return v.cost("expression") + v.cost("call")
+	}
+	return v.cost("expression")
+}
+
+// validateStandardConstruct validates a single node of an RFC 9535 filter expression.
+func (v *SecurityValidator) validateStandardConstruct(node interface{}, path string) error {
+	switch n := node.(type) {
+	case *rfc9535.FunctionExpr:
+		return v.checkConstruct(n.Func.Name, n.Func.Name+"(", "", path)
+	case *rfc9535.NameSelector:
+		return v.checkConstruct("", n.Name, "", path)
+	case *rfc9535.LiteralExpr:
+		literal, _ := n.Value.(string)
+		return v.checkConstruct("", "", literal, path)
+	}
+	return nil
+}
+
+// walkStandardExpr calls visit for expr and every node below it, including the
+// segments and selectors of the queries embedded in it.
+func walkStandardExpr(expr rfc9535.Expr, visit func(node interface{})) {
+	visit(expr)
+	switch x := expr.(type) {
+	case *rfc9535.QueryExpr:
+		for _, segment := range x.Query.Segments {
+			visit(segment)
+			for _, selector := range segment.Selectors {
+				visit(selector)
+				if filter, ok := selector.(*rfc9535.FilterSelector); ok {
+					walkStandardExpr(filter.Expr, visit)
+				}
 			}
 		}
+	case *rfc9535.FunctionExpr:
+		for _, arg := range x.Args {
+			walkStandardExpr(arg, visit)
+		}
+	case *rfc9535.NotExpr:
+		walkStandardExpr(x.Operand, visit)
+	case *rfc9535.AndExpr:
+		walkStandardExpr(x.Left, visit)
+		walkStandardExpr(x.Right, visit)
+	case *rfc9535.OrExpr:
+		walkStandardExpr(x.Left, visit)
+		walkStandardExpr(x.Right, visit)
+	case *rfc9535.ComparisonExpr:
+		walkStandardExpr(x.Left, visit)
+		walkStandardExpr(x.Right, visit)
 	}
+}
 
-	// Check for network/file access attempts
+// validateLiteral rejects string and regex literals that reach for the network or
+// the file system.
+func (v *SecurityValidator) validateLiteral(literal, path string) error {
 	if !v.config.AllowNetworkAccess {
 		networkPatterns := []string{"http://", "https://", "ws://", "wss://"}
 		for _, pattern := range networkPatterns {
-			if strings.Contains(strings.ToLower(filter), pattern) {
+			if strings.Contains(literal, pattern) {
 				return NewError(ErrInvalidExpression,
 					"network access not allowed in filter expressions",
 					path, -1)
@@ -198,7 +422,7 @@ func (v *SecurityValidator) validateSingleFilter(filter, path string) error {
 	if !v.config.AllowFileAccess {
 		filePatterns := []string{"file://", "../", "./", "/etc/", "/var/", "c:\\", "\\\\"}
 		for _, pattern := range filePatterns {
-			if strings.Contains(strings.ToLower(filter), pattern) {
+			if strings.Contains(literal, pattern) {
 				return NewError(ErrInvalidExpression,
 					"file access not allowed in filter expressions",
 					path, -1)
@@ -209,6 +433,45 @@ func (v *SecurityValidator) validateSingleFilter(filter, path string) error {
 	return nil
 }
 
+// calledName returns the name of the function or method a call node invokes.
+func calledName(node filters.Node) string {
+	switch n := node.(type) {
+	case *filters.FunctionCall:
+		return n.Func.Name
+	case *filters.Call:
+		switch callee := n.Callee.(type) {
+		case *filters.Identifier:
+			return callee.Name
+		case *filters.Member:
+			return callee.Property
+		}
+		return n.Callee.String()
+	}
+	return ""
+}
+
+// constructText renders the code constructs BlockedPatterns are matched against: names,
+// member names and calls, written name(. Literals are data and are not rendered.
+func constructText(node filters.Node) string {
+	switch n := node.(type) {
+	case *filters.Identifier:
+		return n.Name
+	case *filters.ContextVar:
+		return n.Name
+	case *filters.Member:
+		return n.Property
+	case *filters.Index:
+		if key, ok := n.Index.(*filters.Literal); ok {
+			if name, ok := key.Value.(string); ok {
+				return name
+			}
+		}
+	case *filters.Call, *filters.FunctionCall:
+		return calledName(n) + "("
+	}
+	return ""
+}
+
 // isFunctionAllowed checks if a function is in the allowed list.
 func (v *SecurityValidator) isFunctionAllowed(funcName string) bool {
 	for _, allowed := range v.config.AllowedFunctions {
@@ -0,0 +1,107 @@
+package jsonpathplus
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSecurityValidator(t *testing.T) {
+	validator := NewSecurityValidator(DefaultSecurityConfig())
+
+	t.Run("Complexity", func(t *testing.T) {
+		tests := []struct {
+			path     string
+			expected int
+		}{
+			{"$.store.book", 2},
+			{"$['a..b*?[,]']", 1},
+			{"$..book[*]", 6},
+			{"$.book[?(@.price < 10)]", 9},
+			{"$.book[?(length(@.title) > 10)]", 12},
+			{"$.book[(@.length-1)]", 9},
+		}
+		for _, test := range tests {
+			complexity, err := validator.Complexity(test.path)
+			if err != nil {
+				t.Fatalf("Complexity %s failed: %v", test.path, err)
+			}
+			if complexity != test.expected {
+				t.Errorf("Complexity %s: expected %d, got %d", test.path, test.expected, complexity)
+			}
+		}
+
+		config := DefaultSecurityConfig()
+		config.NodeCosts = map[string]int{"recursive": 40, "index_wildcard": 0}
+		if complexity, _ := NewSecurityValidator(config).Complexity("$..book[*]"); complexity != 41 {
+			t.Errorf("Expected NodeCosts to override the defaults, got complexity %d", complexity)
+		}
+		if err := NewSecurityValidator(config).ValidatePath("$..a..b"); !errors.Is(err, &JSONPathError{Type: ErrInvalidPath}) {
+			t.Errorf("Expected ErrInvalidPath for a path over the complexity limit, got %v", err)
+		}
+	})
+
+	t.Run("Allowed", func(t *testing.T) {
+		paths := []string{
+			"$['a(b)'].c",
+			"$['eval('].x",
+			"$.users[?((@.age > 21) && (@.name == 'x(y)'))]",
+			"$.users[?(@.note == 'eval(x) and __init__')]",
+			"$.users[?(count(@.tags[*]) > 1 && match(@.id, '[a-z]+'))]",
+			"$..*[?(@string())]",
+		}
+		for _, path := range paths {
+			if err := validator.ValidatePath(path); err != nil {
+				t.Errorf("ValidatePath %s: expected no error, got %v", path, err)
+			}
+		}
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		paths := []string{
+			"$.users[?(eval('x'))]",
+			"$.users[?((@.a) && system(@.b))]",
+			"$.users[?(@.name.startsWith('a'))]",
+			"$.users[?(@.__proto__)]",
+			"$.users[?(@['__proto__'])]",
+			"$.users[?(@.url == 'https://example.com')]",
+			"$.users[?(@.file == '../secret')]",
+			"$.users[?(@.a[?(@.b == 'http://x')])]",
+			"$.users[(exec(@))]",
+			"$.users[?(@.a >)]",
+		}
+		for _, path := range paths {
+			if err := validator.ValidatePath(path); !errors.Is(err, &JSONPathError{Type: ErrInvalidExpression}) {
+				t.Errorf("ValidatePath %s: expected ErrInvalidExpression, got %v", path, err)
+			}
+		}
+
+		if err := validator.ValidatePath("users"); !errors.Is(err, &JSONPathError{Type: ErrInvalidPath}) {
+			t.Errorf("Expected ErrInvalidPath for an unparseable path, got %v", err)
+		}
+	})
+
+	t.Run("BlockedPatterns", func(t *testing.T) {
+		// Allowing the calls shows that each default pattern blocks them on its own
+		config := DefaultSecurityConfig()
+		config.AllowedFunctions = append(config.AllowedFunctions, "eval", "exec", "system", "require", "import")
+		permissive := NewSecurityValidator(config)
+		paths := []string{
+			"$.users[?(eval('x'))]",
+			"$.users[?(exec(@.cmd))]",
+			"$.users[?(@.a && system(@.b))]",
+			"$.users[(require('fs'))]",
+			"$.users[?(import(@.module))]",
+			"$.users[?(@.constructor.__proto__)]",
+		}
+		if len(config.BlockedPatterns) != len(paths) {
+			t.Fatalf("Expected a path for each of the %d default patterns", len(config.BlockedPatterns))
+		}
+		for i, pattern := range config.BlockedPatterns {
+			err := permissive.ValidatePath(paths[i])
+			if !errors.Is(err, &JSONPathError{Type: ErrInvalidExpression}) || !strings.Contains(err.Error(), pattern.String()) {
+				t.Errorf("ValidatePath %s: expected pattern %s to block it, got %v", paths[i], pattern, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package jsonpathplus
+
+import (
+	"context"
+	"log/slog"
+)
+
+// SlogLogger adapts a *slog.Logger to the Logger interface.
+type SlogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger creates a Logger that writes to logger, or to slog.Default() when
+// logger is nil. Fields become slog attributes.
+func NewSlogLogger(logger *slog.Logger) *SlogLogger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &SlogLogger{logger: logger}
+}
+
+// Debug logs a debug message with optional fields.
+func (l *SlogLogger) Debug(msg string, fields ...Field) {
+	l.log(slog.LevelDebug, msg, fields)
+}
+
+// Info logs an info message with optional fields.
+func (l *SlogLogger) Info(msg string, fields ...Field) {
+	l.log(slog.LevelInfo, msg, fields)
+}
+
+// Warn logs a warning message with optional fields.
+func (l *SlogLogger) Warn(msg string, fields ...Field) {
+	l.log(slog.LevelWarn, msg, fields)
+}
+
+// Error logs an error message with optional fields.
+func (l *SlogLogger) Error(msg string, fields ...Field) {
+	l.log(slog.LevelError, msg, fields)
+}
+
+func (l *SlogLogger) log(level slog.Level, msg string, fields []Field) {
+	ctx := context.Background()
+	if !l.logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := make([]slog.Attr, len(fields))
+	for i, field := range fields {
+		attrs[i] = slog.Any(field.Key, field.Value)
+	}
+	l.logger.LogAttrs(ctx, level, msg, attrs...)
+}
@@ -0,0 +1,170 @@
+package jsonpathplus
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/reclaimprotocol/jsonpathplus-go/internal/rfc9535"
+)
+
+func TestStandardMode(t *testing.T) {
+	jsonStr := `{"store":{"book":[{"title":"A","price":8,"tags":["x"]},{"title":"Bb","price":12}]},` +
+		`"it's":{"a\nb":1},"arr":[0,1,2,3,4,5],"mixed":[1,"1",null,{"a":1},[1]]}`
+	standard := &Options{Standard: true}
+
+	t.Run("NormalizedPaths", func(t *testing.T) {
+		tests := []struct {
+			path     string
+			expected []string
+		}{
+			{"$.store.book[*].title", []string{"$['store']['book'][0]['title']", "$['store']['book'][1]['title']"}},
+			{"$['it\\'s'].*", []string{`$['it\'s']['a\nb']`}},
+			{"$.arr[-1]", []string{"$['arr'][5]"}},
+			{"$.arr[::-2]", []string{"$['arr'][5]", "$['arr'][3]", "$['arr'][1]"}},
+			{"$.arr[1:5:2]", []string{"$['arr'][1]", "$['arr'][3]"}},
+			{"$.store..title", []string{"$['store']['book'][0]['title']", "$['store']['book'][1]['title']"}},
+			{"$", []string{"$"}},
+		}
+
+		for _, test := range tests {
+			results, err := QueryWithOptions(test.path, jsonStr, standard)
+			if err != nil {
+				t.Fatalf("Query %s failed: %v", test.path, err)
+			}
+			paths := make([]string, len(results))
+			for i, result := range results {
+				paths[i] = result.Path
+			}
+			if !reflect.DeepEqual(paths, test.expected) {
+				t.Errorf("Query %s: expected paths %q, got %q", test.path, test.expected, paths)
+			}
+		}
+
+		results, err := QueryWithOptions("$['it\\'s'].*", jsonStr, standard)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		if results[0].Pointer != "/it's/a\nb" {
+			t.Errorf("Expected pointer /it's/a\\nb, got %q", results[0].Pointer)
+		}
+	})
+
+	t.Run("Filters", func(t *testing.T) {
+		tests := []struct {
+			path     string
+			expected []interface{}
+		}{
+			{"$.store.book[?@.price < 10].title", []interface{}{"A"}},
+			{"$.store.book[?@.tags].title", []interface{}{"A"}},
+			{"$.store.book[?!@.tags].title", []interface{}{"Bb"}},
+			{"$.store.book[?length(@.title) > 1].title", []interface{}{"Bb"}},
+			{"$.store.book[?count(@.*) == 3].title", []interface{}{"A"}},
+			{"$.store.book[?match(@.title, 'B.')].title", []interface{}{"Bb"}},
+			{"$.store.book[?match(@.title, 'B')].title", []interface{}{}},
+			{"$.store.book[?search(@.title, 'b')].title", []interface{}{"Bb"}},
+			{"$.store.book[?value(@.tags[0]) == 'x'].title", []interface{}{"A"}},
+			{"$.store.book[?@.price == 12.0 || @.title == 'A'].title", []interface{}{"A", "Bb"}},
+			// Values of different types are never equal, and Nothing only equals Nothing
+			{"$.mixed[?@ == 1]", []interface{}{float64(1)}},
+			{"$.mixed[?@.a == 1].a", []interface{}{float64(1)}},
+			{"$.arr[?@.missing == @.other]", []interface{}{float64(0), float64(1), float64(2), float64(3), float64(4), float64(5)}},
+			{"$.mixed[?@ == null]", []interface{}{nil}},
+		}
+
+		for _, test := range tests {
+			results, err := QueryWithOptions(test.path, jsonStr, standard)
+			if err != nil {
+				t.Fatalf("Query %s failed: %v", test.path, err)
+			}
+			values := make([]interface{}, len(results))
+			for i, result := range results {
+				values[i] = result.Value
+			}
+			if !reflect.DeepEqual(values, test.expected) {
+				t.Errorf("Query %s: expected %v, got %v", test.path, test.expected, values)
+			}
+		}
+	})
+
+	t.Run("RejectsNonStandardSyntax", func(t *testing.T) {
+		paths := []string{
+			"$..book[(@.length-1)]",
+			"$.store.book[?(@.title.match(/A/))]",
+			"$.store.book[?@.price<10]^",
+			"$.store.*~",
+			" $.store",
+			"$.arr[01]",
+			"$.arr[-0]",
+			"$.store.book[?length(@.*) > 1]",
+			"$.store.book[?count(@.title) == 1 == true]",
+			"$.store.book[?@..title == 'A']",
+			"$.store.book[?length(@.title)]",
+			"$.store.book[?unknown(@)]",
+			"$['a\\x']",
+			"$.mixed[?@ == [1]]",
+		}
+		for _, path := range paths {
+			_, err := QueryWithOptions(path, jsonStr, standard)
+			var jsonPathErr *JSONPathError
+			if !errors.As(err, &jsonPathErr) || jsonPathErr.Type != ErrInvalidPath {
+				t.Errorf("Query %s: expected ErrInvalidPath, got %v", path, err)
+			}
+		}
+	})
+
+	t.Run("CompileStandard", func(t *testing.T) {
+		jp, err := NewJSONPathEngine().CompileStandard("$.store.book[?@.price > 10].title")
+		if err != nil {
+			t.Fatalf("CompileStandard failed: %v", err)
+		}
+		data, err := JSONParse(jsonStr)
+		if err != nil {
+			t.Fatalf("JSONParse failed: %v", err)
+		}
+		results, err := jp.Execute(data)
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if len(results) != 1 || results[0].Value != "Bb" || results[0].Path != "$['store']['book'][1]['title']" {
+			t.Errorf("Unexpected results %+v", results)
+		}
+	})
+
+	t.Run("RegexpCache", func(t *testing.T) {
+		// Patterns read from the document are matched correctly and cached in bounded space
+		doc := `[{"s":"ab","p":"a."},{"s":"ab","p":"b."},{"s":"xy","p":"x[y]"}]`
+		results, err := QueryWithOptions("$[?match(@.s, @.p)].s", doc, standard)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		if len(results) != 2 {
+			t.Errorf("Expected 2 results, got %+v", results)
+		}
+
+		cache := rfc9535.NewRegexpCache(2)
+		compiles := 0
+		compile := func(pattern string) {
+			cache.Compile(pattern, func() (*regexp.Regexp, error) {
+				compiles++
+				return regexp.Compile(pattern)
+			})
+		}
+		compile("a")
+		compile("b")
+		compile("a") // b is now the least recently used pattern
+		compile("c")
+		if cache.Len() != 2 || compiles != 3 {
+			t.Errorf("Expected 2 cached patterns after 3 compiles, got %d after %d", cache.Len(), compiles)
+		}
+		compile("a")
+		compile("b")
+		if compiles != 4 {
+			t.Errorf("Expected only b to have been evicted, got %d compiles", compiles)
+		}
+		if _, err := cache.Compile("(", func() (*regexp.Regexp, error) { return regexp.Compile("(") }); err == nil || cache.Len() != 2 {
+			t.Error("Expected an invalid pattern to fail without being cached")
+		}
+	})
+}
@@ -32,6 +32,22 @@ func TestStringIndexPreservation(t *testing.T) {
 	t.Run("ArrayOfObjectsStringIndex", func(t *testing.T) {
 		testArrayOfObjectsStringIndex(t, engine)
 	})
+
+	t.Run("ExactSpans", func(t *testing.T) {
+		testExactSpans(t, engine)
+	})
+
+	t.Run("KeyAndValueSpans", func(t *testing.T) {
+		testKeyAndValueSpans(t, engine)
+	})
+
+	t.Run("NumericLikeKeys", func(t *testing.T) {
+		testNumericLikeKeys(t, engine)
+	})
+
+	t.Run("PositionUnits", func(t *testing.T) {
+		testPositionUnits(t, engine)
+	})
 }
 
 func testSimpleObjectStringIndex(t *testing.T, _ *JSONPathEngine) {
@@ -281,6 +297,249 @@ func testArrayOfObjectsStringIndex(t *testing.T, _ *JSONPathEngine) {
 	}
 }
 
+func testExactSpans(t *testing.T, _ *JSONPathEngine) {
+	// Nested arrays, repeated key names and a duplicated key, which JSON.parse
+	// resolves to the last occurrence
+	jsonStr := `{"a":[1,[2,3]],"b":{"a":[4,5]},"c":[{"id":6},{"id":7}],"d":1,"d":"x"}`
+
+	tests := []struct {
+		path     string
+		expected []string
+	}{
+		{"$.a[1][0]", []string{`2`}},
+		{"$.b.a[1]", []string{`5`}},
+		{"$.a[1]", []string{`[2,3]`}},
+		{"$..a", []string{`"a"`, `"a"`}},
+		{"$.c[*].id", []string{`"id"`, `"id"`}},
+		{"$.c[1]", []string{`{"id":7}`}},
+		{"$.b.*~", []string{`"a"`}},
+		{"$", []string{jsonStr}},
+	}
+
+	expectedStarts := map[string][]int{
+		"$..a":      {1, 20},
+		"$.c[*].id": {37, 46},
+		"$.d":       {61}, // The second "d" key
+	}
+
+	for _, test := range tests {
+		results, err := Query(test.path, jsonStr)
+		if err != nil {
+			t.Fatalf("Query %s failed: %v", test.path, err)
+		}
+		if len(results) != len(test.expected) {
+			t.Fatalf("Query %s: expected %d results, got %d", test.path, len(test.expected), len(results))
+		}
+		for i, result := range results {
+			if got := jsonStr[result.Start:result.End]; got != test.expected[i] {
+				t.Errorf("Query %s result %d: expected span %s, got %s", test.path, i, test.expected[i], got)
+			}
+			if result.Length != result.End-result.Start {
+				t.Errorf("Query %s result %d: length %d does not match span", test.path, i, result.Length)
+			}
+		}
+	}
+
+	for path, starts := range expectedStarts {
+		results, err := Query(path, jsonStr)
+		if err != nil {
+			t.Fatalf("Query %s failed: %v", path, err)
+		}
+		for i, result := range results {
+			if result.Start != starts[i] {
+				t.Errorf("Query %s result %d: expected start %d, got %d", path, i, starts[i], result.Start)
+			}
+		}
+	}
+}
+
+func testNumericLikeKeys(t *testing.T, _ *JSONPathEngine) {
+	// Keys that read as the same number must keep their own spans and pointers
+	jsonStr := `{"01":"a","1":"b","+1":"c","x":{"01":[0],"1":[1]}}`
+
+	tests := []struct {
+		path    string
+		key     string
+		value   string
+		pointer string
+	}{
+		{"$['01']", `"01"`, `"a"`, "/01"},
+		{"$['1']", `"1"`, `"b"`, "/1"},
+		{"$['+1']", `"+1"`, `"c"`, "/+1"},
+		{"$.x['01'][0]", "", `0`, "/x/01/0"},
+		{"$.x.1", `"1"`, `[1]`, "/x/1"},
+	}
+
+	for _, test := range tests {
+		results, err := Query(test.path, jsonStr)
+		if err != nil {
+			t.Fatalf("Query %s failed: %v", test.path, err)
+		}
+		if len(results) != 1 {
+			t.Fatalf("Query %s: expected 1 result, got %d", test.path, len(results))
+		}
+		result := results[0]
+		if got := spanText(jsonStr, result.KeySpan); got != test.key {
+			t.Errorf("Query %s: expected key span %s, got %s", test.path, test.key, got)
+		}
+		if got := spanText(jsonStr, result.ValueSpan); got != test.value {
+			t.Errorf("Query %s: expected value span %s, got %s", test.path, test.value, got)
+		}
+		if result.Pointer != test.pointer {
+			t.Errorf("Query %s: expected pointer %s, got %s", test.path, test.pointer, result.Pointer)
+		}
+	}
+
+	results, err := Query("$.x.*", jsonStr)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(results) != 2 || results[0].Path == results[1].Path {
+		t.Fatalf("Expected distinct paths for the keys 01 and 1, got %v", results)
+	}
+	for i, expected := range []string{`[0]`, `[1]`} {
+		if got := spanText(jsonStr, results[i].ValueSpan); got != expected {
+			t.Errorf("Result %d: expected value span %s, got %s", i, expected, got)
+		}
+	}
+}
+
+// spanText returns the text a span covers, or "" for a nil span
+func spanText(jsonStr string, span *Span) string {
+	if span == nil {
+		return ""
+	}
+	return jsonStr[span.Start:span.End]
+}
+
+func testKeyAndValueSpans(t *testing.T, _ *JSONPathEngine) {
+	jsonStr := `{"name": "john", "tags": ["a", {"k": null}], "n": -1.5e3}`
+
+	tests := []struct {
+		path  string
+		key   string // Empty when the result has no key span
+		value string
+	}{
+		{"$.name", `"name"`, `"john"`},
+		{"$.tags", `"tags"`, `["a", {"k": null}]`},
+		{"$.tags[0]", "", `"a"`},
+		{"$.tags[1]", "", `{"k": null}`},
+		{"$.tags[1].k", `"k"`, `null`},
+		{"$.n", `"n"`, `-1.5e3`},
+		{"$.tags[1].*~", "", `"k"`},
+		{"$", "", jsonStr},
+	}
+
+	for _, test := range tests {
+		results, err := Query(test.path, jsonStr)
+		if err != nil {
+			t.Fatalf("Query %s failed: %v", test.path, err)
+		}
+		if len(results) != 1 {
+			t.Fatalf("Query %s: expected 1 result, got %d", test.path, len(results))
+		}
+		result := results[0]
+
+		if test.key == "" {
+			if result.KeySpan != nil {
+				t.Errorf("Query %s: expected no key span, got %v", test.path, *result.KeySpan)
+			}
+		} else if result.KeySpan == nil {
+			t.Errorf("Query %s: expected key span %s, got nil", test.path, test.key)
+		} else if got := jsonStr[result.KeySpan.Start:result.KeySpan.End]; got != test.key {
+			t.Errorf("Query %s: expected key span %s, got %s", test.path, test.key, got)
+		}
+
+		if result.ValueSpan == nil {
+			t.Errorf("Query %s: expected value span %s, got nil", test.path, test.value)
+		} else if got := jsonStr[result.ValueSpan.Start:result.ValueSpan.End]; got != test.value {
+			t.Errorf("Query %s: expected value span %s, got %s", test.path, test.value, got)
+		}
+	}
+
+	// Spans are only known when the input is a JSON string
+	results, err := Query("$.name", map[string]interface{}{"name": "john"})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if results[0].KeySpan != nil || results[0].ValueSpan != nil {
+		t.Errorf("Expected no spans for parsed input, got %v and %v", results[0].KeySpan, results[0].ValueSpan)
+	}
+}
+
+func testPositionUnits(t *testing.T, engine *JSONPathEngine) {
+	jsonStr := "{\n  \"naïve\": \"😀x\",\n  \"b\": [\"é\", 1]\n}"
+
+	tests := []struct {
+		path     string
+		unit     PositionUnit
+		expected Span // Value span of the single result
+	}{
+		{"$['naïve']", PositionUnitBytes, Span{Start: 14, End: 21, Line: 2, Column: 13, EndLine: 2, EndColumn: 20}},
+		{"$['naïve']", PositionUnitRunes, Span{Start: 13, End: 17, Line: 2, Column: 12, EndLine: 2, EndColumn: 16}},
+		{"$['naïve']", PositionUnitUTF16, Span{Start: 13, End: 18, Line: 2, Column: 12, EndLine: 2, EndColumn: 17}},
+		{"$.b[1]", PositionUnitBytes, Span{Start: 37, End: 38, Line: 3, Column: 15, EndLine: 3, EndColumn: 16}},
+		{"$.b[1]", PositionUnitRunes, Span{Start: 32, End: 33, Line: 3, Column: 14, EndLine: 3, EndColumn: 15}},
+		{"$.b[1]", PositionUnitUTF16, Span{Start: 33, End: 34, Line: 3, Column: 14, EndLine: 3, EndColumn: 15}},
+		{"$.b[1]", "", Span{Start: 37, End: 38, Line: 3, Column: 15, EndLine: 3, EndColumn: 16}},
+	}
+
+	for _, test := range tests {
+		results, err := engine.QueryWithOptions(test.path, jsonStr, &Options{PositionUnit: test.unit})
+		if err != nil {
+			t.Fatalf("Query %s failed: %v", test.path, err)
+		}
+		if len(results) != 1 || results[0].ValueSpan == nil {
+			t.Fatalf("Query %s: expected 1 result with a value span, got %v", test.path, results)
+		}
+		if got := *results[0].ValueSpan; got != test.expected {
+			t.Errorf("Query %s in %q: expected value span %+v, got %+v", test.path, test.unit, test.expected, got)
+		}
+	}
+
+	// Start, End and Length follow the unit as well
+	results, err := engine.QueryWithOptions("$.b", jsonStr, &Options{PositionUnit: PositionUnitUTF16})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if results[0].Start != 22 || results[0].End != 25 || results[0].Length != 3 {
+		t.Errorf("Expected key position 22-25 in UTF-16 units, got %d-%d (length %d)",
+			results[0].Start, results[0].End, results[0].Length)
+	}
+
+	if _, err := engine.QueryWithOptions("$.b", jsonStr, &Options{PositionUnit: "chars"}); err == nil {
+		t.Error("Expected error for unknown position unit")
+	}
+
+	// Each byte of an invalid sequence is one unit: a stray continuation byte, a
+	// truncated three-byte sequence and an invalid lead byte
+	invalid := "{\"a\": \"\x80\xe2\x82x\xff😀\", \"b\": 1}"
+	for _, unit := range []PositionUnit{PositionUnitRunes, PositionUnitUTF16} {
+		results, err := engine.QueryWithOptions("$.b", invalid, &Options{PositionUnit: unit})
+		if err != nil {
+			t.Fatalf("Query in %q failed: %v", unit, err)
+		}
+		// The value of b is at byte 24; only the emoji takes fewer units than bytes
+		want := Span{Start: 21, End: 22, Line: 1, Column: 22, EndLine: 1, EndColumn: 23}
+		if unit == PositionUnitUTF16 {
+			want = Span{Start: 22, End: 23, Line: 1, Column: 23, EndLine: 1, EndColumn: 24}
+		}
+		if got := *results[0].ValueSpan; got != want {
+			t.Errorf("Invalid UTF-8 in %q: expected value span %+v, got %+v", unit, want, got)
+		}
+	}
+
+	// Checkpoints land on character boundaries even when a character straddles one
+	long := "{\"a\": \"" + strings.Repeat("é", 300) + "\xff\", \"b\": 1}"
+	results, err = engine.QueryWithOptions("$.b", long, &Options{PositionUnit: PositionUnitRunes})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if got := results[0].ValueSpan.Start; got != 316 {
+		t.Errorf("Expected the value of b at rune 316, got %d", got)
+	}
+}
+
 // Benchmark string index preservation performance.
 func BenchmarkStringIndexPreservation(b *testing.B) {
 	jsonStr := `{"users":[{"name":"alice","age":25},{"name":"bob","age":30},{"name":"charlie","age":35}]}`
@@ -0,0 +1,97 @@
+package jsonpathplus
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/reclaimprotocol/jsonpathplus-go/pkg/types"
+)
+
+// Tracer is notified around the evaluation of each AST node (alias for types.Tracer).
+// Set it as Options.Tracer.
+type Tracer = types.Tracer
+
+// TraceSpan is one evaluation of an AST node recorded by a TraceRecorder.
+type TraceSpan struct {
+	Node     *types.AstNode
+	Inputs   int           // Values the node was evaluated against
+	Outputs  int           // Values the node itself selected, before the steps after it
+	Elapsed  time.Duration // Time spent in the node, including the steps after it
+	Children []*TraceSpan  // Spans of the nodes evaluated on this node's outputs
+}
+
+// TraceRecorder is a Tracer that records the spans of a query as a tree. A recorder
+// traces one query at a time; call Reset before reusing it.
+type TraceRecorder struct {
+	roots []*TraceSpan
+	open  []*TraceSpan
+}
+
+// NewTraceRecorder creates an empty trace recorder.
+func NewTraceRecorder() *TraceRecorder {
+	return &TraceRecorder{}
+}
+
+// StartSpan opens a span for node as a child of the innermost open span.
+func (r *TraceRecorder) StartSpan(node *types.AstNode, inputs int) {
+	span := &TraceSpan{Node: node, Inputs: inputs}
+	if n := len(r.open); n > 0 {
+		parent := r.open[n-1]
+		parent.Children = append(parent.Children, span)
+	} else {
+		r.roots = append(r.roots, span)
+	}
+	r.open = append(r.open, span)
+}
+
+// EndSpan closes the innermost open span.
+func (r *TraceRecorder) EndSpan(_ *types.AstNode, outputs int, elapsed time.Duration) {
+	n := len(r.open)
+	if n == 0 {
+		return
+	}
+	span := r.open[n-1]
+	span.Outputs = outputs
+	span.Elapsed = elapsed
+	r.open = r.open[:n-1]
+}
+
+// Root returns the span of the root node of the traced query, or nil before a
+// query has been traced.
+func (r *TraceRecorder) Root() *TraceSpan {
+	if len(r.roots) == 0 {
+		return nil
+	}
+	return r.roots[0]
+}
+
+// Reset discards the recorded spans.
+func (r *TraceRecorder) Reset() {
+	r.roots = nil
+	r.open = nil
+}
+
+// String renders the trace as an indented tree with one span per line.
+func (r *TraceRecorder) String() string {
+	var sb strings.Builder
+	for _, root := range r.roots {
+		root.write(&sb, 0)
+	}
+	return sb.String()
+}
+
+// String renders the span and its children as an indented tree.
+func (s *TraceSpan) String() string {
+	var sb strings.Builder
+	s.write(&sb, 0)
+	return sb.String()
+}
+
+func (s *TraceSpan) write(sb *strings.Builder, depth int) {
+	fmt.Fprintf(sb, "%s%s(%s) in=%d out=%d %v\n",
+		strings.Repeat("  ", depth), s.Node.Type, s.Node.Value, s.Inputs, s.Outputs, s.Elapsed)
+	for _, child := range s.Children {
+		child.write(sb, depth+1)
+	}
+}
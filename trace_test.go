@@ -0,0 +1,165 @@
+package jsonpathplus
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/reclaimprotocol/jsonpathplus-go/pkg/types"
+)
+
+func TestTraceRecorder(t *testing.T) {
+	data := `{"store":{"book":[{"price":5,"title":"a"},{"price":15,"title":"b"}]}}`
+
+	t.Run("Tree", func(t *testing.T) {
+		recorder := NewTraceRecorder()
+		results, err := QueryWithOptions("$.store.book[?(@.price < 10)].title", data, &Options{Tracer: recorder})
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		if len(results) != 1 {
+			t.Fatalf("Expected 1 result, got %d", len(results))
+		}
+
+		expected := []struct {
+			nodeType string
+			inputs   int
+			outputs  int
+		}{
+			{"root", 1, 1},
+			{"property", 1, 1},
+			{"property", 1, 1},
+			{"filter", 1, 1},
+			{"property", 1, 1},
+		}
+		span := recorder.Root()
+		for i, want := range expected {
+			if span == nil {
+				t.Fatalf("Missing span %d in trace:\n%s", i, recorder)
+			}
+			if span.Node.Type != want.nodeType || span.Inputs != want.inputs || span.Outputs != want.outputs {
+				t.Errorf("Span %d: expected %s in=%d out=%d, got %s in=%d out=%d",
+					i, want.nodeType, want.inputs, want.outputs, span.Node.Type, span.Inputs, span.Outputs)
+			}
+			if span.Elapsed > recorder.Root().Elapsed {
+				t.Errorf("Span %d took longer than the root span", i)
+			}
+			if len(span.Children) == 0 {
+				span = nil
+			} else {
+				span = span.Children[0]
+			}
+		}
+		if span != nil {
+			t.Errorf("Unexpected span %s in trace:\n%s", span.Node.Type, recorder)
+		}
+	})
+
+	t.Run("FanOut", func(t *testing.T) {
+		recorder := NewTraceRecorder()
+		if _, err := QueryWithOptions("$..book[?(@.price < 10)]", data, &Options{Tracer: recorder}); err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+
+		recursive := recorder.Root().Children[0]
+		if recursive.Node.Type != "recursive" {
+			t.Fatalf("Expected a recursive span, got %s", recursive.Node.Type)
+		}
+		// The descent visits nine values and the property is tried at each of them
+		if recursive.Outputs != 9 || len(recursive.Children) != 1 {
+			t.Fatalf("Expected the descent to hand nine values to one property span:\n%s", recorder)
+		}
+		if property := recursive.Children[0]; property.Inputs != 9 || property.Outputs != 1 {
+			t.Errorf("Expected property(book) in=9 out=1:\n%s", recorder)
+		}
+		if !strings.Contains(recorder.String(), "      filter(?(@.price < 10)) in=1 out=1 ") {
+			t.Errorf("Expected the filter span nested under the property:\n%s", recorder)
+		}
+
+		recorder.Reset()
+		if recorder.Root() != nil {
+			t.Error("Expected Reset to discard the trace")
+		}
+	})
+
+	t.Run("RecursiveWildcard", func(t *testing.T) {
+		recorder := NewTraceRecorder()
+		results, err := QueryWithOptions("$..*", data, &Options{Tracer: recorder})
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		if len(results) != 8 {
+			t.Fatalf("Expected 8 results, got %d", len(results))
+		}
+		// The wildcard is applied to the root and the four objects and arrays below it
+		assertSpans(t, recorder, []string{"root in=1 out=1", "recursive in=1 out=5", "wildcard in=5 out=8"})
+
+		recorder.Reset()
+		results, err = QueryWithOptions("$..*[?(@ == 'b')]", data, &Options{Tracer: recorder})
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		if len(results) != 1 {
+			t.Fatalf("Expected 1 result, got %d", len(results))
+		}
+		assertSpans(t, recorder, []string{"root in=1 out=1", "recursive in=1 out=5", "wildcard in=5 out=8", "filter in=8 out=1"})
+	})
+
+	t.Run("Standard", func(t *testing.T) {
+		jp, err := NewJSONPathEngine().CompileStandard("$..book[?@.price < 10].title")
+		if err != nil {
+			t.Fatalf("CompileStandard failed: %v", err)
+		}
+		parsed, _ := JSONParse(data)
+		recorder := NewTraceRecorder()
+		results, err := jp.ExecuteWithOptions(parsed, &Options{Tracer: recorder})
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if len(results) != 1 {
+			t.Fatalf("Expected 1 result, got %d", len(results))
+		}
+		// Selectors are traced once per node they are applied to
+		assertSpans(t, recorder, []string{
+			"root in=1 out=1",
+			"recursive in=1 out=9",
+			"property in=9 out=1",
+			"filter in=1 out=1",
+			"property in=1 out=1",
+		})
+	})
+}
+
+// assertSpans checks the root span and its children, one per line of a depth-first
+// listing, against "type in=N out=M" summaries that add up the spans of each node
+func assertSpans(t *testing.T, recorder *TraceRecorder, expected []string) {
+	t.Helper()
+	type totals struct{ inputs, outputs int }
+	var order []*types.AstNode
+	sums := make(map[*types.AstNode]*totals)
+	var walk func(span *TraceSpan)
+	walk = func(span *TraceSpan) {
+		sum, ok := sums[span.Node]
+		if !ok {
+			sum = &totals{}
+			sums[span.Node] = sum
+			order = append(order, span.Node)
+		}
+		sum.inputs += span.Inputs
+		sum.outputs += span.Outputs
+		for _, child := range span.Children {
+			walk(child)
+		}
+	}
+	if root := recorder.Root(); root != nil {
+		walk(root)
+	}
+
+	var got []string
+	for _, node := range order {
+		got = append(got, fmt.Sprintf("%s in=%d out=%d", node.Type, sums[node].inputs, sums[node].outputs))
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected spans\n%s\ngot\n%s\ntrace:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"), recorder)
+	}
+}
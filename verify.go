@@ -0,0 +1,59 @@
+package jsonpathplus
+
+import "context"
+
+// RangeClaim claims that Range is exactly the raw value of one match of Path
+type RangeClaim struct {
+	Path  string `json:"path"`
+	Range Range  `json:"range"`
+}
+
+// VerifyRange reports whether the byte range [start, end) of jsonStr is exactly the
+// raw value of one of the matches of path. Errors are returned for invalid JSON or
+// an invalid path, never for a claim that simply does not hold.
+func VerifyRange(jsonStr, path string, start, end int) (bool, error) {
+	return defaultEngine.VerifyRange(jsonStr, path, start, end)
+}
+
+// VerifyRanges reports whether every claim holds for jsonStr, parsing it only once
+func VerifyRanges(jsonStr string, claims []RangeClaim) (bool, error) {
+	return defaultEngine.VerifyRanges(jsonStr, claims)
+}
+
+// VerifyRange checks a single range claim using the engine
+func (engine *JSONPathEngine) VerifyRange(jsonStr, path string, start, end int) (bool, error) {
+	return engine.VerifyRanges(jsonStr, []RangeClaim{{Path: path, Range: Range{Start: start, End: end}}})
+}
+
+// VerifyRanges checks a set of range claims using the engine. Each distinct path is
+// evaluated once.
+func (engine *JSONPathEngine) VerifyRanges(jsonStr string, claims []RangeClaim) (bool, error) {
+	doc, err := parseDocument(jsonStr)
+	if err != nil {
+		return false, err
+	}
+
+	matched := make(map[string]map[Range]bool)
+	valid := true
+	for _, claim := range claims {
+		spans, ok := matched[claim.Path]
+		if !ok {
+			results, err := engine.queryDocument(context.Background(), claim.Path, doc, &Options{})
+			if err != nil {
+				return false, err
+			}
+			spans = make(map[Range]bool, len(results))
+			for _, result := range results {
+				if result.ValueSpan != nil {
+					spans[Range{Start: result.ValueSpan.Start, End: result.ValueSpan.End}] = true
+				}
+			}
+			matched[claim.Path] = spans
+		}
+		// Keep going after a failed claim so an invalid path later on is still reported
+		if !spans[claim.Range] {
+			valid = false
+		}
+	}
+	return valid, nil
+}
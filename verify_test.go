@@ -0,0 +1,85 @@
+package jsonpathplus
+
+import "testing"
+
+func TestVerifyRange(t *testing.T) {
+	jsonStr := `{"a":[1,{"b":"x"}],"b":"x","c":{"b":"y"}}`
+
+	tests := []struct {
+		name       string
+		path       string
+		start, end int
+		expected   bool
+	}{
+		{"ArrayElement", "$.a[0]", 6, 7, true},
+		{"NestedMember", "$.a[1].b", 13, 16, true},
+		{"Container", "$.a", 5, 18, true},
+		{"RecursiveMatch", "$..b", 36, 39, true},
+		{"KeyIsNotValue", "$.b", 19, 22, false},
+		{"PartialValue", "$.b", 24, 25, false},
+		{"SameTextDifferentPath", "$.b", 13, 16, false},
+		{"OutOfBounds", "$.b", 23, 100, false},
+		{"NoMatches", "$.missing", 0, 1, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ok, err := VerifyRange(jsonStr, test.path, test.start, test.end)
+			if err != nil {
+				t.Fatalf("VerifyRange failed: %v", err)
+			}
+			if ok != test.expected {
+				t.Errorf("VerifyRange(%s, %d, %d) = %v, expected %v (span %q)",
+					test.path, test.start, test.end, ok, test.expected, safeSlice(jsonStr, test.start, test.end))
+			}
+		})
+	}
+
+	t.Run("NumericLikeKeys", func(t *testing.T) {
+		// "01" and "1" read as the same number but are different members
+		doc := `{"01":"a","1":"b"}`
+		if ok, err := VerifyRange(doc, "$['01']", 6, 9); err != nil || !ok {
+			t.Errorf("Expected the range of \"a\" to verify, got %v, %v", ok, err)
+		}
+		if ok, err := VerifyRange(doc, "$['01']", 14, 17); err != nil || ok {
+			t.Errorf("Expected the range of \"b\" to be rejected, got %v, %v", ok, err)
+		}
+		claims := []RangeClaim{{Path: "$['1']", Range: Range{Start: 14, End: 17}}}
+		if ok, err := VerifyRanges(doc, claims); err != nil || !ok {
+			t.Errorf("Expected the claim on $['1'] to hold, got %v, %v", ok, err)
+		}
+	})
+
+	t.Run("Ranges", func(t *testing.T) {
+		claims := []RangeClaim{
+			{Path: "$.b", Range: Range{Start: 23, End: 26}},
+			{Path: "$..b", Range: Range{Start: 13, End: 16}},
+			{Path: "$..b", Range: Range{Start: 36, End: 39}},
+		}
+		ok, err := VerifyRanges(jsonStr, claims)
+		if err != nil || !ok {
+			t.Fatalf("Expected all claims to hold, got %v, %v", ok, err)
+		}
+
+		claims = append(claims, RangeClaim{Path: "$.c", Range: Range{Start: 23, End: 26}})
+		if ok, err := VerifyRanges(jsonStr, claims); err != nil || ok {
+			t.Errorf("Expected a false claim to fail the set, got %v, %v", ok, err)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		if _, err := VerifyRange(jsonStr, "$[?(@ ==)]", 0, 1); err == nil {
+			t.Error("Expected error for invalid path")
+		}
+		if _, err := VerifyRange(`{"a":1`, "$.a", 5, 6); err == nil {
+			t.Error("Expected error for invalid JSON")
+		}
+	})
+}
+
+func safeSlice(s string, start, end int) string {
+	if start < 0 || end > len(s) || start > end {
+		return ""
+	}
+	return s[start:end]
+}